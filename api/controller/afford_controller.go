@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// ValidateAffordability 读取月收入,现有负债,负债收入比和压力测试基点
+func (v InputValidator) ValidateAffordability(c *gin.Context) (loan.AffordabilityParams, error) {
+	monthlyIncome, err := decimal.NewFromString(c.DefaultPostForm("monthlyIncome", "0"))
+	if err != nil || !monthlyIncome.IsPositive() {
+		return loan.AffordabilityParams{}, errors.New("Invalid monthlyIncome: it should be a positive number")
+	}
+	monthlyDebts, err := decimal.NewFromString(c.DefaultPostForm("monthlyDebts", "0"))
+	if err != nil || monthlyDebts.IsNegative() {
+		return loan.AffordabilityParams{}, errors.New("Invalid monthlyDebts: it should be a positive number")
+	}
+	maxDTI, err := decimal.NewFromString(c.DefaultPostForm("maxDTI", "50"))
+	if err != nil || !maxDTI.IsPositive() || maxDTI.GreaterThan(decimal.NewFromInt(100)) {
+		return loan.AffordabilityParams{}, errors.New("Invalid maxDTI: it should be between 0 and 100")
+	}
+	stressBP, err := decimal.NewFromString(c.DefaultPostForm("stressBP", "100"))
+	if err != nil || stressBP.IsNegative() || stressBP.GreaterThan(decimal.NewFromInt(500)) {
+		return loan.AffordabilityParams{}, errors.New("Invalid stressBP: it should be between 0 and 500")
+	}
+
+	return loan.AffordabilityParams{
+		MonthlyIncome: monthlyIncome,
+		MonthlyDebts:  monthlyDebts,
+		MaxDTI:        maxDTI,
+		StressBP:      stressBP,
+	}, nil
+}
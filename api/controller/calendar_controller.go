@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 提醒的单位
+var alarmUnits = map[byte]time.Duration{'d': 24 * time.Hour, 'h': time.Hour, 'm': time.Minute}
+
+// 提醒最多5个,最早提前30天
+const (
+	maxAlarms      = 5
+	maxAlarmBefore = 30 * 24 * time.Hour
+)
+
+// ValidateCalendar 读取提醒和工作日顺延,提醒格式为逗号分隔的 1d,12h,30m,为空时不提醒
+func (v InputValidator) ValidateCalendar(c *gin.Context) (loan.CalendarOptions, error) {
+	alarms := make([]time.Duration, 0)
+	for _, item := range strings.Split(c.DefaultPostForm("icsAlarms", "1d"), ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		unit, ok := alarmUnits[item[len(item)-1]]
+		if !ok {
+			return loan.CalendarOptions{}, errors.New("Invalid icsAlarms: each alarm should be a number with unit d, h or m, such as 1d,12h")
+		}
+		// 先比较数值再换算,避免数值过大时乘法溢出
+		n, err := strconv.Atoi(item[:len(item)-1])
+		if err != nil || n < 0 || n > int(maxAlarmBefore/unit) {
+			return loan.CalendarOptions{}, errors.New("Invalid icsAlarms: each alarm should be between 0m and 30d")
+		}
+		alarms = append(alarms, time.Duration(n)*unit)
+	}
+	if len(alarms) > maxAlarms {
+		return loan.CalendarOptions{}, errors.New("Invalid icsAlarms: at most 5 alarms")
+	}
+
+	businessDay := c.PostForm("businessDay")
+	if businessDay != "" && businessDay != "on" && businessDay != "off" {
+		return loan.CalendarOptions{}, errors.New("Invalid businessDay: it should be on or off")
+	}
+
+	return loan.CalendarOptions{
+		Alarms:      alarms,
+		BusinessDay: businessDay == "on",
+	}, nil
+}
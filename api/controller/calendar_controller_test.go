@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateCalendarAlarms(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		alarms string
+		want   []time.Duration
+		err    bool
+	}{
+		{name: "default units", alarms: "1d, 12h,30m", want: []time.Duration{24 * time.Hour, 12 * time.Hour, 30 * time.Minute}},
+		{name: "empty", alarms: "", want: []time.Duration{}},
+		{name: "thirty days", alarms: "30d,720h,43200m", want: []time.Duration{720 * time.Hour, 720 * time.Hour, 720 * time.Hour}},
+		{name: "over thirty days", alarms: "31d", err: true},
+		{name: "over thirty days in minutes", alarms: "43201m", err: true},
+		// 乘以单位后溢出为负数或很小的值,也要拒绝
+		{name: "overflow", alarms: "106751991167301d", err: true},
+		{name: "overflow to small", alarms: "213503982334602d", err: true},
+		{name: "negative", alarms: "-1d", err: true},
+		{name: "bad unit", alarms: "1w", err: true},
+		{name: "too many", alarms: "1m,2m,3m,4m,5m,6m", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"icsAlarms": {tt.alarms}}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/loan/ics", strings.NewReader(form.Encode()))
+			c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			options, err := NewInputValidator(0, 0).ValidateCalendar(c)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", options.Alarms)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(options.Alarms) != len(tt.want) {
+				t.Fatalf("alarms = %v, want %v", options.Alarms, tt.want)
+			}
+			for i := range tt.want {
+				if options.Alarms[i] != tt.want[i] {
+					t.Errorf("alarm %d = %v, want %v", i, options.Alarms[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// 未填写对比方案时,对比等额本息和等额本金
+const defaultScenarios = "等额本息,emi,,,\n等额本金,epp,,,"
+
+// ValidateCompare 解析对比方案,每行一个方案,格式为 "名称,还款方式,加点,期限,提前还款"
+// 加点,期限为空时沿用表单的值,加点单位和 plusSpread 一致
+// 提前还款为空时沿用表单的提前还款,填写 none 时不提前还款,
+// 也可以填写本方案自己的提前还款,多笔用 ; 分隔,每笔格式为 "金额@日期[:keep]",
+// 如 "200000@2024-06-20:keep;100000@2025-06-20",加 :keep 时月供不变缩短期限,默认减少月供
+func (v InputValidator) ValidateCompare(c *gin.Context, base loan.Input) ([]loan.Scenario, error) {
+	text := strings.TrimSpace(c.DefaultPostForm("scenarios", ""))
+	if text == "" {
+		text = defaultScenarios
+	}
+	unit := c.DefaultPostForm("plusSpreadUnit", SpreadUnitPercent)
+
+	scenarios := make([]loan.Scenario, 0)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("Invalid scenarios line %d: it should be name,method,plusSpread,loanTerm,earlyRepayment", i+1)
+		}
+		for j := range fields {
+			fields[j] = strings.TrimSpace(fields[j])
+		}
+
+		scenario := loan.Scenario{Name: fields[0], Action: fields[1], Input: base}
+		switch scenario.Action {
+		case "emi", "epp", "balloon", "biweekly":
+		default:
+			return nil, fmt.Errorf("Invalid scenarios line %d: method should be emi, epp, balloon or biweekly", i+1)
+		}
+		if scenario.Name == "" {
+			scenario.Name = scenario.Action
+		}
+		if fields[2] != "" {
+			plusSpread, err := v.parseSpread(fields[2], unit)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid scenarios line %d: plusSpread %s", i+1, err.Error())
+			}
+			scenario.Input.Loan.PlusSpread = plusSpread
+		}
+		if fields[3] != "" {
+			loanTerm, err := strconv.Atoi(fields[3])
+			if err != nil || loanTerm < 12 || loanTerm > 360 {
+				return nil, fmt.Errorf("Invalid scenarios line %d: loanTerm should be between 12 and 360", i+1)
+			}
+			// 与表单一样,固定利率年数不能超过贷款期限
+			if base.Loan.FixedYears*12 > loanTerm {
+				return nil, fmt.Errorf("Invalid scenarios line %d: loanTerm should not be shorter than fixedYears", i+1)
+			}
+			scenario.Input.Loan.InitialTerm = loanTerm
+		}
+		switch fields[4] {
+		case "":
+		case "none":
+			scenario.Input.EarlyRepayment = nil
+		default:
+			earlyRepayment, err := parseScenarioPrepayments(fields[4], base.Loan.InitialDate)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid scenarios line %d: %s", i+1, err.Error())
+			}
+			scenario.Input.EarlyRepayment = earlyRepayment
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	if len(scenarios) == 0 {
+		return nil, errors.New("Invalid scenarios: at least one scenario is required")
+	}
+	return scenarios, nil
+}
+
+// 解析对比方案的提前还款,多笔用 ; 分隔,每笔格式为 "金额@日期[:keep|:reduce]"
+func parseScenarioPrepayments(text string, startDate time.Time) ([]loan.EarlyRepayment, error) {
+	earlyRepayment := make([]loan.EarlyRepayment, 0)
+	for _, item := range strings.Split(text, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		amountText, dateText, ok := strings.Cut(item, "@")
+		if !ok {
+			return nil, errors.New("earlyRepayment should be amount@date[:keep], separated by ;")
+		}
+		mode := EarlyRepaymentReduce
+		if before, after, found := strings.Cut(dateText, ":"); found {
+			dateText, mode = before, strings.TrimSpace(after)
+		}
+		if mode != EarlyRepaymentReduce && mode != EarlyRepaymentKeep {
+			return nil, errors.New("earlyRepayment mode should be reduce or keep")
+		}
+		amount, err := decimal.NewFromString(strings.TrimSpace(amountText))
+		if err != nil || !amount.IsPositive() {
+			return nil, errors.New("earlyRepayment amount should be a positive number")
+		}
+		date := loan.ParseDate(strings.TrimSpace(dateText))
+		if date.IsZero() {
+			return nil, errors.New("earlyRepayment date should be yyyy-mm-dd")
+		}
+		if !date.After(startDate) {
+			return nil, errors.New("earlyRepayment date should be after startDate")
+		}
+		earlyRepayment = append(earlyRepayment, loan.EarlyRepayment{Amount: amount, Date: date, KeepInstallment: mode == EarlyRepaymentKeep})
+	}
+	if len(earlyRepayment) == 0 {
+		return nil, errors.New("earlyRepayment should be empty, none or amount@date[:keep]")
+	}
+	return earlyRepayment, nil
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+func TestParseScenarioPrepayments(t *testing.T) {
+	start := loan.ParseDate("2022-05-25")
+	tests := []struct {
+		name string
+		text string
+		want []loan.EarlyRepayment
+		err  bool
+	}{
+		{
+			name: "single reduce",
+			text: "200000@2024-06-20",
+			want: []loan.EarlyRepayment{{Amount: decimal.NewFromInt(200000), Date: loan.ParseDate("2024-06-20")}},
+		},
+		{
+			name: "multiple with modes",
+			text: "200000@2024-06-20:keep; 100000.5@2025-06-20:reduce;",
+			want: []loan.EarlyRepayment{
+				{Amount: decimal.NewFromInt(200000), Date: loan.ParseDate("2024-06-20"), KeepInstallment: true},
+				{Amount: decimal.RequireFromString("100000.5"), Date: loan.ParseDate("2025-06-20")},
+			},
+		},
+		{name: "missing date", text: "200000", err: true},
+		{name: "bad mode", text: "200000@2024-06-20:shorten", err: true},
+		{name: "zero amount", text: "0@2024-06-20", err: true},
+		{name: "bad date", text: "200000@2024/06/20", err: true},
+		{name: "before start", text: "200000@2022-05-01", err: true},
+		{name: "only separators", text: ";;", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseScenarioPrepayments(tt.text, start)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d prepayments, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].Amount.Equal(tt.want[i].Amount) || !got[i].Date.Equal(tt.want[i].Date) || got[i].KeepInstallment != tt.want[i].KeepInstallment {
+					t.Errorf("prepayment %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCompareEarlyRepayment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base := loan.Input{
+		Loan:           loan.Loan{InitialDate: loan.ParseDate("2022-05-25")},
+		EarlyRepayment: []loan.EarlyRepayment{{Amount: decimal.NewFromInt(50000), Date: loan.ParseDate("2023-08-19")}},
+	}
+	form := url.Values{"scenarios": {"沿用,emi,,,\n不还,emi,,,none\n自定义,epp,,,200000@2024-06-20:keep"}}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/loan/compare", strings.NewReader(form.Encode()))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	scenarios, err := NewInputValidator(0, 0).ValidateCompare(c, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) != 3 {
+		t.Fatalf("got %d scenarios, want 3", len(scenarios))
+	}
+	if got := scenarios[0].Input.EarlyRepayment; len(got) != 1 || !got[0].Amount.Equal(decimal.NewFromInt(50000)) {
+		t.Errorf("empty column should keep the form's prepayments, got %+v", got)
+	}
+	if got := scenarios[1].Input.EarlyRepayment; got != nil {
+		t.Errorf("none should clear prepayments, got %+v", got)
+	}
+	got := scenarios[2].Input.EarlyRepayment
+	if len(got) != 1 || !got[0].Amount.Equal(decimal.NewFromInt(200000)) || !got[0].KeepInstallment {
+		t.Errorf("scenario prepayments = %+v", got)
+	}
+	// 方案的提前还款不能影响表单的提前还款
+	if !base.EarlyRepayment[0].Amount.Equal(decimal.NewFromInt(50000)) {
+		t.Error("base input was modified")
+	}
+}
+
+func TestValidateCompareLoanTerm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base := loan.Input{Loan: loan.Loan{InitialTerm: 360, InitialDate: loan.ParseDate("2022-05-25"), FixedYears: 5}}
+	tests := []struct {
+		name      string
+		scenarios string
+		err       bool
+	}{
+		{name: "form term", scenarios: "固定,emi,,,"},
+		{name: "term equals fixed years", scenarios: "五年,emi,,60,"},
+		{name: "term shorter than fixed years", scenarios: "三年,emi,,36,", err: true},
+		{name: "out of range", scenarios: "过长,emi,,480,", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"scenarios": {tt.scenarios}}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/loan/compare", strings.NewReader(form.Encode()))
+			c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			_, err := NewInputValidator(0, 0).ValidateCompare(c, base)
+			if tt.err != (err != nil) {
+				t.Errorf("err = %v, want error %v", err, tt.err)
+			}
+		})
+	}
+}
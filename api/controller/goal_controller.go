@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// ValidateGoal 读取反向求解的变量,目标和求解范围
+// 求解范围为空时,期限取12~360个月,加点取允许的加点范围,本金取0~100000000
+// 加点的范围单位和 plusSpread 一致
+func (v InputValidator) ValidateGoal(c *gin.Context) (loan.Goal, error) {
+	goal := loan.Goal{
+		Variable: c.DefaultPostForm("goalVariable", loan.GoalVariableTerm),
+		Target:   c.DefaultPostForm("goalTarget", loan.GoalTargetInstallment),
+	}
+
+	switch goal.Target {
+	case loan.GoalTargetInstallment, loan.GoalTargetInterest:
+		value, err := decimal.NewFromString(c.DefaultPostForm("goalValue", "0"))
+		if err != nil || !value.IsPositive() {
+			return loan.Goal{}, errors.New("Invalid goalValue: it should be a positive number")
+		}
+		goal.Value = value
+	case loan.GoalTargetPayoff:
+		goal.Date = loan.ParseDate(c.DefaultPostForm("goalDate", ""))
+		if goal.Date.IsZero() {
+			return loan.Goal{}, errors.New("Invalid goalDate: it should be yyyy-mm-dd")
+		}
+	default:
+		return loan.Goal{}, errors.New("Invalid goalTarget: it should be installment, interest or payoff")
+	}
+
+	// 按变量解析求解范围
+	var parseBound func(text string) (decimal.Decimal, error)
+	switch goal.Variable {
+	case loan.GoalVariableTerm:
+		goal.Min, goal.Max = decimal.NewFromInt(12), decimal.NewFromInt(360)
+		parseBound = func(text string) (decimal.Decimal, error) {
+			term, err := strconv.Atoi(text)
+			if err != nil || term < 12 || term > 360 {
+				return decimal.Zero, errors.New("Invalid goal range: loanTerm should be between 12 and 360")
+			}
+			return decimal.NewFromInt(int64(term)), nil
+		}
+	case loan.GoalVariableSpread:
+		unit := c.DefaultPostForm("plusSpreadUnit", SpreadUnitPercent)
+		goal.Min = decimal.NewFromInt(int64(v.SpreadMinBP)).Div(decimal.NewFromInt(100))
+		goal.Max = decimal.NewFromInt(int64(v.SpreadMaxBP)).Div(decimal.NewFromInt(100))
+		parseBound = func(text string) (decimal.Decimal, error) {
+			spread, err := v.parseSpread(text, unit)
+			if err != nil {
+				return decimal.Zero, errors.New("Invalid goal range: plusSpread " + err.Error())
+			}
+			return spread, nil
+		}
+	case loan.GoalVariablePrincipal:
+		goal.Min, goal.Max = decimal.Zero, decimal.NewFromInt(100000000)
+		parseBound = func(text string) (decimal.Decimal, error) {
+			principal, err := decimal.NewFromString(text)
+			if err != nil || principal.IsNegative() || principal.GreaterThan(decimal.NewFromInt(100000000)) {
+				return decimal.Zero, errors.New("Invalid goal range: principal should be between 0 and 100000000")
+			}
+			return principal, nil
+		}
+	default:
+		return loan.Goal{}, errors.New("Invalid goalVariable: it should be term, spread or principal")
+	}
+	if text := strings.TrimSpace(c.DefaultPostForm("goalMin", "")); text != "" {
+		value, err := parseBound(text)
+		if err != nil {
+			return loan.Goal{}, err
+		}
+		goal.Min = value
+	}
+	if text := strings.TrimSpace(c.DefaultPostForm("goalMax", "")); text != "" {
+		value, err := parseBound(text)
+		if err != nil {
+			return loan.Goal{}, err
+		}
+		goal.Max = value
+	}
+	if goal.Max.LessThan(goal.Min) {
+		return loan.Goal{}, errors.New("Invalid goal range: goalMax should not be less than goalMin")
+	}
+	return goal, nil
+}
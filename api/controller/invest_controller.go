@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// InvestInput 提前还款与投资对比的参数
+type InvestInput struct {
+	Candidate    loan.EarlyRepayment // 待决策的提前还款
+	AnnualReturn decimal.Decimal     // 投资年化收益率(%)
+	TaxRate      decimal.Decimal     // 投资收益税率(%)
+}
+
+// ValidateInvest 读取待决策的提前还款和投资收益率
+func (v InputValidator) ValidateInvest(c *gin.Context) (InvestInput, error) {
+	amount, err := decimal.NewFromString(c.DefaultPostForm("candidateAmount", "0"))
+	if err != nil || !amount.IsPositive() {
+		return InvestInput{}, errors.New("Invalid candidateAmount: it should be a positive number")
+	}
+	date := loan.ParseDate(c.DefaultPostForm("candidateDate", ""))
+	if date.IsZero() {
+		return InvestInput{}, errors.New("Invalid candidateDate: it should be yyyy-mm-dd")
+	}
+	annualReturn, err := decimal.NewFromString(c.DefaultPostForm("investReturn", "3"))
+	if err != nil || annualReturn.IsNegative() || annualReturn.GreaterThan(decimal.NewFromInt(100)) {
+		return InvestInput{}, errors.New("Invalid investReturn: it should be between 0 and 100")
+	}
+	taxRate, err := decimal.NewFromString(c.DefaultPostForm("investTaxRate", "0"))
+	if err != nil || taxRate.IsNegative() || taxRate.GreaterThan(decimal.NewFromInt(100)) {
+		return InvestInput{}, errors.New("Invalid investTaxRate: it should be between 0 and 100")
+	}
+
+	return InvestInput{
+		Candidate:    loan.EarlyRepayment{Amount: amount, Date: date},
+		AnnualReturn: annualReturn,
+		TaxRate:      taxRate,
+	}, nil
+}
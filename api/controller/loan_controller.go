@@ -2,15 +2,67 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
 	"github.com/shopspring/decimal"
 )
 
+// 加点单位
+const (
+	SpreadUnitBP      = "bp"      // 基点,1bp=0.01%
+	SpreadUnitPercent = "percent" // 百分点
+)
+
+// 提前还款方式
+const (
+	EarlyRepaymentReduce = "reduce" // 期限不变,减少月供
+	EarlyRepaymentKeep   = "keep"   // 月供不变,缩短期限
+)
+
+// 默认加点范围(基点)
+const (
+	defaultSpreadMinBP = -100
+	defaultSpreadMaxBP = 100
+)
+
 // InputValidator 是一个实现了 Validator 接口的结构体
-type InputValidator struct{}
+type InputValidator struct {
+	SpreadMinBP int // 加点下限(基点)
+	SpreadMaxBP int // 加点上限(基点)
+}
+
+// NewInputValidator 创建校验器,加点范围均为0时使用默认范围
+func NewInputValidator(spreadMinBP, spreadMaxBP int) InputValidator {
+	if spreadMinBP == 0 && spreadMaxBP == 0 {
+		spreadMinBP, spreadMaxBP = defaultSpreadMinBP, defaultSpreadMaxBP
+	}
+	return InputValidator{SpreadMinBP: spreadMinBP, SpreadMaxBP: spreadMaxBP}
+}
+
+// 按单位解析加点并校验范围,返回百分点表示的加点
+func (v InputValidator) parseSpread(value string, unit string) (decimal.Decimal, error) {
+	spread, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil {
+		return decimal.Zero, errors.New("it should be a number")
+	}
+	switch unit {
+	case SpreadUnitBP:
+		spread = spread.Div(decimal.NewFromInt(100))
+	case SpreadUnitPercent, "":
+	default:
+		return decimal.Zero, errors.New("unit should be bp or percent")
+	}
+	bp := spread.Mul(decimal.NewFromInt(100))
+	if bp.LessThan(decimal.NewFromInt(int64(v.SpreadMinBP))) || bp.GreaterThan(decimal.NewFromInt(int64(v.SpreadMaxBP))) {
+		return decimal.Zero, fmt.Errorf("it should be between %dbp and %dbp", v.SpreadMinBP, v.SpreadMaxBP)
+	}
+	return spread, nil
+}
 
 func (v InputValidator) Validate(c *gin.Context) (inputData loan.Input, err error, action string) {
 	// 获取表单数据
@@ -36,12 +88,10 @@ func (v InputValidator) Validate(c *gin.Context) (inputData loan.Input, err erro
 		return loan.Input{}, errors.New("Invalid startDate: it cannot be empty"), action
 	}
 
-	plusSpread, err := strconv.ParseFloat(c.DefaultPostForm("plusSpread", "0"), 64)
+	plusSpreadUnit := c.DefaultPostForm("plusSpreadUnit", SpreadUnitPercent)
+	plusSpread, err := v.parseSpread(c.DefaultPostForm("plusSpread", "0"), plusSpreadUnit)
 	if err != nil {
-		return loan.Input{}, errors.New("Invalid plusSpread: it should be a number"), action
-	}
-	if plusSpread >= 1 {
-		return loan.Input{}, errors.New("Invalid plusSpread: it should be between 0 and 1"), action
+		return loan.Input{}, errors.New("Invalid plusSpread: " + err.Error()), action
 	}
 	paymentDueDay, err := strconv.Atoi(c.DefaultPostForm("paymentDueDay", "1"))
 	if err != nil {
@@ -51,6 +101,62 @@ func (v InputValidator) Validate(c *gin.Context) (inputData loan.Input, err erro
 	if paymentDueDay < 1 || paymentDueDay > 31 {
 		return loan.Input{}, errors.New("Invalid PaymentDueDay: it should be between 1 and 31"), action
 	}
+	balloonAmount, err := strconv.ParseFloat(c.DefaultPostForm("balloonAmount", "0"), 64)
+	if err != nil {
+		return loan.Input{}, errors.New("Invalid balloonAmount: it should be a number"), action
+	}
+	if balloonAmount < 0 || (balloonAmount > 0 && balloonAmount >= principal) {
+		return loan.Input{}, errors.New("Invalid balloonAmount: it should be between 0 and principal"), action
+	}
+	fixedRate, err := strconv.ParseFloat(c.DefaultPostForm("fixedRate", "0"), 64)
+	if err != nil {
+		return loan.Input{}, errors.New("Invalid fixedRate: it should be a number"), action
+	}
+	if fixedRate < 0 || fixedRate >= 20 {
+		return loan.Input{}, errors.New("Invalid fixedRate: it should be between 0 and 20"), action
+	}
+	fixedYears, err := strconv.Atoi(c.DefaultPostForm("fixedYears", "0"))
+	if err != nil {
+		return loan.Input{}, errors.New("Invalid fixedYears: it should be an integer"), action
+	}
+	if fixedYears < 0 || fixedYears*12 > loanTerm {
+		return loan.Input{}, errors.New("Invalid fixedYears: it should be between 0 and loanTerm/12"), action
+	}
+	rateFloor, err := strconv.ParseFloat(c.DefaultPostForm("rateFloor", "0"), 64)
+	if err != nil {
+		return loan.Input{}, errors.New("Invalid rateFloor: it should be a number"), action
+	}
+	rateCap, err := strconv.ParseFloat(c.DefaultPostForm("rateCap", "0"), 64)
+	if err != nil {
+		return loan.Input{}, errors.New("Invalid rateCap: it should be a number"), action
+	}
+	if rateFloor < 0 || rateCap < 0 || (rateCap > 0 && rateCap < rateFloor) {
+		return loan.Input{}, errors.New("Invalid rateFloor or rateCap: they should be positive and rateCap should not be less than rateFloor"), action
+	}
+	spreadChanges, err := v.parseSpreadChanges(c.DefaultPostForm("spreadChanges", ""), plusSpreadUnit)
+	if err != nil {
+		return loan.Input{}, err, action
+	}
+	penaltyRateMarkup, err := strconv.ParseFloat(c.DefaultPostForm("penaltyRateMarkup", "50"), 64)
+	if err != nil {
+		return loan.Input{}, errors.New("Invalid penaltyRateMarkup: it should be a number"), action
+	}
+	if penaltyRateMarkup < 0 || penaltyRateMarkup > 100 {
+		return loan.Input{}, errors.New("Invalid penaltyRateMarkup: it should be between 0 and 100"), action
+	}
+	actualPayments, err := parseActualPayments(c.DefaultPostForm("actualPayments", ""))
+	if err != nil {
+		return loan.Input{}, err, action
+	}
+	prepaymentPenalties, err := parsePrepaymentPenalties(c.DefaultPostForm("prepaymentPenalties", ""))
+	if err != nil {
+		return loan.Input{}, err, action
+	}
+	// 结清日期,为空时按计划还完
+	payoffDate := loan.ParseDate(c.DefaultPostForm("payoffDate", ""))
+	if !payoffDate.IsZero() && !payoffDate.After(loan.ParseDate(startDate)) {
+		return loan.Input{}, errors.New("Invalid payoffDate: it should be after startDate"), action
+	}
 	// 获取提前还款信息的值
 	earlyRepayment1Amount, err := strconv.ParseFloat(c.DefaultPostForm("earlyRepayment1Amount", "0"), 64)
 	if err != nil {
@@ -70,20 +176,129 @@ func (v InputValidator) Validate(c *gin.Context) (inputData loan.Input, err erro
 	}
 	earlyRepayment3Date := c.DefaultPostForm("earlyRepayment3Date", "2099-05-25")
 
+	// 提前还款后缩短期限还是减少月供
+	earlyRepaymentMode := c.DefaultPostForm("earlyRepaymentMode", EarlyRepaymentReduce)
+	if earlyRepaymentMode != EarlyRepaymentReduce && earlyRepaymentMode != EarlyRepaymentKeep {
+		return loan.Input{}, errors.New("Invalid earlyRepaymentMode: it should be reduce or keep"), action
+	}
+	keepInstallment := earlyRepaymentMode == EarlyRepaymentKeep
+
 	inputData = loan.Input{
 		Loan: loan.Loan{
 			InitialPrincipal: decimal.NewFromFloat(principal),
 			InitialTerm:      loanTerm,
 			InitialDate:      loan.ParseDate(startDate),
 			LPR:              loan.Lprs, // 常量
-			PlusSpread:       decimal.NewFromFloat(plusSpread),
+			PlusSpread:       plusSpread,
 			PaymentDueDay:    paymentDueDay,
+			BalloonAmount:    decimal.NewFromFloat(balloonAmount),
+			FixedRate:        decimal.NewFromFloat(fixedRate),
+			FixedYears:       fixedYears,
+			SpreadChanges:    spreadChanges,
+			RateFloor:        decimal.NewFromFloat(rateFloor),
+			RateCap:          decimal.NewFromFloat(rateCap),
+			// 页面按百分比输入
+			PenaltyRateMarkup:   decimal.NewFromFloat(penaltyRateMarkup).Div(decimal.NewFromInt(100)),
+			PrepaymentPenalties: prepaymentPenalties,
 		},
 		EarlyRepayment: []loan.EarlyRepayment{
-			{Amount: decimal.NewFromFloat(earlyRepayment1Amount), Date: loan.ParseDate(earlyRepayment1Date)},
-			{Amount: decimal.NewFromFloat(earlyRepayment2Amount), Date: loan.ParseDate(earlyRepayment2Date)},
-			{Amount: decimal.NewFromFloat(earlyRepayment3Amount), Date: loan.ParseDate(earlyRepayment3Date)},
+			{Amount: decimal.NewFromFloat(earlyRepayment1Amount), Date: loan.ParseDate(earlyRepayment1Date), KeepInstallment: keepInstallment},
+			{Amount: decimal.NewFromFloat(earlyRepayment2Amount), Date: loan.ParseDate(earlyRepayment2Date), KeepInstallment: keepInstallment},
+			{Amount: decimal.NewFromFloat(earlyRepayment3Amount), Date: loan.ParseDate(earlyRepayment3Date), KeepInstallment: keepInstallment},
 		},
+		ActualPayments: actualPayments,
+		PayoffDate:     payoffDate,
 	}
 	return inputData, nil, action
 }
+
+// 多行文本中的一条记录,格式为 "日期,数值"
+type datedLine struct {
+	date  time.Time
+	value string
+}
+
+// 解析多行文本,每行一条 "日期,数值",空行忽略
+func parseDatedLines(text string, name string) ([]datedLine, error) {
+	lines := make([]datedLine, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Invalid %s: each line should be date,value", name)
+		}
+		date := loan.ParseDate(strings.TrimSpace(fields[0]))
+		if date.IsZero() {
+			return nil, fmt.Errorf("Invalid %s: date should be yyyy-mm-dd", name)
+		}
+		lines = append(lines, datedLine{date: date, value: strings.TrimSpace(fields[1])})
+	}
+	return lines, nil
+}
+
+// 解析加点调整记录,每行一条,格式为 "生效日期,调整后的加点",如 "2023-09-25,-0.3"
+// 加点单位和 plusSpread 一致
+func (v InputValidator) parseSpreadChanges(text string, unit string) ([]loan.SpreadChange, error) {
+	lines, err := parseDatedLines(text, "spreadChanges")
+	if err != nil {
+		return nil, err
+	}
+	spreadChanges := make([]loan.SpreadChange, 0, len(lines))
+	for _, line := range lines {
+		plusSpread, err := v.parseSpread(line.value, unit)
+		if err != nil {
+			return nil, errors.New("Invalid spreadChanges: plusSpread " + err.Error())
+		}
+		spreadChanges = append(spreadChanges, loan.SpreadChange{Date: line.date, PlusSpread: plusSpread})
+	}
+	return spreadChanges, nil
+}
+
+// 解析实际还款记录,每行一条,格式为 "还款日期,还款金额",如 "2022-06-18,4919.4"
+func parseActualPayments(text string) ([]loan.ActualPayment, error) {
+	lines, err := parseDatedLines(text, "actualPayments")
+	if err != nil {
+		return nil, err
+	}
+	actualPayments := make([]loan.ActualPayment, 0, len(lines))
+	for _, line := range lines {
+		amount, err := decimal.NewFromString(line.value)
+		if err != nil || amount.IsNegative() {
+			return nil, errors.New("Invalid actualPayments: amount should be a positive number")
+		}
+		actualPayments = append(actualPayments, loan.ActualPayment{Date: line.date, Amount: amount})
+	}
+	return actualPayments, nil
+}
+
+// 解析提前还款违约金规则,每行一条,格式为 "月数,百分比,利息月数",如 "12,1,0" 表示一年内提前还款收取1%
+func parsePrepaymentPenalties(text string) ([]loan.PrepaymentPenaltyRule, error) {
+	rules := make([]loan.PrepaymentPenaltyRule, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			return nil, errors.New("Invalid prepaymentPenalties: each line should be months,percent,interestMonths")
+		}
+		withinMonths, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil || withinMonths <= 0 {
+			return nil, errors.New("Invalid prepaymentPenalties: months should be a positive integer")
+		}
+		percent, err := decimal.NewFromString(strings.TrimSpace(fields[1]))
+		if err != nil || percent.IsNegative() || percent.GreaterThan(decimal.NewFromInt(100)) {
+			return nil, errors.New("Invalid prepaymentPenalties: percent should be between 0 and 100")
+		}
+		interestMonths, err := strconv.Atoi(strings.TrimSpace(fields[2]))
+		if err != nil || interestMonths < 0 {
+			return nil, errors.New("Invalid prepaymentPenalties: interestMonths should be a non-negative integer")
+		}
+		rules = append(rules, loan.PrepaymentPenaltyRule{WithinMonths: withinMonths, Percent: percent, InterestMonths: interestMonths})
+	}
+	return rules, nil
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+func TestParseSpread(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator InputValidator
+		value     string
+		unit      string
+		want      string // 百分点
+		err       bool
+	}{
+		{name: "percent", validator: NewInputValidator(0, 0), value: "0.35", unit: SpreadUnitPercent, want: "0.35"},
+		{name: "default unit", validator: NewInputValidator(0, 0), value: " -0.3 ", want: "-0.3"},
+		{name: "bp", validator: NewInputValidator(0, 0), value: "-30", unit: SpreadUnitBP, want: "-0.3"},
+		{name: "fractional bp", validator: NewInputValidator(0, 0), value: "2.5", unit: SpreadUnitBP, want: "0.025"},
+		{name: "at upper bound", validator: NewInputValidator(0, 0), value: "100", unit: SpreadUnitBP, want: "1"},
+		{name: "above default range", validator: NewInputValidator(0, 0), value: "1.01", unit: SpreadUnitPercent, err: true},
+		// 范围可以在配置中修改
+		{name: "configured range", validator: NewInputValidator(-50, 300), value: "2.5", unit: SpreadUnitPercent, want: "2.5"},
+		{name: "below configured range", validator: NewInputValidator(-50, 300), value: "-60", unit: SpreadUnitBP, err: true},
+		{name: "not a number", validator: NewInputValidator(0, 0), value: "abc", unit: SpreadUnitBP, err: true},
+		{name: "unknown unit", validator: NewInputValidator(0, 0), value: "10", unit: "basis", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.validator.parseSpread(tt.value, tt.unit)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("spread = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpreadChanges(t *testing.T) {
+	v := NewInputValidator(0, 0)
+	got, err := v.parseSpreadChanges("2023-09-25,-30\n2024-01-01,-40", SpreadUnitBP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []loan.SpreadChange{
+		{Date: loan.ParseDate("2023-09-25"), PlusSpread: decimal.NewFromFloat(-0.3)},
+		{Date: loan.ParseDate("2024-01-01"), PlusSpread: decimal.NewFromFloat(-0.4)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !got[i].Date.Equal(want[i].Date) || !got[i].PlusSpread.Equal(want[i].PlusSpread) {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	// 调整后的加点同样校验范围
+	if _, err := v.parseSpreadChanges("2023-09-25,-300", SpreadUnitBP); err == nil {
+		t.Error("expected an error for a spread change out of range")
+	}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// 模拟路径数上限,避免单个请求占用过长时间
+const maxMonteCarloPaths = 10000
+
+// ValidateMonteCarlo 读取随机游走模型和模拟参数,概率按百分比输入
+func (v InputValidator) ValidateMonteCarlo(c *gin.Context) (loan.MonteCarloConfig, error) {
+	paths, err := strconv.Atoi(c.DefaultPostForm("mcPaths", "1000"))
+	if err != nil || paths < 1 || paths > maxMonteCarloPaths {
+		return loan.MonteCarloConfig{}, errors.New("Invalid mcPaths: it should be between 1 and 10000")
+	}
+	seed, err := strconv.ParseInt(c.DefaultPostForm("mcSeed", "1"), 10, 64)
+	if err != nil {
+		return loan.MonteCarloConfig{}, errors.New("Invalid mcSeed: it should be an integer")
+	}
+	stepBP, err := decimal.NewFromString(c.DefaultPostForm("mcStepBP", "5"))
+	if err != nil || !stepBP.IsPositive() || stepBP.GreaterThan(decimal.NewFromInt(100)) {
+		return loan.MonteCarloConfig{}, errors.New("Invalid mcStepBP: it should be between 0 and 100")
+	}
+	upProb, err := strconv.ParseFloat(c.DefaultPostForm("mcUpProb", "10"), 64)
+	if err != nil || upProb < 0 || upProb > 100 {
+		return loan.MonteCarloConfig{}, errors.New("Invalid mcUpProb: it should be between 0 and 100")
+	}
+	downProb, err := strconv.ParseFloat(c.DefaultPostForm("mcDownProb", "10"), 64)
+	if err != nil || downProb < 0 || upProb+downProb > 100 {
+		return loan.MonteCarloConfig{}, errors.New("Invalid mcDownProb: mcUpProb+mcDownProb should not exceed 100")
+	}
+	minLPR, err := parseLPR(c.DefaultPostForm("mcMinLPR", "2"))
+	if err != nil {
+		return loan.MonteCarloConfig{}, errors.New("Invalid mcMinLPR: " + err.Error())
+	}
+	maxLPR, err := parseLPR(c.DefaultPostForm("mcMaxLPR", "8"))
+	if err != nil || maxLPR.LessThan(minLPR) {
+		return loan.MonteCarloConfig{}, errors.New("Invalid mcMaxLPR: it should be between mcMinLPR and 20")
+	}
+
+	return loan.MonteCarloConfig{
+		Model: loan.RandomWalkModel{
+			StepBP:   stepBP,
+			UpProb:   upProb / 100,
+			DownProb: downProb / 100,
+			MinLPR:   minLPR,
+			MaxLPR:   maxLPR,
+		},
+		Paths: paths,
+		Seed:  seed,
+	}, nil
+}
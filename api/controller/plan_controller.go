@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// ValidatePlan 读取提前还款预算和银行限制
+// 一次性资金每行一条,格式为 "到账日期,金额",如 "2024-02-01,100000"
+func (v InputValidator) ValidatePlan(c *gin.Context) (loan.PrepaymentBudget, error) {
+	monthlySurplus, err := decimal.NewFromString(c.DefaultPostForm("monthlySurplus", "0"))
+	if err != nil || monthlySurplus.IsNegative() {
+		return loan.PrepaymentBudget{}, errors.New("Invalid monthlySurplus: it should be a positive number")
+	}
+	lines, err := parseDatedLines(c.DefaultPostForm("lumpSums", ""), "lumpSums")
+	if err != nil {
+		return loan.PrepaymentBudget{}, err
+	}
+	lumpSums := make([]loan.LumpSum, 0, len(lines))
+	for _, line := range lines {
+		amount, err := decimal.NewFromString(line.value)
+		if err != nil || !amount.IsPositive() {
+			return loan.PrepaymentBudget{}, errors.New("Invalid lumpSums: amount should be a positive number")
+		}
+		lumpSums = append(lumpSums, loan.LumpSum{Date: line.date, Amount: amount})
+	}
+	if !monthlySurplus.IsPositive() && len(lumpSums) == 0 {
+		return loan.PrepaymentBudget{}, errors.New("Invalid budget: monthlySurplus or lumpSums is required")
+	}
+	minAmount, err := decimal.NewFromString(c.DefaultPostForm("prepayMinAmount", "0"))
+	if err != nil || minAmount.IsNegative() {
+		return loan.PrepaymentBudget{}, errors.New("Invalid prepayMinAmount: it should be a positive number")
+	}
+	minIntervalMonths, err := strconv.Atoi(c.DefaultPostForm("prepayIntervalMonths", "0"))
+	if err != nil || minIntervalMonths < 0 {
+		return loan.PrepaymentBudget{}, errors.New("Invalid prepayIntervalMonths: it should be a positive integer")
+	}
+	objective := c.DefaultPostForm("planObjective", loan.PlanObjectiveInterest)
+	if objective != loan.PlanObjectiveInterest && objective != loan.PlanObjectivePayoff {
+		return loan.PrepaymentBudget{}, errors.New("Invalid planObjective: it should be interest or payoff")
+	}
+
+	return loan.PrepaymentBudget{
+		MonthlySurplus:    monthlySurplus,
+		LumpSums:          lumpSums,
+		MinAmount:         minAmount,
+		MinIntervalMonths: minIntervalMonths,
+		Objective:         objective,
+	}, nil
+}
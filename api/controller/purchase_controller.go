@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/purchase"
+	"github.com/shopspring/decimal"
+)
+
+// 房屋状态
+const (
+	HouseNew      = "new"      // 一手房
+	HouseUnderTwo = "under2"   // 二手房不满两年
+	HouseOverTwo  = "over2"    // 二手房满两年
+	HouseFiveOnly = "fiveonly" // 二手房满五唯一
+)
+
+// ValidatePurchase 读取成交价,套数,面积,房屋状态和费用,百分比按%输入
+func (v InputValidator) ValidatePurchase(c *gin.Context) (purchase.Input, error) {
+	price, err := decimal.NewFromString(c.DefaultPostForm("housePrice", "0"))
+	if err != nil || !price.IsPositive() || price.GreaterThan(decimal.NewFromInt(1000000000)) {
+		return purchase.Input{}, errors.New("Invalid housePrice: it should be between 0 and 1000000000")
+	}
+	homeType := c.DefaultPostForm("homeType", purchase.FirstHome)
+	if homeType != purchase.FirstHome && homeType != purchase.SecondHome {
+		return purchase.Input{}, errors.New("Invalid homeType: it should be first or second")
+	}
+	area, err := decimal.NewFromString(c.DefaultPostForm("houseArea", "0"))
+	if err != nil || !area.IsPositive() {
+		return purchase.Input{}, errors.New("Invalid houseArea: it should be a positive number")
+	}
+	houseStatus := c.DefaultPostForm("houseStatus", HouseNew)
+	switch houseStatus {
+	case HouseNew, HouseUnderTwo, HouseOverTwo, HouseFiveOnly:
+	default:
+		return purchase.Input{}, errors.New("Invalid houseStatus: it should be new, under2, over2 or fiveonly")
+	}
+	downPaymentRatio, err := decimal.NewFromString(c.DefaultPostForm("downPaymentRatio", "0"))
+	if err != nil || downPaymentRatio.IsNegative() || downPaymentRatio.GreaterThan(decimal.NewFromInt(100)) {
+		return purchase.Input{}, errors.New("Invalid downPaymentRatio: it should be between 0 and 100")
+	}
+	agencyFeeRate, err := decimal.NewFromString(c.DefaultPostForm("agencyFeeRate", "0"))
+	if err != nil || agencyFeeRate.IsNegative() || agencyFeeRate.GreaterThan(decimal.NewFromInt(10)) {
+		return purchase.Input{}, errors.New("Invalid agencyFeeRate: it should be between 0 and 10")
+	}
+	otherFees, err := decimal.NewFromString(c.DefaultPostForm("otherFees", "0"))
+	if err != nil || otherFees.IsNegative() {
+		return purchase.Input{}, errors.New("Invalid otherFees: it should be a positive number")
+	}
+
+	return purchase.Input{
+		Price:            price,
+		HomeType:         homeType,
+		Area:             area,
+		NewHome:          houseStatus == HouseNew,
+		OverTwoYears:     houseStatus == HouseOverTwo,
+		FiveYearsOnly:    houseStatus == HouseFiveOnly,
+		DownPaymentRatio: downPaymentRatio,
+		AgencyFeeRate:    agencyFeeRate,
+		OtherFees:        otherFees,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// ReconcileInput 对账参数
+type ReconcileInput struct {
+	Transactions []loan.BankTransaction // 银行实际扣款记录
+	Tolerance    decimal.Decimal        // 允许的差异
+	Format       string                 // 输出格式 json 或 html
+}
+
+// ValidateReconcile 读取上传的银行扣款csv和对账参数
+func (v InputValidator) ValidateReconcile(c *gin.Context) (ReconcileInput, error) {
+	format := c.DefaultPostForm("format", "html")
+	if format != "json" && format != "html" {
+		return ReconcileInput{}, errors.New("Invalid format: it should be json or html")
+	}
+	tolerance, err := decimal.NewFromString(c.DefaultPostForm("tolerance", "0.01"))
+	if err != nil || tolerance.IsNegative() {
+		return ReconcileInput{}, errors.New("Invalid tolerance: it should be a positive number")
+	}
+
+	fileHeader, err := c.FormFile("bankCsv")
+	if err != nil {
+		return ReconcileInput{}, errors.New("Invalid bankCsv: a csv file is required")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ReconcileInput{}, errors.New("Invalid bankCsv: " + err.Error())
+	}
+	defer file.Close()
+	transactions, err := loan.ParseBankTransactions(file)
+	if err != nil {
+		return ReconcileInput{}, errors.New("Invalid bankCsv: " + err.Error())
+	}
+
+	return ReconcileInput{Transactions: transactions, Tolerance: tolerance, Format: format}, nil
+}
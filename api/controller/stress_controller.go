@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// 未填写LPR路径时,测试持平和每年上下25个基点
+const defaultLPRPaths = "持平,flat,\n每年+25bp,step,25\n每年-25bp,step,-25"
+
+// ValidateStress 解析LPR远期路径,每行一条,格式为 "名称,类型,参数"
+// flat: 参数为远期LPR(%),为空时沿用最新LPR
+// step: 参数为每年变动的基点,可为负数
+// custom: 参数为第1年,第2年...的LPR(%),用空格分隔,如 "3.95 3.85 3.75"
+func (v InputValidator) ValidateStress(c *gin.Context) ([]loan.LPRPath, error) {
+	text := strings.TrimSpace(c.DefaultPostForm("lprPaths", ""))
+	if text == "" {
+		text = defaultLPRPaths
+	}
+
+	paths := make([]loan.LPRPath, 0)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Invalid lprPaths line %d: it should be name,kind,value", i+1)
+		}
+		for j := range fields {
+			fields[j] = strings.TrimSpace(fields[j])
+		}
+
+		path := loan.LPRPath{Name: fields[0], Kind: fields[1]}
+		if path.Name == "" {
+			path.Name = path.Kind
+		}
+		switch path.Kind {
+		case loan.LPRPathFlat:
+			if fields[2] != "" {
+				rate, err := parseLPR(fields[2])
+				if err != nil {
+					return nil, fmt.Errorf("Invalid lprPaths line %d: %s", i+1, err.Error())
+				}
+				path.Rate = rate
+			}
+		case loan.LPRPathStep:
+			stepBP, err := decimal.NewFromString(fields[2])
+			if err != nil || stepBP.Abs().GreaterThan(decimal.NewFromInt(200)) {
+				return nil, fmt.Errorf("Invalid lprPaths line %d: step should be between -200bp and 200bp", i+1)
+			}
+			path.StepBP = stepBP
+		case loan.LPRPathCustom:
+			for _, value := range strings.Fields(fields[2]) {
+				rate, err := parseLPR(value)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid lprPaths line %d: %s", i+1, err.Error())
+				}
+				path.Rates = append(path.Rates, rate)
+			}
+			if len(path.Rates) == 0 {
+				return nil, fmt.Errorf("Invalid lprPaths line %d: custom path needs at least one rate", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("Invalid lprPaths line %d: kind should be flat, step or custom", i+1)
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("Invalid lprPaths: at least one path is required")
+	}
+	return paths, nil
+}
+
+// LPR 在0到20之间(%)
+func parseLPR(value string) (decimal.Decimal, error) {
+	rate, err := decimal.NewFromString(value)
+	if err != nil || rate.IsNegative() || rate.GreaterThan(decimal.NewFromInt(20)) {
+		return decimal.Zero, errors.New("lpr should be between 0 and 20")
+	}
+	return rate, nil
+}
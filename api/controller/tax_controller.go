@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// TaxDeductionInput 住房贷款利息扣除参数
+type TaxDeductionInput struct {
+	Params loan.TaxDeductionParams
+	Format string // 输出格式 json 或 csv,只用于接口,表单字段为 taxFormat(format 已用于对账)
+}
+
+// 综合所得适用的税率(%)
+var marginalTaxRates = []int64{3, 10, 20, 25, 30, 35, 45}
+
+func parseMarginalRate(value string) (decimal.Decimal, bool) {
+	rate, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, false
+	}
+	for _, r := range marginalTaxRates {
+		if rate.Equal(decimal.NewFromInt(r)) {
+			return rate, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// ValidateTaxDeduction 读取扣除方式,夫妻双方的边际税率和输出格式
+func (v InputValidator) ValidateTaxDeduction(c *gin.Context) (TaxDeductionInput, error) {
+	format := c.DefaultPostForm("taxFormat", "json")
+	if format != "json" && format != "csv" {
+		return TaxDeductionInput{}, errors.New("Invalid taxFormat: it should be json or csv")
+	}
+	taxDeductionSplit := c.DefaultPostForm("taxDeductionSplit", "self")
+	if taxDeductionSplit != "self" && taxDeductionSplit != "split" {
+		return TaxDeductionInput{}, errors.New("Invalid taxDeductionSplit: it should be self or split")
+	}
+	marginalRate, ok := parseMarginalRate(c.DefaultPostForm("marginalTaxRate", "10"))
+	if !ok {
+		return TaxDeductionInput{}, errors.New("Invalid marginalTaxRate: it should be one of 3, 10, 20, 25, 30, 35, 45")
+	}
+	spouseMarginalRate, ok := parseMarginalRate(c.DefaultPostForm("spouseMarginalTaxRate", "10"))
+	if !ok {
+		return TaxDeductionInput{}, errors.New("Invalid spouseMarginalTaxRate: it should be one of 3, 10, 20, 25, 30, 35, 45")
+	}
+
+	return TaxDeductionInput{
+		Params: loan.TaxDeductionParams{
+			SpouseSplit:        taxDeductionSplit == "split",
+			MarginalRate:       marginalRate,
+			SpouseMarginalRate: spouseMarginalRate,
+		},
+		Format: format,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 可贷额度测算,json 为 true 时返回json,否则渲染页面
+func handleAffordRequest(validator controller.InputValidator, json bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		params, err := validator.ValidateAffordability(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		affordability, err := loan.MaxAffordablePrincipal(inputData, action, params)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if json {
+			c.JSON(http.StatusOK, affordability)
+			return
+		}
+		c.HTML(http.StatusOK, "afford.tmpl", gin.H{
+			"Params":        params,
+			"Affordability": affordability,
+			"LoanTerm":      inputData.Loan.InitialTerm,
+			"Action":        action,
+		})
+	}
+}
+
+func AffordRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 按收入和负债收入比测算最高贷款额度
+	group.POST("/loan/afford", handleAffordRequest(validator, false))
+	group.POST("/api/loan/afford", handleAffordRequest(validator, true))
+}
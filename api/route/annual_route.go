@@ -0,0 +1,31 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+func handleAnnualRequest(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reports := loan.BuildReport(inputData, action)
+		c.JSON(http.StatusOK, inputData.Loan.BuildAnnualStatements(reports))
+	}
+}
+
+func AnnualRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 按自然年和贷款年度汇总
+	group.POST("/api/loan/annual", handleAnnualRequest(validator))
+}
@@ -0,0 +1,104 @@
+package route
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 订阅链接中保存的表单字段,即还款计划和日历选项
+// 需要和 Validate,ValidateCalendar 读取的字段一致,由测试检查
+var calendarFields = []string{
+	"action", "principal", "loanTerm", "startDate", "plusSpread", "plusSpreadUnit", "paymentDueDay",
+	"balloonAmount", "fixedRate", "fixedYears", "rateFloor", "rateCap", "spreadChanges",
+	"penaltyRateMarkup", "actualPayments", "prepaymentPenalties", "payoffDate",
+	"earlyRepayment1Amount", "earlyRepayment1Date", "earlyRepayment2Amount", "earlyRepayment2Date",
+	"earlyRepayment3Amount", "earlyRepayment3Date", "earlyRepaymentMode",
+	"icsAlarms", "businessDay",
+}
+
+// 表单参数编码为订阅链接的 token,字段按名称排序,相同参数得到相同的链接
+func calendarToken(form url.Values) string {
+	values := url.Values{}
+	for _, field := range calendarFields {
+		if value, ok := form[field]; ok {
+			values[field] = value
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(values.Encode()))
+}
+
+// 订阅链接的完整地址
+func calendarURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/loan/ics/" + calendarToken(c.Request.PostForm)
+}
+
+// 生成还款日历,download 为 true 时作为附件下载
+func writeCalendar(c *gin.Context, validator controller.InputValidator, download bool) {
+	inputData, err, action := validator.Validate(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	options, err := validator.ValidateCalendar(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options.Name = "房贷还款"
+	options.ID = calendarToken(c.Request.PostForm)
+	options.Generated = time.Now()
+	if download {
+		c.Header("Content-Disposition", `attachment; filename="loan_`+action+`.ics"`)
+	}
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := loan.WriteICalendar(c.Writer, loan.BuildReport(inputData, action), options); err != nil {
+		c.Error(err)
+	}
+}
+
+// 下载.ics文件
+func handleCalendarRequest(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writeCalendar(c, validator, true)
+	}
+}
+
+// 订阅链接,token 解码后作为表单参数重新计算
+func handleCalendarSubscription(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, err := base64.RawURLEncoding.DecodeString(c.Param("token"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token: it should be base64url encoded"})
+			return
+		}
+		form, err := url.ParseQuery(string(query))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token: " + err.Error()})
+			return
+		}
+		c.Request.PostForm = form
+		writeCalendar(c, validator, false)
+	}
+}
+
+func CalendarRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 导出还款日历
+	group.POST("/api/loan/ics", handleCalendarRequest(validator))
+	// 订阅还款日历
+	group.GET("/api/loan/ics/:token", handleCalendarSubscription(validator))
+}
@@ -0,0 +1,54 @@
+package route
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 校验器读取的表单字段,从源码中 PostForm 系列调用的字段名收集
+func validatorFields(t *testing.T, files ...string) []string {
+	t.Helper()
+	seen := make(map[string]bool)
+	for _, file := range files {
+		parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(parsed, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !strings.Contains(selector.Sel.Name, "PostForm") {
+				return true
+			}
+			if literal, ok := call.Args[0].(*ast.BasicLit); ok && literal.Kind == token.STRING {
+				name, _ := strconv.Unquote(literal.Value)
+				seen[name] = true
+			}
+			return true
+		})
+	}
+	fields := make([]string, 0, len(seen))
+	for name := range seen {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestCalendarFields(t *testing.T) {
+	// 订阅链接重新计算时使用 Validate 和 ValidateCalendar,字段需要全部保存在 token 中
+	want := validatorFields(t, "../controller/loan_controller.go", "../controller/calendar_controller.go")
+	got := append([]string(nil), calendarFields...)
+	sort.Strings(got)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("calendarFields = %v\nvalidator reads %v", got, want)
+	}
+}
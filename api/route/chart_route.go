@@ -0,0 +1,56 @@
+package route
+
+import (
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/chart"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 页面中的图表,svg直接嵌入,下载链接为data url,不依赖外部资源
+func chartViews(reports []loan.Report) []gin.H {
+	charts := chart.RenderAll(reports)
+	views := make([]gin.H, 0, len(charts))
+	for _, c := range charts {
+		views = append(views, gin.H{
+			"Name":  c.Name,
+			"Title": c.Title,
+			"SVG":   template.HTML(c.SVG),
+			"Href":  template.URL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(c.SVG))),
+		})
+	}
+	return views
+}
+
+// 下载单个svg图表
+func handleChartRequest(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reports := loan.BuildReport(inputData, action)
+		svg, err := chart.Render(c.DefaultPostForm("chart", chart.Balance), reports)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+svg.Name+`.svg"`)
+		c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(svg.SVG))
+	}
+}
+
+func ChartRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// svg图表
+	group.POST("/api/loan/chart", handleChartRequest(validator))
+}
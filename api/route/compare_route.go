@@ -0,0 +1,42 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 对比多个方案,json 为 true 时返回json,否则渲染页面
+func handleCompareRequest(validator controller.InputValidator, json bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, _ := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		scenarios, err := validator.ValidateCompare(c, inputData)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		comparison := loan.Compare(scenarios)
+		if json {
+			c.JSON(http.StatusOK, comparison)
+			return
+		}
+		c.HTML(http.StatusOK, "compare.tmpl", comparison)
+	}
+}
+
+func CompareRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 多方案对比
+	group.POST("/loan/compare", handleCompareRequest(validator, false))
+	group.POST("/api/loan/compare", handleCompareRequest(validator, true))
+}
@@ -0,0 +1,44 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 导出还款计划和年度汇总,每个一个工作表
+func handleExportRequest(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reports := loan.BuildReport(inputData, action)
+		annual := inputData.Loan.BuildAnnualStatements(reports)
+		sheets := []loan.Sheet{
+			loan.ReportSheet(reports),
+			loan.AnnualSheet("自然年度", annual.CalendarYears),
+			loan.AnnualSheet("贷款年度", annual.LoanYears),
+		}
+
+		c.Header("Content-Disposition", `attachment; filename="loan_`+action+`.xls"`)
+		c.Header("Content-Type", "application/vnd.ms-excel")
+		c.Status(http.StatusOK)
+		if err := loan.WriteWorkbook(c.Writer, sheets); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+func ExportRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 导出excel
+	group.POST("/api/loan/export", handleExportRequest(validator))
+}
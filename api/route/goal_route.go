@@ -0,0 +1,50 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 反向求解期限,加点或本金,json 为 true 时返回json,否则渲染页面
+func handleGoalRequest(validator controller.InputValidator, json bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		goal, err := validator.ValidateGoal(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := loan.SeekGoal(inputData, action, goal)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if json {
+			c.JSON(http.StatusOK, result)
+			return
+		}
+		c.HTML(http.StatusOK, "goal.tmpl", gin.H{
+			"Goal":   goal,
+			"Result": result,
+			"Action": action,
+		})
+	}
+}
+
+func GoalRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 按目标月供,利息或结清日期反向求解
+	group.POST("/loan/goal", handleGoalRequest(validator, false))
+	group.POST("/api/loan/goal", handleGoalRequest(validator, true))
+}
@@ -0,0 +1,46 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 提前还款与投资对比,json 为 true 时返回json,否则渲染页面
+func handleInvestRequest(validator controller.InputValidator, json bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		investInput, err := validator.ValidateInvest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		analysis, err := loan.AnalyzePrepayVsInvest(inputData, action, investInput.Candidate, investInput.AnnualReturn, investInput.TaxRate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if json {
+			c.JSON(http.StatusOK, analysis)
+			return
+		}
+		c.HTML(http.StatusOK, "invest.tmpl", analysis)
+	}
+}
+
+func InvestRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 提前还款还是投资
+	group.POST("/loan/invest", handleInvestRequest(validator, false))
+	group.POST("/api/loan/invest", handleInvestRequest(validator, true))
+}
@@ -2,61 +2,192 @@ package route
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
 	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
 	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
 )
 
-func renderTemplate(c *gin.Context, inputData loan.Input, report string) {
-	c.HTML(http.StatusOK, "loan.tmpl", gin.H{
+// 渲染页面,result 为计算结果,与表单回显的数据合并
+func renderTemplate(c *gin.Context, validator controller.InputValidator, inputData loan.Input, result gin.H) {
+	// 加点按用户选择的单位回显
+	plusSpreadUnit := c.DefaultPostForm("plusSpreadUnit", controller.SpreadUnitPercent)
+	data := gin.H{
 		"Principal":             inputData.Loan.InitialPrincipal,
 		"LoanTerm":              inputData.Loan.InitialTerm,
 		"StartDate":             inputData.Loan.InitialDate.Format("2006-01-02"),
-		"PlusSpread":            inputData.Loan.PlusSpread,
+		"PlusSpread":            spreadInUnit(inputData.Loan.PlusSpread, plusSpreadUnit),
+		"PlusSpreadUnit":        plusSpreadUnit,
+		"SpreadMinBP":           validator.SpreadMinBP,
+		"SpreadMaxBP":           validator.SpreadMaxBP,
+		"CurrentLPR":            inputData.Loan.LPRAt(inputData.Loan.InitialDate),
+		"EffectiveRate":         inputData.Loan.EffectiveRate(inputData.Loan.InitialDate),
 		"PaymentDueDay":         inputData.Loan.PaymentDueDay,
+		"BalloonAmount":         inputData.Loan.BalloonAmount,
+		"FixedRate":             inputData.Loan.FixedRate,
+		"FixedYears":            inputData.Loan.FixedYears,
+		"SpreadChanges":         formatSpreadChanges(inputData.Loan.SpreadChanges, plusSpreadUnit),
+		"RateFloor":             inputData.Loan.RateFloor,
+		"RateCap":               inputData.Loan.RateCap,
+		"PenaltyRateMarkup":     inputData.Loan.PenaltyRateMarkup.Mul(decimal.NewFromInt(100)),
+		"ActualPayments":        formatActualPayments(inputData.ActualPayments),
+		"PrepaymentPenalties":   formatPrepaymentPenalties(inputData.Loan.PrepaymentPenalties),
 		"earlyRepayment1Amount": inputData.EarlyRepayment[0].Amount,
 		"earlyRepayment1Date":   inputData.EarlyRepayment[0].Date.Format("2006-01-02"),
 		"earlyRepayment2Amount": inputData.EarlyRepayment[1].Amount,
 		"earlyRepayment2Date":   inputData.EarlyRepayment[1].Date.Format("2006-01-02"),
 		"earlyRepayment3Amount": inputData.EarlyRepayment[2].Amount,
 		"earlyRepayment3Date":   inputData.EarlyRepayment[2].Date.Format("2006-01-02"),
-		"Report":                report, // 报表结果
-	})
+		"EarlyRepaymentMode":    earlyRepaymentMode(inputData.EarlyRepayment),
+		"PayoffDate":            formatDate(inputData.PayoffDate),
+		"Scenarios":             c.DefaultPostForm("scenarios", ""),
+		"LPRPaths":              c.DefaultPostForm("lprPaths", ""),
+		"McPaths":               c.DefaultPostForm("mcPaths", "1000"),
+		"McSeed":                c.DefaultPostForm("mcSeed", "1"),
+		"McStepBP":              c.DefaultPostForm("mcStepBP", "5"),
+		"McUpProb":              c.DefaultPostForm("mcUpProb", "10"),
+		"McDownProb":            c.DefaultPostForm("mcDownProb", "10"),
+		"McMinLPR":              c.DefaultPostForm("mcMinLPR", "2"),
+		"McMaxLPR":              c.DefaultPostForm("mcMaxLPR", "8"),
+		"MonthlyIncome":         c.DefaultPostForm("monthlyIncome", ""),
+		"MonthlyDebts":          c.DefaultPostForm("monthlyDebts", "0"),
+		"MaxDTI":                c.DefaultPostForm("maxDTI", "50"),
+		"StressBP":              c.DefaultPostForm("stressBP", "100"),
+		"GoalVariable":          c.DefaultPostForm("goalVariable", loan.GoalVariableTerm),
+		"GoalTarget":            c.DefaultPostForm("goalTarget", loan.GoalTargetInstallment),
+		"GoalValue":             c.DefaultPostForm("goalValue", ""),
+		"GoalDate":              c.DefaultPostForm("goalDate", ""),
+		"GoalMin":               c.DefaultPostForm("goalMin", ""),
+		"GoalMax":               c.DefaultPostForm("goalMax", ""),
+		"HousePrice":            c.DefaultPostForm("housePrice", ""),
+		"HomeType":              c.DefaultPostForm("homeType", "first"),
+		"HouseArea":             c.DefaultPostForm("houseArea", ""),
+		"HouseStatus":           c.DefaultPostForm("houseStatus", controller.HouseNew),
+		"DownPaymentRatio":      c.DefaultPostForm("downPaymentRatio", ""),
+		"AgencyFeeRate":         c.DefaultPostForm("agencyFeeRate", "0"),
+		"OtherFees":             c.DefaultPostForm("otherFees", "0"),
+		"TaxDeductionSplit":     c.DefaultPostForm("taxDeductionSplit", "self"),
+		"MarginalTaxRate":       c.DefaultPostForm("marginalTaxRate", "10"),
+		"SpouseMarginalTaxRate": c.DefaultPostForm("spouseMarginalTaxRate", "10"),
+		"CandidateAmount":       c.DefaultPostForm("candidateAmount", ""),
+		"CandidateDate":         c.DefaultPostForm("candidateDate", ""),
+		"InvestReturn":          c.DefaultPostForm("investReturn", "3"),
+		"InvestTaxRate":         c.DefaultPostForm("investTaxRate", "0"),
+		"MonthlySurplus":        c.DefaultPostForm("monthlySurplus", ""),
+		"LumpSums":              c.DefaultPostForm("lumpSums", ""),
+		"PrepayMinAmount":       c.DefaultPostForm("prepayMinAmount", ""),
+		"PrepayIntervalMonths":  c.DefaultPostForm("prepayIntervalMonths", ""),
+		"PlanObjective":         c.DefaultPostForm("planObjective", loan.PlanObjectiveInterest),
+		"IcsAlarms":             c.DefaultPostForm("icsAlarms", "1d"),
+		"BusinessDay":           c.DefaultPostForm("businessDay", "off"),
+	}
+	for key, value := range result {
+		data[key] = value
+	}
+	c.HTML(http.StatusOK, "loan.tmpl", data)
+}
+
+// 提前还款方式还原为表单的选项
+func earlyRepaymentMode(earlyRepayments []loan.EarlyRepayment) string {
+	if len(earlyRepayments) > 0 && earlyRepayments[0].KeepInstallment {
+		return controller.EarlyRepaymentKeep
+	}
+	return controller.EarlyRepaymentReduce
+}
+
+// 空日期显示为空字符串
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// 百分点表示的加点转换为页面显示的单位
+func spreadInUnit(plusSpread decimal.Decimal, unit string) decimal.Decimal {
+	if unit == controller.SpreadUnitBP {
+		return plusSpread.Mul(decimal.NewFromInt(100))
+	}
+	return plusSpread
 }
 
-func handleGETRequest(c *gin.Context) {
-	validator := controller.InputValidator{}
-	inputData, err, _ := validator.Validate(c)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// 加点调整记录还原为表单的多行文本
+func formatSpreadChanges(spreadChanges []loan.SpreadChange, unit string) string {
+	lines := make([]string, 0, len(spreadChanges))
+	for _, change := range spreadChanges {
+		lines = append(lines, change.Date.Format("2006-01-02")+","+spreadInUnit(change.PlusSpread, unit).String())
 	}
+	return strings.Join(lines, "\n")
+}
+
+// 实际还款记录还原为表单的多行文本
+func formatActualPayments(actualPayments []loan.ActualPayment) string {
+	lines := make([]string, 0, len(actualPayments))
+	for _, payment := range actualPayments {
+		lines = append(lines, payment.Date.Format("2006-01-02")+","+payment.Amount.String())
+	}
+	return strings.Join(lines, "\n")
+}
 
-	renderTemplate(c, inputData, "")
+// 提前还款违约金规则还原为表单的多行文本
+func formatPrepaymentPenalties(rules []loan.PrepaymentPenaltyRule) string {
+	lines := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		lines = append(lines, strconv.Itoa(rule.WithinMonths)+","+rule.Percent.String()+","+strconv.Itoa(rule.InterestMonths))
+	}
+	return strings.Join(lines, "\n")
 }
 
-func handlePOSTRequest(c *gin.Context) {
-	validator := controller.InputValidator{}
-	inputData, err, action := validator.Validate(c)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func handleGETRequest(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, _ := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		return
+		renderTemplate(c, validator, inputData, nil)
 	}
+}
 
-	report := loan.LoanPrintTable(inputData, action)
+func handlePOSTRequest(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 
-	// 使用 renderTemplate 函数渲染模板
-	renderTemplate(c, inputData, report)
+			return
+		}
+
+		reports := loan.BuildReport(inputData, action)
+		result := gin.H{
+			"Reports": reports, // 报表结果
+			"Summary": loan.BuildSummary(inputData, action, reports),
+			"Annual":  inputData.Loan.BuildAnnualStatements(reports),
+			"Charts":  chartViews(reports),
+			// 还款日历的订阅链接
+			"CalendarURL": calendarURL(c),
+		}
+		if !inputData.PayoffDate.IsZero() {
+			result["Payoff"] = inputData.PayoffQuote(inputData.PayoffDate, action)
+		}
+
+		// 使用 renderTemplate 函数渲染模板
+		renderTemplate(c, validator, inputData, result)
+	}
 }
 
 func LoanRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
-	// 计算还款计划
+	// 加点范围由配置决定
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
 
-	group.GET("/loan", handleGETRequest)
-	group.POST("/loan", handlePOSTRequest)
+	// 计算还款计划
+	group.GET("/loan", handleGETRequest(validator))
+	group.POST("/loan", handlePOSTRequest(validator))
 
 }
@@ -0,0 +1,55 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// LPR 蒙特卡洛模拟,json 为 true 时返回json,否则渲染页面
+// 模拟在 timeout 内没有完成时返回503,提示减少路径数
+func handleMonteCarloRequest(validator controller.InputValidator, timeout time.Duration, json bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		config, err := validator.ValidateMonteCarlo(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		result, err := loan.SimulateLPR(ctx, inputData, action, config)
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "simulation timed out: try fewer mcPaths"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if json {
+			c.JSON(http.StatusOK, result)
+			return
+		}
+		c.HTML(http.StatusOK, "montecarlo.tmpl", result)
+	}
+}
+
+func MonteCarloRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// LPR 随机路径模拟
+	group.POST("/loan/montecarlo", handleMonteCarloRequest(validator, timeout, false))
+	group.POST("/api/loan/montecarlo", handleMonteCarloRequest(validator, timeout, true))
+}
@@ -0,0 +1,45 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+)
+
+func TestMonteCarloTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	form := url.Values{
+		"action":        {"emi"},
+		"principal":     {"100000"},
+		"loanTerm":      {"12"},
+		"paymentDueDay": {"18"},
+		"mcPaths":       {"20"},
+	}
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		status  int
+	}{
+		{name: "within timeout", timeout: time.Minute, status: http.StatusOK},
+		{name: "timed out", timeout: 0, status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/api/loan/montecarlo", handleMonteCarloRequest(controller.NewInputValidator(0, 0), tt.timeout, true))
+			request := httptest.NewRequest(http.MethodPost, "/api/loan/montecarlo", strings.NewReader(form.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+)
+
+func handlePayoffRequest(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if inputData.PayoffDate.IsZero() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payoffDate: it cannot be empty"})
+			return
+		}
+
+		c.JSON(http.StatusOK, inputData.PayoffQuote(inputData.PayoffDate, action))
+	}
+}
+
+func PayoffRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 指定日期一次性结清的金额
+	group.POST("/api/loan/payoff", handlePayoffRequest(validator))
+}
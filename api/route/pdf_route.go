@@ -0,0 +1,74 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/pdf"
+)
+
+// 还款计划表pdf
+func handlePDFRequest(validator controller.InputValidator, font pdf.Font, bank string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reports := loan.BuildReport(inputData, action)
+		plan := pdf.Plan{
+			Bank:      bank,
+			Action:    action,
+			Input:     inputData,
+			Summary:   loan.BuildSummary(inputData, action, reports),
+			Reports:   reports,
+			Generated: time.Now(),
+		}
+		c.Header("Content-Disposition", `attachment; filename="loan_`+action+`.pdf"`)
+		c.Header("Content-Type", "application/pdf")
+		c.Status(http.StatusOK)
+		if err := pdf.WritePlan(c.Writer, font, plan); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// 载入pdf字体,需要包含中文字形
+func loadPDFFont(path string) (pdf.Font, error) {
+	if path == "" {
+		return nil, errors.New("PDF_FONT_PATH is not set: set it to a TrueType Chinese font (ttf/ttc) in .env")
+	}
+	font, err := pdf.LoadTrueType(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't load the pdf font PDF_FONT_PATH: %w", err)
+	}
+	if !font.Covers("还款计划表") {
+		return nil, errors.New("the pdf font PDF_FONT_PATH has no Chinese glyphs: " + path)
+	}
+	return font, nil
+}
+
+func PDFRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// pdf 嵌入中文字体,字体不可用时其他功能照常,导出pdf返回503
+	font, err := loadPDFFont(env.PDFFontPath)
+	if err != nil {
+		log.Println("PDF export is disabled: ", err)
+		group.POST("/api/loan/pdf", func(c *gin.Context) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "pdf export is unavailable: " + err.Error()})
+		})
+		return
+	}
+
+	// 导出pdf
+	group.POST("/api/loan/pdf", handlePDFRequest(validator, font, env.BankName))
+}
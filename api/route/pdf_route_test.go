@@ -0,0 +1,42 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+)
+
+// 字体不可用时服务照常启动,导出pdf返回503
+func TestPDFRouteWithoutFont(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "not set", path: "", want: "PDF_FONT_PATH is not set"},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.ttc"), want: "can't load the pdf font"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			PDFRoute(&bootstrap.Env{PDFFontPath: tt.path}, time.Second, router.Group(""))
+			request := httptest.NewRequest(http.MethodPost, "/api/loan/pdf", strings.NewReader("action=emi"))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want 503", recorder.Code)
+			}
+			if !strings.Contains(recorder.Body.String(), tt.want) {
+				t.Errorf("body = %s, want %q", recorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
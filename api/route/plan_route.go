@@ -0,0 +1,49 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 提前还款规划,json 为 true 时返回json,否则渲染页面
+func handlePlanRequest(validator controller.InputValidator, json bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		budget, err := validator.ValidatePlan(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		plan, err := loan.PlanPrepayments(inputData, action, budget)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if json {
+			c.JSON(http.StatusOK, plan)
+			return
+		}
+		c.HTML(http.StatusOK, "plan.tmpl", gin.H{
+			"Plan":    plan,
+			"Reports": plan.Reports,
+		})
+	}
+}
+
+func PlanRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 提前还款规划
+	group.POST("/loan/plan", handlePlanRequest(validator, false))
+	group.POST("/api/loan/plan", handlePlanRequest(validator, true))
+}
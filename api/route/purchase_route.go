@@ -0,0 +1,66 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/purchase"
+)
+
+// 购房费用测算,贷款金额作为本金生成还款计划,json 为 true 时返回json,否则渲染页面
+func handlePurchaseRequest(validator controller.InputValidator, json bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		purchaseInput, err := validator.ValidatePurchase(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		quote, err := purchase.DefaultRules.Calculate(purchaseInput)
+		if err == nil && !quote.LoanAmount.IsPositive() {
+			err = errors.New("loan amount is zero, no repayment schedule is needed")
+		}
+		if err == nil && inputData.Loan.BalloonAmount.GreaterThanOrEqual(quote.LoanAmount) {
+			err = errors.New("balloonAmount should be less than the loan amount")
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		inputData.Loan = quote.ApplyTo(inputData.Loan)
+		reports := loan.BuildReport(inputData, action)
+		summary := loan.BuildSummary(inputData, action, reports)
+		if json {
+			c.JSON(http.StatusOK, gin.H{
+				"Purchase": quote,
+				"Summary":  summary,
+				"Reports":  reports,
+			})
+			return
+		}
+		c.HTML(http.StatusOK, "purchase.tmpl", gin.H{
+			"Purchase": quote,
+			"Summary":  summary,
+			"Reports":  reports,
+		})
+	}
+}
+
+func PurchaseRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 购房费用和还款计划
+	group.POST("/loan/purchase", handlePurchaseRequest(validator, false))
+	group.POST("/api/loan/purchase", handlePurchaseRequest(validator, true))
+}
@@ -0,0 +1,45 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+func handleReconcileRequest(validator controller.InputValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reconcileInput, err := validator.ValidateReconcile(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		report := loan.BuildReport(inputData, action)
+		rows := loan.Reconcile(report, reconcileInput.Transactions, reconcileInput.Tolerance)
+
+		if reconcileInput.Format == "json" {
+			c.JSON(http.StatusOK, gin.H{"tolerance": reconcileInput.Tolerance, "rows": rows})
+			return
+		}
+		c.HTML(http.StatusOK, "reconcile.tmpl", gin.H{
+			"Tolerance": reconcileInput.Tolerance,
+			"Rows":      rows,
+		})
+	}
+}
+
+func ReconcileRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 上传银行扣款记录,与还款计划对账
+	group.POST("/loan/reconcile", handleReconcileRequest(validator))
+}
@@ -10,9 +10,27 @@ import (
 func Setup(env *bootstrap.Env, timeout time.Duration, gin *gin.Engine) {
 	// 载入assets
 	gin.Static("/static", "assets/static")
+	gin.SetFuncMap(templateFuncs)
 	gin.LoadHTMLGlob("assets/templates/*")
 	publicRouter := gin.Group("")
 	// All Public APIs
 	PingRoute(env, timeout, publicRouter)
 	LoanRoute(env, timeout, publicRouter)
+	ReconcileRoute(env, timeout, publicRouter)
+	PayoffRoute(env, timeout, publicRouter)
+	SummaryRoute(env, timeout, publicRouter)
+	CompareRoute(env, timeout, publicRouter)
+	InvestRoute(env, timeout, publicRouter)
+	PlanRoute(env, timeout, publicRouter)
+	StressRoute(env, timeout, publicRouter)
+	MonteCarloRoute(env, timeout, publicRouter)
+	AffordRoute(env, timeout, publicRouter)
+	GoalRoute(env, timeout, publicRouter)
+	PurchaseRoute(env, timeout, publicRouter)
+	TaxRoute(env, timeout, publicRouter)
+	AnnualRoute(env, timeout, publicRouter)
+	ExportRoute(env, timeout, publicRouter)
+	ChartRoute(env, timeout, publicRouter)
+	PDFRoute(env, timeout, publicRouter)
+	CalendarRoute(env, timeout, publicRouter)
 }
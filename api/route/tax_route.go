@@ -0,0 +1,59 @@
+package route
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/api/controller"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 个税住房贷款利息扣除,json 为 true 时按 taxFormat 返回json或csv,否则渲染页面
+func handleTaxDeductionRequest(validator controller.InputValidator, json bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		inputData, err, action := validator.Validate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		taxInput, err := validator.ValidateTaxDeduction(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reports := loan.BuildReport(inputData, action)
+		deduction, err := loan.BuildTaxDeduction(reports, taxInput.Params)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !json {
+			c.HTML(http.StatusOK, "tax.tmpl", gin.H{
+				"Params":    taxInput.Params,
+				"Deduction": deduction,
+			})
+			return
+		}
+		if taxInput.Format == "csv" {
+			c.Header("Content-Disposition", `attachment; filename="tax_deduction.csv"`)
+			c.Status(http.StatusOK)
+			c.Writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			if err := loan.WriteTaxDeductionCSV(c.Writer, deduction); err != nil {
+				c.Error(err)
+			}
+			return
+		}
+		c.JSON(http.StatusOK, deduction)
+	}
+}
+
+func TaxRoute(env *bootstrap.Env, timeout time.Duration, group *gin.RouterGroup) {
+	validator := controller.NewInputValidator(env.SpreadMinBP, env.SpreadMaxBP)
+
+	// 个税住房贷款利息专项附加扣除
+	group.POST("/loan/tax", handleTaxDeductionRequest(validator, false))
+	group.POST("/api/loan/tax", handleTaxDeductionRequest(validator, true))
+}
@@ -0,0 +1,70 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/bootstrap"
+)
+
+// 主表单同时提交对账的 format,个税只读取 taxFormat
+func TestTaxRouteWithMainForm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetFuncMap(templateFuncs)
+	router.LoadHTMLGlob("../../assets/templates/*")
+	TaxRoute(&bootstrap.Env{}, time.Second, router.Group(""))
+
+	post := func(path, action, taxFormat string) *httptest.ResponseRecorder {
+		form := url.Values{
+			"action":        {action},
+			"principal":     {"1000000"},
+			"loanTerm":      {"36"},
+			"paymentDueDay": {"18"},
+			"format":        {"html"},
+			"taxFormat":     {taxFormat},
+		}
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		action      string
+		taxFormat   string
+		status      int
+		contentType string
+	}{
+		{name: "html epp", path: "/loan/tax", action: "epp", taxFormat: "csv", status: http.StatusOK, contentType: "text/html"},
+		{name: "html emi", path: "/loan/tax", action: "emi", taxFormat: "csv", status: http.StatusOK, contentType: "text/html"},
+		{name: "csv epp", path: "/api/loan/tax", action: "epp", taxFormat: "csv", status: http.StatusOK, contentType: "text/csv"},
+		{name: "csv emi", path: "/api/loan/tax", action: "emi", taxFormat: "csv", status: http.StatusOK, contentType: "text/csv"},
+		{name: "json", path: "/api/loan/tax", action: "emi", taxFormat: "json", status: http.StatusOK, contentType: "application/json"},
+		{name: "invalid taxFormat", path: "/api/loan/tax", action: "emi", taxFormat: "html", status: http.StatusBadRequest, contentType: "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := post(tt.path, tt.action, tt.taxFormat)
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+			if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("content type = %s, want %s", got, tt.contentType)
+			}
+		})
+	}
+
+	// 导出csv按所选还款方式计算
+	if post("/api/loan/tax", "epp", "csv").Body.String() == post("/api/loan/tax", "emi", "csv").Body.String() {
+		t.Error("epp and emi exports should differ")
+	}
+}
@@ -0,0 +1,41 @@
+package route
+
+import (
+	"html/template"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+)
+
+// 模板函数,需在载入模板之前设置
+var templateFuncs = template.FuncMap{
+	"money":          loan.FormatMoney,
+	"scheduleRow":    scheduleRowClass,
+	"scheduleHeader": func() []string { return loan.ReportHeader },
+}
+
+// 还款计划行的样式,突出提前还款,利率变化(LPR重定价和加点调整),结清和逾期
+// 利率变化与之前最近一条有利率的记录比较
+func scheduleRowClass(reports []loan.Report, i int) string {
+	report := reports[i]
+	switch {
+	case report.Status == "逾期":
+		return "overdue"
+	case report.IsEmptyEarlyRepayment():
+		return "placeholder"
+	case report.Purpose == "提前还款":
+		return "prepayment"
+	case report.Purpose == "结清":
+		return "payoff"
+	case report.Purpose == "利率调整":
+		return "rate-change"
+	}
+	for j := i - 1; j >= 0 && report.DueDateRate.IsPositive(); j-- {
+		if previous := reports[j].DueDateRate; previous.IsPositive() {
+			if !previous.Equal(report.DueDateRate) {
+				return "rate-change"
+			}
+			break
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package route
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+func TestScheduleRowClass(t *testing.T) {
+	row := func(purpose, rate string, amount int64) loan.Report {
+		return loan.Report{Purpose: purpose, DueDateRate: decimal.RequireFromString(rate), MonthTotalAmount: decimal.NewFromInt(amount)}
+	}
+	overdue := row("分期", "4.9", 1000)
+	overdue.Status = "逾期"
+	tests := []struct {
+		name    string
+		reports []loan.Report
+		want    string // 最后一行的样式
+	}{
+		{name: "installment", reports: []loan.Report{row("贷款发放", "4.9", 0), row("分期", "4.9", 1000)}},
+		{name: "overdue", reports: []loan.Report{row("贷款发放", "4.9", 0), overdue}, want: "overdue"},
+		{name: "placeholder", reports: []loan.Report{row("分期", "4.9", 1000), row("提前还款", "0", 0)}, want: "placeholder"},
+		{name: "prepayment", reports: []loan.Report{row("分期", "4.9", 1000), row("提前还款", "4.9", 5000)}, want: "prepayment"},
+		{name: "payoff", reports: []loan.Report{row("分期", "4.9", 1000), row("结清", "4.9", 5000)}, want: "payoff"},
+		{name: "rate change row", reports: []loan.Report{row("分期", "4.9", 1000), row("利率调整", "4.2", 0)}, want: "rate-change"},
+		// LPR重定价的分期和之前最近一条有利率的记录比较
+		{name: "repricing", reports: []loan.Report{row("分期", "4.9", 1000), row("提前还款", "0", 0), row("分期", "4.75", 1000)}, want: "rate-change"},
+		{name: "same rate after placeholder", reports: []loan.Report{row("分期", "4.9", 1000), row("提前还款", "0", 0), row("分期", "4.9", 1000)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scheduleRowClass(tt.reports, len(tt.reports)-1); got != tt.want {
+				t.Errorf("class = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleTemplate(t *testing.T) {
+	// 和 Setup 一样先设置模板函数再载入所有模板
+	templates, err := template.New("").Funcs(templateFuncs).ParseGlob("../../assets/templates/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := loan.Input{
+		Loan:           loan.Loan{InitialPrincipal: decimal.NewFromInt(1200000), InitialTerm: 12, InitialDate: loan.ParseDate("2023-01-10"), PaymentDueDay: 18, FixedRate: decimal.NewFromFloat(4.9), LPR: loan.Lprs},
+		EarlyRepayment: []loan.EarlyRepayment{{Amount: decimal.NewFromInt(100000), Date: loan.ParseDate("2023-05-25")}},
+	}
+	var b strings.Builder
+	if err := templates.ExecuteTemplate(&b, "schedule", loan.BuildReport(input, "emi")); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+	for _, want := range []string{`<tr class="prepayment">`, `<td class="num">1,200,000.00</td>`, `<td class="num">4.9</td>`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("schedule should contain %s", want)
+		}
+	}
+	if rows := strings.Count(html, "<tr class="); rows != 14 {
+		t.Errorf("%d rows, want 14", rows)
+	}
+
+	// 表头来自 loan.ReportHeader,每行的列数与表头相同
+	header := "<th>" + strings.Join(loan.ReportHeader, "</th><th>") + "</th>"
+	if !strings.Contains(html, header) {
+		t.Errorf("schedule header should be %s", header)
+	}
+	for _, row := range strings.Split(html, "<tr class=")[1:] {
+		row = row[:strings.Index(row, "</tr>")]
+		if cells := strings.Count(row, "<td"); cells != len(loan.ReportHeader) {
+			t.Fatalf("%d cells in a row, want %d", cells, len(loan.ReportHeader))
+		}
+	}
+}
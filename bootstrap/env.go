@@ -19,6 +19,10 @@ type Env struct {
 	RefreshTokenExpiryHour int    `mapstructure:"REFRESH_TOKEN_EXPIRY_HOUR"`
 	AccessTokenSecret      string `mapstructure:"ACCESS_TOKEN_SECRET"`
 	RefreshTokenSecret     string `mapstructure:"REFRESH_TOKEN_SECRET"`
+	SpreadMinBP            int    `mapstructure:"SPREAD_MIN_BP"`
+	SpreadMaxBP            int    `mapstructure:"SPREAD_MAX_BP"`
+	BankName               string `mapstructure:"BANK_NAME"`
+	PDFFontPath            string `mapstructure:"PDF_FONT_PATH"`
 }
 
 func NewEnv() *Env {
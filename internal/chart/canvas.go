@@ -0,0 +1,79 @@
+package chart
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Canvas 图表的绘制接口,坐标原点在左上角,单位与svg一致
+// svg 输出到页面,pdf 等其他格式实现同样的接口即可复用图表
+type Canvas interface {
+	Line(x1, y1, x2, y2 float64, color string, dashed bool)
+	Polyline(points [][2]float64, color string)
+	Rect(x, y, w, h float64, color string)
+	Circle(x, y, r float64, color string)
+	// Text anchor 为 start,middle 或 end
+	Text(x, y, size float64, color, anchor string, bold bool, text string)
+	// Tip 开始一组带提示的图形,End 结束,不支持提示的格式可以忽略
+	Tip(title string)
+	End()
+}
+
+// svg画布
+type svgCanvas struct {
+	b strings.Builder
+}
+
+func newSVGCanvas() *svgCanvas {
+	c := &svgCanvas{}
+	fmt.Fprintf(&c.b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`,
+		Width, Height, Width, Height)
+	fmt.Fprintf(&c.b, `<rect width="%d" height="%d" fill="#fff"/>`, Width, Height)
+	return c
+}
+
+func (c *svgCanvas) Line(x1, y1, x2, y2 float64, color string, dashed bool) {
+	dash := ""
+	if dashed {
+		dash = ` stroke-dasharray="4,3"`
+	}
+	fmt.Fprintf(&c.b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"%s/>`, x1, y1, x2, y2, color, dash)
+}
+
+func (c *svgCanvas) Polyline(points [][2]float64, color string) {
+	coords := make([]string, 0, len(points))
+	for _, point := range points {
+		coords = append(coords, fmt.Sprintf("%.1f,%.1f", point[0], point[1]))
+	}
+	fmt.Fprintf(&c.b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`, strings.Join(coords, " "), color)
+}
+
+func (c *svgCanvas) Rect(x, y, w, h float64, color string) {
+	fmt.Fprintf(&c.b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`, x, y, w, h, color)
+}
+
+func (c *svgCanvas) Circle(x, y, r float64, color string) {
+	fmt.Fprintf(&c.b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"/>`, x, y, r, color)
+}
+
+func (c *svgCanvas) Text(x, y, size float64, color, anchor string, bold bool, text string) {
+	weight := ""
+	if bold {
+		weight = ` font-weight="bold"`
+	}
+	fmt.Fprintf(&c.b, `<text x="%.1f" y="%.1f" font-size="%g" fill="%s" text-anchor="%s"%s>%s</text>`,
+		x, y, size, color, anchor, weight, html.EscapeString(text))
+}
+
+func (c *svgCanvas) Tip(title string) {
+	fmt.Fprintf(&c.b, `<g><title>%s</title>`, html.EscapeString(title))
+}
+
+func (c *svgCanvas) End() {
+	c.b.WriteString(`</g>`)
+}
+
+func (c *svgCanvas) String() string {
+	return c.b.String() + `</svg>`
+}
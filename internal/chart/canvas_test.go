@@ -0,0 +1,102 @@
+package chart
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// 记录绘制调用的画布,用于检查图表不依赖svg
+type recordingCanvas struct {
+	calls map[string]int
+	tips  int // 未结束的提示分组
+}
+
+func (c *recordingCanvas) Line(x1, y1, x2, y2 float64, color string, dashed bool) { c.calls["line"]++ }
+func (c *recordingCanvas) Polyline(points [][2]float64, color string)             { c.calls["polyline"]++ }
+func (c *recordingCanvas) Rect(x, y, w, h float64, color string)                  { c.calls["rect"]++ }
+func (c *recordingCanvas) Circle(x, y, r float64, color string)                   { c.calls["circle"]++ }
+func (c *recordingCanvas) Text(x, y, size float64, color, anchor string, bold bool, text string) {
+	c.calls["text"]++
+}
+func (c *recordingCanvas) Tip(title string) { c.tips++ }
+func (c *recordingCanvas) End()             { c.tips-- }
+
+func TestDraw(t *testing.T) {
+	input := loan.Input{
+		Loan: loan.Loan{
+			InitialPrincipal: decimal.NewFromInt(120000),
+			InitialTerm:      24,
+			InitialDate:      loan.ParseDate("2021-05-25"),
+			PaymentDueDay:    18,
+			LPR:              loan.Lprs,
+			PlusSpread:       decimal.NewFromFloat(0.1),
+		},
+		EarlyRepayment: []loan.EarlyRepayment{{Amount: decimal.NewFromInt(10000), Date: loan.ParseDate("2022-01-20")}},
+	}
+	reports := loan.BuildReport(input, "emi")
+
+	tests := []struct {
+		name  string
+		chart string
+		shape string // 图表的主要图形
+	}{
+		{name: "balance", chart: Balance, shape: "polyline"},
+		{name: "principal and interest", chart: PrincipalInterest, shape: "rect"},
+		{name: "cumulative interest", chart: CumulativeInterest, shape: "polyline"},
+		{name: "apr", chart: APR, shape: "polyline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canvas := &recordingCanvas{calls: make(map[string]int)}
+			title, err := Draw(tt.chart, reports, canvas)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if title == "" || canvas.calls[tt.shape] == 0 || canvas.calls["text"] == 0 {
+				t.Errorf("title %q, calls %v", title, canvas.calls)
+			}
+			if canvas.tips != 0 {
+				t.Errorf("%d tip groups are not closed", canvas.tips)
+			}
+
+			// svg 是合法的xml,提示和文字经过转义
+			chart, err := Render(tt.chart, reports)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoder := xml.NewDecoder(strings.NewReader(chart.SVG))
+			for {
+				if _, err := decoder.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("invalid svg: %v", err)
+				}
+			}
+		})
+	}
+
+	if _, err := Draw("unknown", reports, &recordingCanvas{calls: make(map[string]int)}); err == nil {
+		t.Error("expected an error for an unknown chart")
+	}
+	if _, err := Draw(Balance, reports[:1], &recordingCanvas{calls: make(map[string]int)}); err == nil {
+		t.Error("expected an error without enough rows")
+	}
+}
+
+func TestSVGCanvasEscapes(t *testing.T) {
+	canvas := newSVGCanvas()
+	canvas.Tip(`<提前还款> & "利率"`)
+	canvas.Text(1, 2, 10, "#000", "start", true, "a<b")
+	canvas.End()
+	svg := canvas.String()
+	for _, want := range []string{`<title>&lt;提前还款&gt; &amp; &#34;利率&#34;</title>`, `font-weight="bold">a&lt;b</text>`, `</g></svg>`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg should contain %s:\n%s", want, svg)
+		}
+	}
+}
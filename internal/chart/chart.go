@@ -0,0 +1,341 @@
+package chart
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// 图表名称
+const (
+	Balance            = "balance"             // 剩余本金
+	PrincipalInterest  = "principal-interest"  // 每期本金和利息
+	CumulativeInterest = "cumulative-interest" // 累计利息
+	APR                = "apr"                 // 利率
+)
+
+// Names 所有图表,按页面显示的顺序
+var Names = []string{Balance, PrincipalInterest, CumulativeInterest, APR}
+
+// 图表尺寸
+const (
+	Width        = 800
+	Height       = 260
+	marginLeft   = 80
+	marginRight  = 20
+	marginTop    = 30
+	marginBottom = 40
+)
+
+// 颜色
+const (
+	colorPrincipal = "#4ba875"
+	colorInterest  = "#e8a33d"
+	colorLine      = "#2f6fb0"
+	colorEvent     = "#c0392b"
+	colorGrid      = "#ddd"
+	colorAxis      = "#999"
+	colorText      = "#000"
+)
+
+// Chart 服务端生成的svg图表
+type Chart struct {
+	Name  string
+	Title string
+	SVG   string
+}
+
+// 还款计划中的事件,提前还款和利率调整
+type event struct {
+	date  time.Time
+	label string
+}
+
+// 坐标系,横轴为日期,纵轴为金额或利率
+type plot struct {
+	start, end time.Time
+	min, max   float64
+	canvas     Canvas
+}
+
+// 去掉未填写的提前还款
+func chartRows(reports []loan.Report) ([]loan.Report, error) {
+	rows := make([]loan.Report, 0, len(reports))
+	for _, report := range reports {
+		if report.IsEmptyEarlyRepayment() {
+			continue
+		}
+		rows = append(rows, report)
+	}
+	if len(rows) < 2 {
+		return nil, errors.New("chart: not enough data")
+	}
+	return rows, nil
+}
+
+// Draw 按名称在画布上绘制图表,返回图表标题
+func Draw(name string, reports []loan.Report, canvas Canvas) (string, error) {
+	rows, err := chartRows(reports)
+	if err != nil {
+		return "", err
+	}
+	switch name {
+	case Balance:
+		return balance(rows, canvas), nil
+	case PrincipalInterest:
+		return principalInterest(rows, canvas), nil
+	case CumulativeInterest:
+		return cumulativeInterest(rows, canvas), nil
+	case APR:
+		return apr(rows, canvas), nil
+	}
+	return "", errors.New("chart: unknown chart " + name)
+}
+
+// Render 按名称生成svg图表,未填写的提前还款不参与绘制
+func Render(name string, reports []loan.Report) (Chart, error) {
+	canvas := newSVGCanvas()
+	title, err := Draw(name, reports, canvas)
+	if err != nil {
+		return Chart{}, err
+	}
+	return Chart{Name: name, Title: title, SVG: canvas.String()}, nil
+}
+
+// RenderAll 生成所有图表
+func RenderAll(reports []loan.Report) []Chart {
+	charts := make([]Chart, 0, len(Names))
+	for _, name := range Names {
+		if chart, err := Render(name, reports); err == nil {
+			charts = append(charts, chart)
+		}
+	}
+	return charts
+}
+
+// 剩余本金曲线
+func balance(rows []loan.Report, canvas Canvas) string {
+	value := func(r loan.Report) float64 { return r.RemainingPrincipal.InexactFloat64() }
+	p := newPlot(canvas, rows, 0, maxOf(rows, value))
+	p.polyline(rows, value, colorLine, false)
+	p.annotate(events(rows), true)
+	return p.finish("剩余本金")
+}
+
+// 每期本金和利息的堆叠柱状图
+func principalInterest(rows []loan.Report, canvas Canvas) string {
+	installments := make([]loan.Report, 0, len(rows))
+	for _, row := range rows {
+		if row.Purpose == "分期" {
+			installments = append(installments, row)
+		}
+	}
+	p := newPlot(canvas, rows, 0, maxOf(installments, func(r loan.Report) float64 {
+		return r.Principal.Add(r.Interest).InexactFloat64()
+	}))
+	barWidth := math.Max(1, float64(Width-marginLeft-marginRight)/float64(len(installments)+1)*0.8)
+	for _, row := range installments {
+		principal, interest := row.Principal.InexactFloat64(), row.Interest.InexactFloat64()
+		x := p.x(row.DueDate) - barWidth/2
+		canvas.Tip(fmt.Sprintf("第%d期 %s 本金 %s 利息 %s", row.LoanTerm, row.DueDate.Format("2006-01-02"), row.Principal, row.Interest))
+		canvas.Rect(x, p.y(interest), barWidth, p.y(0)-p.y(interest), colorInterest)
+		canvas.Rect(x, p.y(interest+principal), barWidth, p.y(interest)-p.y(interest+principal), colorPrincipal)
+		canvas.End()
+	}
+	p.annotate(events(rows), true)
+	p.legend([][2]string{{"本金", colorPrincipal}, {"利息", colorInterest}})
+	return p.finish("每期本金和利息")
+}
+
+// 累计利息曲线
+func cumulativeInterest(rows []loan.Report, canvas Canvas) string {
+	value := func(r loan.Report) float64 { return r.TotalInterestPaid.InexactFloat64() }
+	p := newPlot(canvas, rows, 0, maxOf(rows, value))
+	p.polyline(rows, value, colorInterest, false)
+	p.annotate(events(rows), true)
+	return p.finish("累计利息")
+}
+
+// 执行利率阶梯线,利率调整处标注调整后的利率
+func apr(rows []loan.Report, canvas Canvas) string {
+	rated := make([]loan.Report, 0, len(rows))
+	for _, row := range rows {
+		if row.DueDateRate.IsPositive() {
+			rated = append(rated, row)
+		}
+	}
+	if len(rated) == 0 {
+		rated = rows
+	}
+	value := func(r loan.Report) float64 { return r.DueDateRate.InexactFloat64() }
+	low, high := minOf(rated, value), maxOf(rated, value)
+	pad := math.Max((high-low)*0.2, 0.25)
+	p := newPlot(canvas, rows, math.Max(0, low-pad), high+pad)
+	p.polyline(rated, value, colorLine, true)
+
+	for _, row := range rateChanges(rows) {
+		x, y := p.x(row.DueDate), p.y(value(row))
+		canvas.Tip(fmt.Sprintf("%s 利率调整 %s%%", row.DueDate.Format("2006-01-02"), row.DueDateRate))
+		canvas.Circle(x, y, 3, colorEvent)
+		canvas.Text(x+3, y-5, 10, colorEvent, "start", false, row.DueDateRate.String())
+		canvas.End()
+	}
+	p.annotate(prepayments(rows), false)
+	return p.finish("执行利率(%)")
+}
+
+// 提前还款和利率变化事件,按日期排列
+func events(rows []loan.Report) []event {
+	result := prepayments(rows)
+	for _, row := range rateChanges(rows) {
+		result = append(result, event{date: row.DueDate, label: "利率 " + row.DueDateRate.String() + "%"})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].date.Before(result[j].date) })
+	return result
+}
+
+// 提前还款事件
+func prepayments(rows []loan.Report) []event {
+	result := make([]event, 0)
+	for _, row := range rows {
+		if row.Purpose == "提前还款" {
+			result = append(result, event{date: row.DueDate, label: "提前还款 " + row.MonthTotalAmount.String()})
+		}
+	}
+	return result
+}
+
+// 利率发生变化的记录,包括利率调整记录和LPR重定价后的分期
+// 与之前最近一条有利率的记录比较,没有利率的记录不参与比较
+func rateChanges(rows []loan.Report) []loan.Report {
+	result := make([]loan.Report, 0)
+	previous := decimal.Zero
+	for _, row := range rows {
+		if !row.DueDateRate.IsPositive() {
+			continue
+		}
+		if previous.IsPositive() && !row.DueDateRate.Equal(previous) {
+			result = append(result, row)
+		}
+		previous = row.DueDateRate
+	}
+	return result
+}
+
+func newPlot(canvas Canvas, rows []loan.Report, min, max float64) *plot {
+	if max <= min {
+		max = min + 1
+	}
+	p := &plot{start: rows[0].DueDate, end: rows[len(rows)-1].DueDate, min: min, max: max, canvas: canvas}
+	if !p.end.After(p.start) {
+		p.end = p.start.AddDate(0, 1, 0)
+	}
+	p.axes()
+	return p
+}
+
+func (p *plot) x(date time.Time) float64 {
+	ratio := date.Sub(p.start).Hours() / p.end.Sub(p.start).Hours()
+	return marginLeft + ratio*float64(Width-marginLeft-marginRight)
+}
+
+func (p *plot) y(value float64) float64 {
+	ratio := (value - p.min) / (p.max - p.min)
+	return float64(Height-marginBottom) - ratio*float64(Height-marginTop-marginBottom)
+}
+
+// 坐标轴,纵轴5条网格线,横轴按年标注,最多10个刻度
+func (p *plot) axes() {
+	for i := 0; i <= 5; i++ {
+		value := p.min + (p.max-p.min)*float64(i)/5
+		y := p.y(value)
+		p.canvas.Line(marginLeft, y, Width-marginRight, y, colorGrid, false)
+		p.canvas.Text(marginLeft-4, y+3, 10, colorText, "end", false, formatValue(value, p.max-p.min))
+	}
+	years := p.end.Year() - p.start.Year() + 1
+	step := (years + 9) / 10
+	for year := p.start.Year() + 1; year <= p.end.Year(); year += step {
+		x := p.x(time.Date(year, 1, 1, 0, 0, 0, 0, p.start.Location()))
+		p.canvas.Line(x, Height-marginBottom, x, Height-marginBottom+4, colorAxis, false)
+		p.canvas.Text(x, Height-marginBottom+16, 10, colorText, "middle", false, fmt.Sprint(year))
+	}
+	p.canvas.Line(marginLeft, Height-marginBottom, Width-marginRight, Height-marginBottom, colorAxis, false)
+}
+
+// 折线,step 为 true 时按阶梯线绘制,每个点带日期和数值的提示
+func (p *plot) polyline(rows []loan.Report, value func(loan.Report) float64, color string, step bool) {
+	points := make([][2]float64, 0, len(rows)*2)
+	for i, row := range rows {
+		x, y := p.x(row.DueDate), p.y(value(row))
+		if step && i > 0 {
+			points = append(points, [2]float64{x, p.y(value(rows[i-1]))})
+		}
+		points = append(points, [2]float64{x, y})
+	}
+	p.canvas.Polyline(points, color)
+	for _, row := range rows {
+		p.canvas.Tip(row.DueDate.Format("2006-01-02") + " " + formatValue(value(row), 0))
+		p.canvas.Circle(p.x(row.DueDate), p.y(value(row)), 4, "transparent")
+		p.canvas.End()
+	}
+}
+
+// 事件竖线,label 为 true 时在顶部显示文字,相邻的文字上下错开
+func (p *plot) annotate(events []event, label bool) {
+	for i, e := range events {
+		x := p.x(e.date)
+		p.canvas.Tip(e.date.Format("2006-01-02") + " " + e.label)
+		p.canvas.Line(x, marginTop, x, Height-marginBottom, colorEvent, true)
+		if label {
+			p.canvas.Text(x+3, float64(marginTop+10+(i%2)*12), 10, colorEvent, "start", false, e.label)
+		}
+		p.canvas.End()
+	}
+}
+
+func (p *plot) legend(items [][2]string) {
+	for i, item := range items {
+		x := float64(Width - marginRight - 120 + i*60)
+		p.canvas.Rect(x, 8, 10, 10, item[1])
+		p.canvas.Text(x+14, 17, 11, colorText, "start", false, item[0])
+	}
+}
+
+func (p *plot) finish(title string) string {
+	p.canvas.Text(marginLeft, 18, 13, colorText, "start", true, title)
+	return title
+}
+
+// 刻度和提示的数值,范围较小时保留小数
+func formatValue(value, span float64) string {
+	switch {
+	case span > 0 && span < 10:
+		return fmt.Sprintf("%.2f", value)
+	case math.Abs(value) >= 10000:
+		return fmt.Sprintf("%.1f万", value/10000)
+	case span == 0:
+		return fmt.Sprintf("%.2f", value)
+	}
+	return fmt.Sprintf("%.0f", value)
+}
+
+func maxOf(rows []loan.Report, value func(loan.Report) float64) float64 {
+	max := 0.0
+	for _, row := range rows {
+		max = math.Max(max, value(row))
+	}
+	return max
+}
+
+func minOf(rows []loan.Report, value func(loan.Report) float64) float64 {
+	min := math.Inf(1)
+	for _, row := range rows {
+		min = math.Min(min, value(row))
+	}
+	return min
+}
@@ -0,0 +1,92 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+func TestRateChanges(t *testing.T) {
+	row := func(date, purpose, rate string) loan.Report {
+		return loan.Report{DueDate: loan.ParseDate(date), Purpose: purpose, DueDateRate: decimal.RequireFromString(rate)}
+	}
+	tests := []struct {
+		name string
+		rows []loan.Report
+		want []string // 利率变化记录的日期
+	}{
+		{
+			name: "constant rate",
+			rows: []loan.Report{row("2023-01-10", "贷款发放", "4.9"), row("2023-02-18", "分期", "4.9"), row("2023-03-18", "分期", "4.9")},
+		},
+		{
+			// LPR重定价没有利率调整记录,分期的利率变化也要标注
+			name: "repricing on an installment",
+			rows: []loan.Report{row("2023-01-10", "贷款发放", "4.9"), row("2023-02-18", "分期", "4.9"), row("2024-01-18", "分期", "4.75")},
+			want: []string{"2024-01-18"},
+		},
+		{
+			name: "rate change row",
+			rows: []loan.Report{row("2023-01-10", "贷款发放", "4.9"), row("2023-09-25", "利率调整", "4.2"), row("2023-10-18", "分期", "4.2")},
+			want: []string{"2023-09-25"},
+		},
+		{
+			// 没有利率的记录不参与比较
+			name: "rows without rate",
+			rows: []loan.Report{row("2023-01-10", "贷款发放", "4.9"), row("2023-02-18", "提前还款", "0"), row("2023-03-18", "分期", "4.9"), row("2023-04-18", "分期", "4.1")},
+			want: []string{"2023-04-18"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rateChanges(tt.rows)
+			dates := make([]string, 0, len(got))
+			for _, r := range got {
+				dates = append(dates, r.DueDate.Format("2006-01-02"))
+			}
+			if strings.Join(dates, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("rate changes = %v, want %v", dates, tt.want)
+			}
+			labelled := 0
+			for _, e := range events(tt.rows) {
+				if strings.HasPrefix(e.label, "利率") {
+					labelled++
+				}
+			}
+			if labelled != len(tt.want) {
+				t.Errorf("%d rate change events, want %d", labelled, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestAPRLabelsRepricing(t *testing.T) {
+	input := loan.Input{Loan: loan.Loan{
+		InitialPrincipal: decimal.NewFromInt(1000000),
+		InitialTerm:      360,
+		InitialDate:      loan.ParseDate("2019-12-25"),
+		LPR:              loan.Lprs,
+		PlusSpread:       decimal.NewFromFloat(0.1),
+		PaymentDueDay:    18,
+	}}
+	reports := loan.BuildReport(input, "emi")
+	rows, err := chartRows(reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := rateChanges(rows)
+	if len(changes) == 0 {
+		t.Fatal("LPR repricing should change the rate")
+	}
+	chart, err := Render(APR, reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range changes {
+		if !strings.Contains(chart.SVG, row.DueDate.Format("2006-01-02")+" 利率调整 "+row.DueDateRate.String()+"%") {
+			t.Errorf("rate change on %s is not labelled", row.DueDate.Format("2006-01-02"))
+		}
+	}
+}
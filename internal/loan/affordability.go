@@ -0,0 +1,141 @@
+package loan
+
+import (
+	"errors"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// AffordabilityParams 可贷额度测算的收入和负债
+type AffordabilityParams struct {
+	MonthlyIncome decimal.Decimal // 月收入
+	MonthlyDebts  decimal.Decimal // 现有每月还债支出
+	MaxDTI        decimal.Decimal // 最高负债收入比(%)
+	StressBP      decimal.Decimal // 压力测试加息的基点
+}
+
+// Affordability 可贷额度
+type Affordability struct {
+	Budget             decimal.Decimal // 可用于月供的金额=月收入*负债收入比-现有负债,双周供每期按12/26折算
+	APR                decimal.Decimal // 当前执行利率(%)
+	StressedAPR        decimal.Decimal // 压力测试利率(%)
+	EstimatedPrincipal decimal.Decimal // 按公式估算的额度
+	MaxPrincipal       decimal.Decimal // 按还款计划校验的最高额度
+	PeakInstallment    decimal.Decimal // 最高额度在压力测试利率下的最高每期还款
+	BaseInstallment    decimal.Decimal // 最高额度在当前利率下的最高每期还款
+}
+
+// 利率整体上浮 bp 个基点,浮动利率加在加点上,固定利率直接上浮,利率上限仍然有效
+func (loan Loan) withRateBuffer(bp decimal.Decimal) Loan {
+	buffer := bp.Div(decimal.NewFromInt(100))
+	loan.PlusSpread = loan.PlusSpread.Add(buffer)
+	spreadChanges := make([]SpreadChange, len(loan.SpreadChanges))
+	for i, change := range loan.SpreadChanges {
+		change.PlusSpread = change.PlusSpread.Add(buffer)
+		spreadChanges[i] = change
+	}
+	loan.SpreadChanges = spreadChanges
+	if !loan.FixedRate.IsZero() {
+		loan.FixedRate = loan.FixedRate.Add(buffer)
+	}
+	return loan
+}
+
+// 不提前还款时的最高每期还款
+// 尾款方式最后一期连同尾款和首期天数差带来的尾差一次性归还,不占用每月预算
+func peakInstallment(loan Loan, action string, principal decimal.Decimal) decimal.Decimal {
+	loan.InitialPrincipal = principal
+	reports := BuildReport(Input{Loan: loan}, action)
+	last := -1
+	for i, report := range reports {
+		if report.Purpose == "分期" {
+			last = i
+		}
+	}
+	peak := decimal.Zero
+	for i, report := range reports {
+		if report.Purpose != "分期" {
+			continue
+		}
+		if action == "balloon" && i == last {
+			continue
+		}
+		if report.MonthTotalAmount.GreaterThan(peak) {
+			peak = report.MonthTotalAmount
+		}
+	}
+	return peak
+}
+
+// 按公式估算额度
+// 等额本息: P = PMT * ((1+r)**n - 1) / (r * (1+r)**n)
+// 等额本金: 第一期最高, PMT = P/n + P*r, P = PMT / (1/n + r)
+// 尾款: 等额本息的额度加上尾款的现值 B / (1+r)**n
+// budget 和 r 都是每期的
+func estimatePrincipal(budget, r float64, loanTerms int, action string, balloon float64) float64 {
+	n := float64(loanTerms)
+	if action == "epp" {
+		return budget / (1/n + r)
+	}
+	if action != "balloon" {
+		balloon = 0
+	}
+	if r == 0 {
+		return budget*n + balloon
+	}
+	pow := math.Pow(1+r, n)
+	return budget*(pow-1)/(r*pow) + balloon/pow
+}
+
+// 额度搜索的上限,与表单本金上限一致
+const maxAffordablePrincipal = 100000000
+
+// MaxAffordablePrincipal 求最高每期还款不超过预算的最高贷款本金
+// 先按公式估算,再用二分法按压力测试利率下的还款计划校验,可以覆盖等额本金首期较高和首期天数不足一月的情况
+func MaxAffordablePrincipal(inputdata Input, action string, params AffordabilityParams) (Affordability, error) {
+	budget := params.MonthlyIncome.Mul(params.MaxDTI).Div(decimal.NewFromInt(100)).Sub(params.MonthlyDebts).Round(2)
+	if !budget.IsPositive() {
+		return Affordability{}, errors.New("no budget left for installment after existing debts")
+	}
+
+	base := inputdata.Loan
+	stressed := base.withRateBuffer(params.StressBP)
+	result := Affordability{
+		Budget:      budget,
+		APR:         base.EffectiveRate(base.InitialDate),
+		StressedAPR: stressed.EffectiveRate(stressed.InitialDate),
+	}
+
+	// 双周供每期预算按一年26期折算
+	periodBudget, period, loanTerms := budget, Monthly, base.InitialTerm
+	if action == "biweekly" {
+		periodBudget = budget.Mul(decimal.NewFromInt(12)).Div(decimal.NewFromInt(26)).Round(2)
+		period, loanTerms = BiWeekly, base.BiWeeklyLoanTerms()
+	}
+	rate := periodInterestRate(result.StressedAPR, period).InexactFloat64()
+	estimate := estimatePrincipal(periodBudget.InexactFloat64(), rate, loanTerms, action, base.BalloonAmount.InexactFloat64())
+	result.EstimatedPrincipal = decimal.NewFromFloat(estimate).Floor()
+
+	ok := func(principal float64) bool {
+		return !peakInstallment(stressed, action, decimal.NewFromFloat(principal).Floor()).GreaterThan(periodBudget)
+	}
+	// 尾款方式本金需大于尾款
+	low := 0.0
+	if action == "balloon" {
+		low = base.BalloonAmount.InexactFloat64()
+		if !ok(low + 1) {
+			return Affordability{}, errors.New("budget is not enough for a loan above the balloon amount")
+		}
+	}
+	// 从估算值的两倍开始,上界不满足预算为止,精度1元
+	high := math.Max(estimate*2, low+1)
+	for ok(high) && high < maxAffordablePrincipal {
+		high *= 2
+	}
+	maxPrincipal := bisect(low, high, 1, ok)
+	result.MaxPrincipal = decimal.NewFromFloat(maxPrincipal).Floor()
+	result.PeakInstallment = peakInstallment(stressed, action, result.MaxPrincipal)
+	result.BaseInstallment = peakInstallment(base, action, result.MaxPrincipal)
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMaxAffordablePrincipal(t *testing.T) {
+	params := AffordabilityParams{
+		MonthlyIncome: decimal.NewFromInt(30000),
+		MaxDTI:        decimal.NewFromInt(50),
+		StressBP:      decimal.NewFromInt(100),
+	}
+	tests := []struct {
+		name    string
+		action  string
+		term    int
+		balloon int64
+	}{
+		{"equal monthly installment", "emi", 360, 0},
+		{"equal principal", "epp", 360, 0},
+		{"biweekly", "biweekly", 360, 0},
+		{"balloon", "balloon", 360, 500000},
+		// 期限短,尾款现值大,额度超过不含尾款的估算值的两倍,上界需要继续扩大
+		{"large balloon", "balloon", 60, 2500000},
+	}
+
+	maxByAction := map[string]decimal.Decimal{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := fixedLoan(1000000, tt.term)
+			loan.BalloonAmount = decimal.NewFromInt(tt.balloon)
+			result, err := MaxAffordablePrincipal(Input{Loan: loan}, tt.action, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			budget := result.Budget
+			if tt.action == "biweekly" {
+				budget = budget.Mul(decimal.NewFromInt(12)).Div(decimal.NewFromInt(26)).Round(2)
+			}
+			stressed := loan.withRateBuffer(params.StressBP)
+			if result.PeakInstallment.GreaterThan(budget) {
+				t.Errorf("peak installment %s exceeds budget %s", result.PeakInstallment, budget)
+			}
+			// 最后一期承担尾差,相邻本金的最高还款有几元的波动,多借1000元一定超出预算
+			if over := peakInstallment(stressed, tt.action, result.MaxPrincipal.Add(decimal.NewFromInt(1000))); !over.GreaterThan(budget) {
+				t.Errorf("max principal %s is not the maximum: %s still fits budget %s", result.MaxPrincipal, over, budget)
+			}
+			if tt.balloon > 0 && !result.MaxPrincipal.GreaterThan(loan.BalloonAmount) {
+				t.Errorf("max principal %s should exceed the balloon %d", result.MaxPrincipal, tt.balloon)
+			}
+			maxByAction[tt.name] = result.MaxPrincipal
+		})
+	}
+
+	// 尾款降低月供,同样预算可以借得更多
+	if !maxByAction["balloon"].GreaterThan(maxByAction["equal monthly installment"]) {
+		t.Errorf("balloon max %s should exceed emi max %s", maxByAction["balloon"], maxByAction["equal monthly installment"])
+	}
+}
+
+func TestPeakInstallmentExcludesBalloon(t *testing.T) {
+	loan := fixedLoan(1000000, 60)
+	loan.BalloonAmount = decimal.NewFromInt(500000)
+	peak := peakInstallment(loan, "balloon", loan.InitialPrincipal)
+	if peak.GreaterThan(decimal.NewFromInt(20000)) {
+		t.Errorf("peak installment %s includes the balloon", peak)
+	}
+}
@@ -0,0 +1,107 @@
+package loan
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AnnualStatement 年度还款汇总
+type AnnualStatement struct {
+	Label         string          // 年度名称,自然年为"2023",贷款年度为"第1年"
+	Start         time.Time       // 年度开始日期
+	End           time.Time       // 年度结束日期
+	Periods       int             // 分期还款次数
+	Principal     decimal.Decimal // 分期归还的本金
+	Interest      decimal.Decimal // 利息
+	Prepayments   decimal.Decimal // 提前还款的本金
+	Fees          decimal.Decimal // 违约金,罚息和复利
+	TotalPaid     decimal.Decimal // 还款总额
+	AverageAPR    decimal.Decimal // 按剩余本金和天数加权的平均年利率
+	EndingBalance decimal.Decimal // 年末剩余本金
+}
+
+// AnnualStatements 按自然年和贷款年度的汇总
+type AnnualStatements struct {
+	CalendarYears []AnnualStatement
+	LoanYears     []AnnualStatement
+}
+
+// BuildAnnualStatements 按自然年(1月1日至12月31日)和贷款年度(放款日至下一个周年日前一天)汇总还款计划
+func (loan *Loan) BuildAnnualStatements(reports []Report) AnnualStatements {
+	initialDate := loan.InitialDate
+	return AnnualStatements{
+		CalendarYears: groupAnnual(reports, func(date time.Time) (string, time.Time, time.Time) {
+			start := time.Date(date.Year(), 1, 1, 0, 0, 0, 0, date.Location())
+			return strconv.Itoa(date.Year()), start, start.AddDate(1, 0, -1)
+		}),
+		LoanYears: groupAnnual(reports, func(date time.Time) (string, time.Time, time.Time) {
+			year := date.Year() - initialDate.Year()
+			if initialDate.AddDate(year, 0, 0).After(date) {
+				year--
+			}
+			start := initialDate.AddDate(year, 0, 0)
+			return "第" + strconv.Itoa(year+1) + "年", start, initialDate.AddDate(year+1, 0, -1)
+		}),
+	}
+}
+
+// 按年度分组汇总,period 返回日期所在年度的名称和起止日期
+// 加权平均利率的权重为上一条记录后的剩余本金*天数,计入本条记录所在的年度,跳过未填写的提前还款
+func groupAnnual(reports []Report, period func(time.Time) (string, time.Time, time.Time)) []AnnualStatement {
+	statements := make([]AnnualStatement, 0)
+	weightedRate, weight := decimal.Zero, decimal.Zero
+	closeYear := func() {
+		if len(statements) > 0 && weight.IsPositive() {
+			statements[len(statements)-1].AverageAPR = weightedRate.Div(weight).Round(4)
+		}
+		weightedRate, weight = decimal.Zero, decimal.Zero
+	}
+
+	var previous *Report
+	for i := range reports {
+		report := reports[i]
+		if report.IsEmptyEarlyRepayment() {
+			continue
+		}
+		label, start, end := period(report.DueDate)
+		if len(statements) == 0 || statements[len(statements)-1].Label != label {
+			closeYear()
+			statements = append(statements, AnnualStatement{Label: label, Start: start, End: end})
+		}
+		statement := &statements[len(statements)-1]
+
+		switch report.Purpose {
+		case "分期", "结清":
+			statement.Principal = statement.Principal.Add(report.Principal)
+		case "提前还款":
+			statement.Prepayments = statement.Prepayments.Add(report.Principal)
+		}
+		switch report.Purpose {
+		case "分期", "提前还款", "结清":
+			statement.Interest = statement.Interest.Add(report.Interest)
+			statement.Fees = statement.Fees.Add(report.Fee).Add(report.PenaltyInterest).Add(report.CompoundInterest)
+			statement.TotalPaid = statement.TotalPaid.Add(report.paid())
+		}
+		if report.Purpose == "分期" {
+			statement.Periods++
+		}
+		statement.EndingBalance = report.RemainingPrincipal
+
+		if previous != nil {
+			rate := report.DueDateRate
+			if report.Purpose == "利率调整" {
+				rate = previous.DueDateRate
+			}
+			days := decimal.NewFromFloat(report.DueDate.Sub(previous.DueDate).Hours() / 24)
+			if w := previous.RemainingPrincipal.Mul(days); w.IsPositive() {
+				weightedRate = weightedRate.Add(w.Mul(rate))
+				weight = weight.Add(w)
+			}
+		}
+		previous = &reports[i]
+	}
+	closeYear()
+	return statements
+}
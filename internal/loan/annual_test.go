@@ -0,0 +1,100 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildAnnualStatements(t *testing.T) {
+	// 2023-01-10 放款,第一期 2023-02-18,24期
+	penaltyLoan := fixedLoan(120000, 24)
+	penaltyLoan.PrepaymentPenalties = []PrepaymentPenaltyRule{{WithinMonths: 12, Percent: decimal.NewFromInt(1)}}
+	floating := Loan{
+		InitialPrincipal: decimal.NewFromInt(120000),
+		InitialTerm:      24,
+		InitialDate:      ParseDate("2021-05-25"),
+		PaymentDueDay:    18,
+		LPR:              Lprs,
+		PlusSpread:       decimal.NewFromFloat(0.1),
+	}
+	// 未填写的提前还款在结清之后,不单独成为一个年度
+	placeholder := EarlyRepayment{Amount: decimal.Zero, Date: ParseDate("2099-05-25")}
+
+	type year struct {
+		label   string
+		start   string
+		end     string
+		periods int
+	}
+	tests := []struct {
+		name     string
+		input    Input
+		calendar []year
+		loan     []year
+		apr      [2]float64 // 第二个自然年平均利率的范围
+	}{
+		{
+			name:     "fixed rate",
+			input:    Input{Loan: fixedLoan(120000, 24), EarlyRepayment: []EarlyRepayment{placeholder}},
+			calendar: []year{{"2023", "2023-01-01", "2023-12-31", 11}, {"2024", "2024-01-01", "2024-12-31", 12}, {"2025", "2025-01-01", "2025-12-31", 1}},
+			loan:     []year{{"第1年", "2023-01-10", "2024-01-09", 11}, {"第2年", "2024-01-10", "2025-01-09", 12}, {"第3年", "2025-01-10", "2026-01-09", 1}},
+			apr:      [2]float64{4.9, 4.9},
+		},
+		{
+			name:     "prepayment with fee",
+			input:    Input{Loan: penaltyLoan, EarlyRepayment: []EarlyRepayment{{Amount: decimal.NewFromInt(30000), Date: ParseDate("2023-06-25")}}},
+			calendar: []year{{"2023", "2023-01-01", "2023-12-31", 11}, {"2024", "2024-01-01", "2024-12-31", 12}, {"2025", "2025-01-01", "2025-12-31", 1}},
+			loan:     []year{{"第1年", "2023-01-10", "2024-01-09", 11}, {"第2年", "2024-01-10", "2025-01-09", 12}, {"第3年", "2025-01-10", "2026-01-09", 1}},
+			apr:      [2]float64{4.9, 4.9},
+		},
+		{
+			// 2022年5月25日从4.75重定价为4.55,最后一期在到期日 2023-05-25,属于第3个贷款年度
+			name:     "floating rate",
+			input:    Input{Loan: floating},
+			calendar: []year{{"2021", "2021-01-01", "2021-12-31", 7}, {"2022", "2022-01-01", "2022-12-31", 12}, {"2023", "2023-01-01", "2023-12-31", 5}},
+			loan:     []year{{"第1年", "2021-05-25", "2022-05-24", 12}, {"第2年", "2022-05-25", "2023-05-24", 11}, {"第3年", "2023-05-25", "2024-05-24", 1}},
+			apr:      [2]float64{4.56, 4.74},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := BuildReport(tt.input, "emi")
+			statements := tt.input.Loan.BuildAnnualStatements(reports)
+			summary := Summarize(reports, nil)
+			for _, group := range []struct {
+				name       string
+				statements []AnnualStatement
+				want       []year
+			}{
+				{"calendar", statements.CalendarYears, tt.calendar},
+				{"loan", statements.LoanYears, tt.loan},
+			} {
+				if len(group.statements) != len(group.want) {
+					t.Fatalf("%s: %d years, want %d", group.name, len(group.statements), len(group.want))
+				}
+				principal, interest, fees := decimal.Zero, decimal.Zero, decimal.Zero
+				for i, statement := range group.statements {
+					want := group.want[i]
+					if statement.Label != want.label || !statement.Start.Equal(ParseDate(want.start)) || !statement.End.Equal(ParseDate(want.end)) || statement.Periods != want.periods {
+						t.Errorf("%s year %d = %s %s~%s %d periods, want %+v", group.name, i, statement.Label, statement.Start.Format("2006-01-02"), statement.End.Format("2006-01-02"), statement.Periods, want)
+					}
+					assertDecimal(t, group.name+" total paid", statement.TotalPaid, statement.Principal.Add(statement.Prepayments).Add(statement.Interest).Add(statement.Fees))
+					principal = principal.Add(statement.Principal).Add(statement.Prepayments)
+					interest = interest.Add(statement.Interest)
+					fees = fees.Add(statement.Fees)
+				}
+				// 各年度合计与整个还款计划一致
+				assertDecimal(t, group.name+" principal", principal, tt.input.Loan.InitialPrincipal)
+				assertDecimal(t, group.name+" interest", interest, summary.TotalInterest)
+				assertDecimal(t, group.name+" fees", fees, summary.TotalFee)
+				assertDecimal(t, group.name+" ending balance", group.statements[len(group.statements)-1].EndingBalance, decimal.Zero)
+			}
+
+			apr := statements.CalendarYears[1].AverageAPR.InexactFloat64()
+			if apr < tt.apr[0] || apr > tt.apr[1] {
+				t.Errorf("average apr = %v, want between %v and %v", apr, tt.apr[0], tt.apr[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package loan
+
+import (
+	"time"
+)
+
+// 尾款(Balloon Payment)
+// 每月按等额本息还款,但贷款到期时有一笔固定的剩余本金(尾款)需要一次性归还.
+// 每月还款额按剩余本金扣除尾款现值后计算,因此比同期限的等额本息更低.
+func (loan *Loan) BalloonPayment(earlyRepayment []EarlyRepayment) []MonthlyPayment {
+	firstDueDate := time.Date(loan.InitialDate.Year(), loan.InitialDate.Month()+1, loan.PaymentDueDay, 0, 0, 0, 0, loan.InitialDate.Location())
+	return loan.periodicInstallment(Monthly, firstDueDate, loan.InitialTerm, loan.BalloonAmount, earlyRepayment)
+}
@@ -0,0 +1,18 @@
+package loan
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// BiWeeklyLoanTerms 双周供的总期数,每年26期
+func (loan *Loan) BiWeeklyLoanTerms() int {
+	return loan.InitialTerm * 26 / 12
+}
+
+// 双周供(Bi-weekly Payment)
+// 从放款日起每14天还款一次,利息按14天计算.
+// 每期还款额按双周利率和总期数等额计算,本金归还更快,总利息比按月还款少.
+func (loan *Loan) BiWeeklyPayment(earlyRepayment []EarlyRepayment) []MonthlyPayment {
+	firstDueDate := BiWeekly.next(loan.InitialDate)
+	return loan.periodicInstallment(BiWeekly, firstDueDate, loan.BiWeeklyLoanTerms(), decimal.Zero, earlyRepayment)
+}
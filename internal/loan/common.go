@@ -1,7 +1,12 @@
 // parseDate 解析日期字符串并返回时间。如果出现错误，将返回一个零值时间。
 package loan
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 func ParseDate(dateString string) time.Time {
 	layout := "2006-01-02" // 统一的日期布局字符串
@@ -12,3 +17,35 @@ func ParseDate(dateString string) time.Time {
 	}
 	return parsedTime
 }
+
+// bisect 二分法求 [low, high] 中满足 ok 的最大值
+// ok 在区间内单调: 小于等于解时为 true,大于解时为 false;精度为 tolerance
+func bisect(low, high, tolerance float64, ok func(float64) bool) float64 {
+	for i := 0; i < 200 && high-low > tolerance; i++ {
+		mid := (low + high) / 2
+		if ok(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low
+}
+
+// FormatMoney 金额保留两位小数,整数部分按千位分隔
+func FormatMoney(amount decimal.Decimal) string {
+	text := amount.StringFixed(2)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	integer, fraction := text[:len(text)-3], text[len(text)-3:]
+	var b strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String() + fraction
+}
@@ -0,0 +1,29 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   string
+	}{
+		{"0", "0.00"},
+		{"123", "123.00"},
+		{"1000", "1,000.00"},
+		{"999.995", "1,000.00"},
+		{"1234567.891", "1,234,567.89"},
+		{"-1000", "-1,000.00"},
+		{"-0.5", "-0.50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			if got := FormatMoney(decimal.RequireFromString(tt.amount)); got != tt.want {
+				t.Errorf("FormatMoney(%s) = %s, want %s", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package loan
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Scenario 对比方案
+type Scenario struct {
+	Name   string // 方案名称
+	Action string // 还款方式 emi,epp,balloon,biweekly
+	Input  Input
+}
+
+// ScenarioResult 单个方案的计算结果
+type ScenarioResult struct {
+	Name    string
+	Action  string
+	Summary Summary
+	Reports []Report
+}
+
+// ComparisonRow 按月对比各方案,差异均相对第一个方案
+type ComparisonRow struct {
+	Month                  time.Time         // 月份
+	Installments           []decimal.Decimal // 各方案当月分期还款
+	InstallmentDiffs       []decimal.Decimal // 当月分期还款差异
+	CumulativeInterests    []decimal.Decimal // 各方案截至当月的累计利息
+	CumulativeInterestDiff []decimal.Decimal // 累计利息差异
+}
+
+// Comparison 多方案对比结果
+type Comparison struct {
+	Scenarios []ScenarioResult
+	Rows      []ComparisonRow
+}
+
+// 复制提前还款记录,生成还款计划时会回写提前还款的本金和利息,并发计算时不能共用
+func (inputdata Input) clone() Input {
+	cloned := inputdata
+	cloned.EarlyRepayment = make([]EarlyRepayment, len(inputdata.EarlyRepayment))
+	copy(cloned.EarlyRepayment, inputdata.EarlyRepayment)
+	return cloned
+}
+
+// 日期所在月的第一天
+func monthOf(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+}
+
+// Compare 并发计算各方案的还款计划,按月对比分期还款和累计利息
+func Compare(scenarios []Scenario) Comparison {
+	results := runScenarios(scenarios)
+	return Comparison{Scenarios: results, Rows: compareByMonth(results)}
+}
+
+// 并发计算各方案的还款计划和汇总
+func runScenarios(scenarios []Scenario) []ScenarioResult {
+	results := make([]ScenarioResult, len(scenarios))
+	var wg sync.WaitGroup
+	for i, scenario := range scenarios {
+		wg.Add(1)
+		go func(i int, scenario Scenario) {
+			defer wg.Done()
+			inputdata := scenario.Input.clone()
+			reports := BuildReport(inputdata, scenario.Action)
+			results[i] = ScenarioResult{
+				Name:    scenario.Name,
+				Action:  scenario.Action,
+				Summary: BuildSummary(inputdata, scenario.Action, reports),
+				Reports: reports,
+			}
+		}(i, scenario)
+	}
+	wg.Wait()
+	return results
+}
+
+// 按自然月汇总各方案的分期还款和累计利息
+func compareByMonth(results []ScenarioResult) []ComparisonRow {
+	// 对比区间从最早的放款月到最晚的结清月
+	var first, last time.Time
+	for _, result := range results {
+		for _, report := range result.Reports {
+			if report.IsEmptyEarlyRepayment() {
+				continue
+			}
+			month := monthOf(report.DueDate)
+			if first.IsZero() || month.Before(first) {
+				first = month
+			}
+			if month.After(last) {
+				last = month
+			}
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	rows := make([]ComparisonRow, 0)
+	indexes := make([]int, len(results))
+	cumulative := make([]decimal.Decimal, len(results))
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		row := ComparisonRow{
+			Month:                  month,
+			Installments:           make([]decimal.Decimal, len(results)),
+			InstallmentDiffs:       make([]decimal.Decimal, len(results)),
+			CumulativeInterests:    make([]decimal.Decimal, len(results)),
+			CumulativeInterestDiff: make([]decimal.Decimal, len(results)),
+		}
+		for s, result := range results {
+			installment := decimal.Zero
+			for ; indexes[s] < len(result.Reports) && monthOf(result.Reports[indexes[s]].DueDate).Equal(month); indexes[s]++ {
+				report := result.Reports[indexes[s]]
+				if report.Purpose == "分期" {
+					installment = installment.Add(report.MonthTotalAmount)
+				}
+				cumulative[s] = report.TotalInterestPaid
+			}
+			row.Installments[s] = installment
+			row.CumulativeInterests[s] = cumulative[s]
+			row.InstallmentDiffs[s] = installment.Sub(row.Installments[0])
+			row.CumulativeInterestDiff[s] = cumulative[s].Sub(row.CumulativeInterests[0])
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
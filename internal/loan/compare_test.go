@@ -0,0 +1,85 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCompare(t *testing.T) {
+	prepayment := Input{Loan: fixedLoan(120000, 24), EarlyRepayment: []EarlyRepayment{{Amount: decimal.NewFromInt(20000), Date: ParseDate("2023-06-25")}}}
+	tests := []struct {
+		name      string
+		scenarios []Scenario
+		months    int // 对比的月数,从放款月到最晚的结清月
+	}{
+		{
+			name: "repayment methods",
+			scenarios: []Scenario{
+				{Name: "等额本息", Action: "emi", Input: Input{Loan: fixedLoan(120000, 24)}},
+				{Name: "等额本金", Action: "epp", Input: Input{Loan: fixedLoan(120000, 24)}},
+			},
+			months: 25,
+		},
+		{
+			// 提前还款后结清更早,之后各月的还款为0
+			name: "prepayment",
+			scenarios: []Scenario{
+				{Name: "不提前还款", Action: "emi", Input: Input{Loan: fixedLoan(120000, 24)}},
+				{Name: "提前还款", Action: "emi", Input: prepayment},
+			},
+			months: 25,
+		},
+		{
+			name: "different terms",
+			scenarios: []Scenario{
+				{Name: "1年", Action: "emi", Input: Input{Loan: fixedLoan(120000, 12)}},
+				{Name: "3年", Action: "emi", Input: Input{Loan: fixedLoan(120000, 36)}},
+			},
+			months: 37,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison := Compare(tt.scenarios)
+			if len(comparison.Scenarios) != len(tt.scenarios) {
+				t.Fatalf("%d results, want %d", len(comparison.Scenarios), len(tt.scenarios))
+			}
+			if len(comparison.Rows) != tt.months {
+				t.Fatalf("%d months, want %d", len(comparison.Rows), tt.months)
+			}
+			for s, result := range comparison.Scenarios {
+				// 并发计算后结果仍按方案顺序
+				if result.Name != tt.scenarios[s].Name {
+					t.Errorf("result %d is %s, want %s", s, result.Name, tt.scenarios[s].Name)
+				}
+				installments := decimal.Zero
+				for _, report := range result.Reports {
+					if report.Purpose == "分期" {
+						installments = installments.Add(report.MonthTotalAmount)
+					}
+				}
+				sum := decimal.Zero
+				previous := decimal.Zero
+				for i, row := range comparison.Rows {
+					if i > 0 && !row.Month.Equal(comparison.Rows[i-1].Month.AddDate(0, 1, 0)) {
+						t.Fatalf("month %s does not follow %s", row.Month.Format("2006-01"), comparison.Rows[i-1].Month.Format("2006-01"))
+					}
+					sum = sum.Add(row.Installments[s])
+					assertDecimal(t, "installment diff", row.InstallmentDiffs[s], row.Installments[s].Sub(row.Installments[0]))
+					assertDecimal(t, "interest diff", row.CumulativeInterestDiff[s], row.CumulativeInterests[s].Sub(row.CumulativeInterests[0]))
+					if row.CumulativeInterests[s].LessThan(previous) {
+						t.Errorf("%s: cumulative interest decreased in %s", result.Name, row.Month.Format("2006-01"))
+					}
+					previous = row.CumulativeInterests[s]
+				}
+				assertDecimal(t, "installments", sum, installments)
+				assertDecimal(t, "total interest", previous, result.Summary.TotalInterest)
+			}
+		})
+	}
+	// 并发计算时复制提前还款记录,不修改传入的方案
+	if !prepayment.EarlyRepayment[0].Principal.IsZero() {
+		t.Error("scenario input was modified")
+	}
+}
@@ -13,24 +13,38 @@ type EarlyRepayment struct {
 	DueDateRate        decimal.Decimal // 当月利率
 	Principal          decimal.Decimal // 本金部分
 	Interest           decimal.Decimal // 利息部分
+	Fee                decimal.Decimal // 违约金
 	RemainingPrincipal decimal.Decimal // 剩余本金
+	KeepInstallment    bool            // 月供不变,缩短还款期限;默认期限不变,减少月供
 }
 
 func (loan *Loan) makeEarlyRepayment(remainingPrincipal decimal.Decimal, earlyRepayments []EarlyRepayment, dueDate time.Time) (amount, daysDiff decimal.Decimal) {
-	previousDueDate := loan.previousDueDate(dueDate)
+	return loan.makeEarlyRepaymentBetween(remainingPrincipal, earlyRepayments, loan.previousDueDate(dueDate), dueDate)
+}
+
+// 处理上一个还款日和本期还款日之间的提前还款,还款周期不是按月时使用
+func (loan *Loan) makeEarlyRepaymentBetween(remainingPrincipal decimal.Decimal, earlyRepayments []EarlyRepayment, previousDueDate, dueDate time.Time) (amount, daysDiff decimal.Decimal) {
 	for i, early := range earlyRepayments {
+		// 金额为0的提前还款不处理,否则会把当期利息计入本金
+		if !early.Amount.IsPositive() {
+			continue
+		}
 		if early.Date.After(previousDueDate) && early.Date.Before(dueDate) {
-			currentYearLPR := loan.getClosestLPRForYear(dueDate)
-			currentYearRate := currentYearLPR.Add(loan.PlusSpread).Div(decimal.NewFromInt(100))
+			currentYearRate := loan.aprAt(dueDate).Div(decimal.NewFromInt(100))
 			daysDiff = loan.daysDiff(previousDueDate, early.Date)
 			earlyInterest := remainingPrincipal.Mul(currentYearRate).Div(decimal.NewFromInt(360)).Mul(daysDiff)
 
-			amount = remainingPrincipal.Add(earlyInterest).Sub(early.Amount)
+			// 违约金按实际归还的本金计算,和本金一起从扣除利息后的提前还款金额中支付
+			APR := currentYearRate.Mul(decimal.NewFromInt(100))
+			fee := loan.prepaymentFee(early.Date, loan.prepaidPrincipal(early.Date, early.Amount.Sub(earlyInterest), APR), APR)
+
+			amount = remainingPrincipal.Add(earlyInterest).Add(fee).Sub(early.Amount)
 			// fmt.Println(dueDate, currentYearRate, earlyInterest, remainingPrincipal, daysDiff)
 
 			// 更新本金利息和利率
-			early.Principal = early.Amount.Sub(earlyInterest).Round(2)
+			early.Principal = early.Amount.Sub(earlyInterest).Sub(fee).Round(2)
 			early.Interest = earlyInterest.Round(2)
+			early.Fee = fee
 			early.RemainingPrincipal = remainingPrincipal.Sub(early.Principal).Round(2)
 			early.DueDateRate = currentYearRate.Mul(decimal.NewFromInt(100))
 			// 将更新后的 early 对象存储回 earlyRepayments 切片中
@@ -42,3 +56,13 @@ func (loan *Loan) makeEarlyRepayment(remainingPrincipal decimal.Decimal, earlyRe
 	// 如果本期没有提前还款没有则返回原值
 	return remainingPrincipal, decimal.Zero
 }
+
+// 本期提前还款是否选择月供不变,缩短还款期限
+func keepInstallmentBetween(earlyRepayments []EarlyRepayment, previousDueDate, dueDate time.Time) bool {
+	for _, early := range earlyRepayments {
+		if early.Amount.IsPositive() && early.Date.After(previousDueDate) && early.Date.Before(dueDate) {
+			return early.KeepInstallment
+		}
+	}
+	return false
+}
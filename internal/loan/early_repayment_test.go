@@ -0,0 +1,60 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 表单中未填写的提前还款金额为0,不能改变还款计划
+func TestZeroAmountEarlyRepayment(t *testing.T) {
+	loan := Loan{
+		InitialPrincipal: decimal.NewFromInt(1000000),
+		InitialTerm:      360,
+		InitialDate:      ParseDate("2022-05-25"),
+		PaymentDueDay:    18,
+		PlusSpread:       decimal.NewFromFloat(0.1),
+		LPR:              Lprs,
+	}
+	schedules := map[string]func([]EarlyRepayment) []MonthlyPayment{
+		"epp":      loan.EqualPrincipalPayment,
+		"emi":      loan.EqualMonthlyInstallment,
+		"balloon":  loan.BalloonPayment,
+		"biweekly": loan.BiWeeklyPayment,
+	}
+	for action, schedule := range schedules {
+		t.Run(action, func(t *testing.T) {
+			want := schedule(nil)
+			got := schedule([]EarlyRepayment{{Amount: decimal.Zero, Date: ParseDate("2023-08-19")}})
+			if len(got) != len(want) {
+				t.Fatalf("got %d payments, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !got[i].Principal.Equal(want[i].Principal) || !got[i].Interest.Equal(want[i].Interest) ||
+					!got[i].RemainingPrincipal.Equal(want[i].RemainingPrincipal) {
+					t.Fatalf("term %d (%s): got principal %s interest %s remaining %s, want %s %s %s",
+						want[i].LoanTerm, want[i].DueDate.Format(time.DateOnly),
+						got[i].Principal, got[i].Interest, got[i].RemainingPrincipal,
+						want[i].Principal, want[i].Interest, want[i].RemainingPrincipal)
+				}
+			}
+		})
+	}
+}
+
+// 只有未填写的提前还款时,汇总中节省的利息为0
+func TestZeroAmountEarlyRepaymentSummary(t *testing.T) {
+	for _, action := range []string{"epp", "emi", "balloon", "biweekly"} {
+		t.Run(action, func(t *testing.T) {
+			input := Input{
+				Loan:           fixedLoan(1000000, 120),
+				EarlyRepayment: []EarlyRepayment{{Amount: decimal.Zero, Date: ParseDate("2023-08-19")}, {Amount: decimal.Zero, Date: ParseDate("2024-03-05")}},
+			}
+			summary := BuildSummary(input, action, BuildReport(input, action))
+			if !summary.InterestSaved.IsZero() {
+				t.Errorf("interest saved = %s, want 0", summary.InterestSaved)
+			}
+		})
+	}
+}
@@ -18,9 +18,10 @@ import (
 // n 是还款总期数
 
 // emi
-func (loan *Loan) calculateEMI(remainingPrincipal decimal.Decimal, monthlyInterestRate decimal.Decimal, loanTerm int, dueDate time.Time) decimal.Decimal {
+// remainTerm 是剩余期数(含本期)
+func (loan *Loan) calculateEMI(remainingPrincipal decimal.Decimal, monthlyInterestRate decimal.Decimal, remainTerm int) decimal.Decimal {
 	onePlusMonthlyInterestRate := decimal.NewFromFloat(1).Add(monthlyInterestRate)
-	onePlusMonthlyInterestRatePow := onePlusMonthlyInterestRate.Pow(decimal.NewFromInt(int64(loan.InitialTerm - loanTerm + 1)))
+	onePlusMonthlyInterestRatePow := onePlusMonthlyInterestRate.Pow(decimal.NewFromInt(int64(remainTerm)))
 	emi := remainingPrincipal.Mul(monthlyInterestRate).Mul(onePlusMonthlyInterestRatePow).Div(onePlusMonthlyInterestRatePow.Sub(decimal.NewFromInt(1))).Round(2)
 	return emi
 }
@@ -31,14 +32,17 @@ func (loan *Loan) EqualMonthlyInstallment(earlyRepayment []EarlyRepayment) []Mon
 	dueDate := time.Date(loan.InitialDate.Year(), loan.InitialDate.Month()+1, loan.PaymentDueDay, 0, 0, 0, 0, loan.InitialDate.Location())
 	// 年利率APR,Annual Percentage Rate
 	// 月利率MIR,Monthly Interest Rate
-	APR := loan.getClosestLPRForYear(dueDate).Add(loan.PlusSpread)
+	APR := loan.aprAt(dueDate)
 	MIR := APR.Div(decimal.NewFromInt(1200))
-	emi := loan.calculateEMI(remainingPrincipal, MIR, 1, dueDate)
+	emi := loan.calculateEMI(remainingPrincipal, MIR, loan.InitialTerm)
 	lastRemainingPrincipal := decimal.Zero
 	principalPayment := decimal.Zero
 	interestPayment := decimal.Zero
+	spreadAdjusted := false
+	// 提前还款缩短期限后最后一期会提前
+	lastTerm := loan.InitialTerm
 
-	for loanTerm := 1; loanTerm <= loan.InitialTerm; loanTerm++ {
+	for loanTerm := 1; loanTerm <= lastTerm; loanTerm++ {
 
 		// 还款总额或者利率变化需要重新计算
 		// 提前还款会影响还款金额
@@ -46,18 +50,50 @@ func (loan *Loan) EqualMonthlyInstallment(earlyRepayment []EarlyRepayment) []Mon
 		// 只在提前还款后,重新计算每月应还本金;否则多次计算会有小数点导致的差异
 		if amount.Cmp(remainingPrincipal) == -1 {
 			remainingPrincipal = amount.Round(2)
-			emi = loan.calculateEMI(remainingPrincipal, MIR, loanTerm, dueDate)
+			// 提前还款已还清
+			if !remainingPrincipal.IsPositive() {
+				break
+			}
+			// 月供不变缩短期限,否则期限不变重算月供
+			n, ok := shortenedTerm(remainingPrincipal, decimal.Zero, MIR, emi)
+			if keepInstallmentBetween(earlyRepayment, loan.previousDueDate(dueDate), dueDate) && ok && n < lastTerm-loanTerm+1 {
+				lastTerm = loanTerm + n - 1
+			} else {
+				emi = loan.calculateEMI(remainingPrincipal, MIR, lastTerm-loanTerm+1)
+			}
 		}
 
+		// 上一期加点调整,从本期起按调整后的利率重算月供
+		if spreadAdjusted {
+			emi = loan.calculateEMI(remainingPrincipal, MIR, lastTerm-loanTerm+1)
+			spreadAdjusted = false
+		}
+		_, spreadChanged := loan.spreadChangeBetween(loan.previousDueDate(dueDate), dueDate)
+
 		// 利率变化,每年利率变更月重算一次.如果每月计算因为小数问题会导致有差异.
 		if loanTerm%12 == 1 {
 			perviousAPR := APR
-			APR = loan.getClosestLPRForYear(dueDate).Add(loan.PlusSpread)
+			APR = loan.aprAt(dueDate)
 			MIR = APR.Div(decimal.NewFromInt(1200))
 			if perviousAPR.Cmp(APR) != 0 {
-				emi = loan.calculateEMI(remainingPrincipal, MIR, loanTerm, dueDate)
+				emi = loan.calculateEMI(remainingPrincipal, MIR, lastTerm-loanTerm+1)
 			}
+		} else if spreadChanged {
+			// 加点调整月按调整后的利率显示,从下一期起重算月供
+			previousAPR := APR
+			APR = loan.aprAt(dueDate)
+			MIR = APR.Div(decimal.NewFromInt(1200))
+			spreadAdjusted = previousAPR.Cmp(APR) != 0
 		}
+		// 按利率分段计息,加点调整生效日也是分段点,可能与lpr变更,第一期和最后一期在同一期
+		interestBetween := func(principal decimal.Decimal, from, to time.Time, days decimal.Decimal, changes ...time.Time) decimal.Decimal {
+			return segmentedInterest(loan.rateSegments(from, to, days, APR, changes...), func(APR, days decimal.Decimal) decimal.Decimal {
+				return principal.Mul(APR).Div(decimal.NewFromInt(100)).Div(decimal.NewFromInt(360)).Mul(days)
+			})
+		}
+
+		// 最后一期归还上期剩余本金
+		lastRemainingPrincipal = remainingPrincipal
 
 		switch {
 		case loanTerm == 1: // 第一期
@@ -72,32 +108,21 @@ func (loan *Loan) EqualMonthlyInstallment(earlyRepayment []EarlyRepayment) []Mon
 
 			// 第一个月天数D=30−放款日+1
 			days := loan.daysDiff(loan.InitialDate, dueDate).Sub(decimal.NewFromInt(1))
-			// 第一期利息按天数计算
-			interestPayment = remainingPrincipal.Mul(APR).Div(decimal.NewFromInt(100)).Div(decimal.NewFromInt(360)).Mul(days).Round(2)
+			// 第一期利息按天数计算,放款次日起计息
+			interestPayment = interestBetween(remainingPrincipal, loan.InitialDate.AddDate(0, 0, 1), dueDate, days)
 			// 本金
 			principalPayment = emi.Sub(interestPayment)
 			remainingPrincipal = remainingPrincipal.Sub(principalPayment)
 
 		case loanTerm%12 == 1: // lpr变更月
 			// 分为两段
-			daysBefore, daysAfter := loan.LPRChangeDateOffset(dueDate)
 			// 上一年利率 2023-05-18 ~ 2023-05-24
-			previousDueDate := loan.previousDueDate(dueDate)
-			previousAPR := loan.getClosestLPRForYear(previousDueDate).Add(loan.PlusSpread)
-			interestPayment = remainingPrincipal.Mul(previousAPR).Div(decimal.NewFromInt(100)).Div(decimal.NewFromInt(360)).Mul(daysBefore).Round(4)
 			// 当年利率 2023-05-25 ~ 2023-06-18
-			interestPayment = interestPayment.Add((remainingPrincipal.Mul(APR).Div(decimal.NewFromInt(100)).Div(decimal.NewFromInt(360))).Mul(daysAfter)).Round(2)
+			interestPayment = interestBetween(remainingPrincipal, loan.previousDueDate(dueDate), dueDate, decimal.NewFromInt(30), loan.lprUpdateDate(dueDate))
 			principalPayment = emi.Sub(interestPayment)
 			remainingPrincipal = remainingPrincipal.Sub(principalPayment)
 
-		case loanTerm == loan.InitialTerm-1: // 倒数第二期
-
-			interestPayment = remainingPrincipal.Mul(MIR).Round(2)
-			principalPayment = emi.Sub(interestPayment)
-			remainingPrincipal = remainingPrincipal.Sub(principalPayment)
-			lastRemainingPrincipal = remainingPrincipal
-			// fmt.Println(lastRemainingPrincipal)
-		case loanTerm == loan.InitialTerm: // 最后一期
+		case loanTerm == lastTerm: // 最后一期
 
 			// 最后一期还款日变更
 			lastDueDate := loan.InitialDate.AddDate(0, loanTerm, 0)
@@ -108,11 +133,17 @@ func (loan *Loan) EqualMonthlyInstallment(earlyRepayment []EarlyRepayment) []Mon
 
 			// 利息=剩余本金*每天利率*天数
 			days := loan.daysDiff(loan.previousDueDate(dueDate), lastDueDate)
-			interestPayment = principalPayment.Mul(APR).Div(decimal.NewFromInt(100)).Div(decimal.NewFromInt(360)).Mul(days).Round(2)
+			interestPayment = interestBetween(principalPayment, loan.previousDueDate(dueDate), lastDueDate, days)
 			emi = principalPayment.Add(interestPayment)
 			// 剩余本金
 			remainingPrincipal = decimal.Zero
 
+		case spreadChanged: // 加点调整月
+			// 分为两段,生效日前按原利率,生效日起按调整后的利率
+			interestPayment = interestBetween(remainingPrincipal, loan.previousDueDate(dueDate), dueDate, decimal.NewFromInt(30))
+			principalPayment = emi.Sub(interestPayment)
+			remainingPrincipal = remainingPrincipal.Sub(principalPayment)
+
 		default:
 			// fmt.Println(loanTerm)
 			interestPayment = remainingPrincipal.Mul(MIR).Round(2)
@@ -120,6 +151,13 @@ func (loan *Loan) EqualMonthlyInstallment(earlyRepayment []EarlyRepayment) []Mon
 			remainingPrincipal = remainingPrincipal.Sub(principalPayment)
 		}
 
+		// 缩短期限后最后一期可能是lpr变更月,归还全部剩余本金
+		if loanTerm == lastTerm && !remainingPrincipal.IsZero() {
+			principalPayment = principalPayment.Add(remainingPrincipal)
+			emi = principalPayment.Add(interestPayment)
+			remainingPrincipal = decimal.Zero
+		}
+
 		// 剩余本金带入下一期计算
 
 		payment := MonthlyPayment{
@@ -134,7 +172,7 @@ func (loan *Loan) EqualMonthlyInstallment(earlyRepayment []EarlyRepayment) []Mon
 
 		monthlyPayments = append(monthlyPayments, payment)
 
-		dueDate = dueDate.AddDate(0, 1, 0)
+		dueDate = Monthly.next(dueDate)
 	}
 
 	return monthlyPayments
@@ -15,6 +15,10 @@ type MonthlyPayment struct {
 	RemainingPrincipal decimal.Decimal // 剩余本金
 	DueDateRate        decimal.Decimal // 当月利率=lpr+加点
 	DueDate            time.Time       // 当月还款日期
+	PenaltyInterest    decimal.Decimal // 本期计提的罚息
+	CompoundInterest   decimal.Decimal // 本期计提的复利
+	Arrears            decimal.Decimal // 逾期未还金额(本金,利息,罚息和复利)
+	Overdue            bool            // 是否逾期
 }
 
 // !EPP 是  "Equal Principal Payments"  的缩写，意思是等额本息。
@@ -42,12 +46,13 @@ func (loan *Loan) EqualPrincipalPayment(earlyRepayment []EarlyRepayment) []Month
 	// 4.如果是lpr变更的月份,分为两段计算.
 	//		第一段lpr为前一年lpr,天数是变更日~还款日(取头去尾)
 	//		第二段为当年lpr,天数是30-第一段
-	// 5.提前还款会对下月的还款计算有影响
+	// 5.如果是加点调整的月份,以生效日为界分段计算,与lpr变更,第一期或最后一期在同一期时一起分段
+	// 6.提前还款会对下月的还款计算有影响
 	//		暂不考虑lpr变更这个月提前还款.
-	// 6.第一期利息的计算
+	// 7.第一期利息的计算
 	//		放款日当天不计算,原因是可能下午才放款?
 	//		天数 = 还款日-放款日-1
-	// 7.最后一期本金的计算去掉误差
+	// 8.最后一期本金的计算去掉误差
 	//		最后一期本金 = 贷款金额 - (贷款金额/期数).round(2)*(期数-1)
 	//		最后一期还款日 = 默认是放款日,而不是还款日
 
@@ -56,16 +61,29 @@ func (loan *Loan) EqualPrincipalPayment(earlyRepayment []EarlyRepayment) []Month
 	principalPayment := remainingPrincipal.Div(decimal.NewFromInt(int64(loan.InitialTerm))).Round(2)
 	lastPrincipalPayment := principalPayment.Add(remainingPrincipal.Sub(principalPayment.Mul(decimal.NewFromInt(int64(loan.InitialTerm)))))
 	dueDate := time.Date(loan.InitialDate.Year(), loan.InitialDate.Month()+1, loan.PaymentDueDay, 0, 0, 0, 0, loan.InitialDate.Location())
+	// 提前还款缩短期限后最后一期会提前
+	lastTerm := loan.InitialTerm
 
-	for loanTerm := 1; loanTerm <= loan.InitialTerm; loanTerm++ {
+	for loanTerm := 1; loanTerm <= lastTerm; loanTerm++ {
 		interestPayment := decimal.Zero
 		// 计算如果有提前还款则需要减去提前还款的本金
 		amount, daysDiff := loan.makeEarlyRepayment(remainingPrincipal, earlyRepayment, dueDate)
 		// 只在提前还款后,重新计算每月应还本金;否则多次计算会有小数点导致的差异
 		if amount.Cmp(remainingPrincipal) == -1 {
-			remainTerm := loan.InitialTerm - loanTerm + 1
-			principalPayment = amount.Div(decimal.NewFromInt(int64(remainTerm))).Round(2)
-			lastPrincipalPayment = principalPayment.Add(amount.Sub(principalPayment.Mul(decimal.NewFromInt(int64(remainTerm)))))
+			// 提前还款已还清
+			if !amount.IsPositive() {
+				break
+			}
+			remainTerm := lastTerm - loanTerm + 1
+			// 每月本金不变缩短期限,剩余本金不足一期的部分在最后一期归还
+			n := int(amount.Div(principalPayment).Ceil().IntPart())
+			if keepInstallmentBetween(earlyRepayment, loan.previousDueDate(dueDate), dueDate) && n < remainTerm {
+				lastTerm = loanTerm + n - 1
+				lastPrincipalPayment = amount.Sub(principalPayment.Mul(decimal.NewFromInt(int64(n - 1))))
+			} else {
+				principalPayment = amount.Div(decimal.NewFromInt(int64(remainTerm))).Round(2)
+				lastPrincipalPayment = principalPayment.Add(amount.Sub(principalPayment.Mul(decimal.NewFromInt(int64(remainTerm)))))
+			}
 
 		}
 
@@ -73,8 +91,15 @@ func (loan *Loan) EqualPrincipalPayment(earlyRepayment []EarlyRepayment) []Month
 		// 以下处理每月正常还款
 		// 年利率APR,Annual Percentage Rate
 		// 月利率MIR,Monthly Interest Rate
-		APR := loan.getClosestLPRForYear(dueDate).Add(loan.PlusSpread)
+		APR := loan.aprAt(dueDate)
 		MIR := APR.Div(decimal.NewFromInt(1200))
+		_, spreadChanged := loan.spreadChangeBetween(loan.previousDueDate(dueDate), dueDate)
+		// 按利率分段计息,加点调整生效日也是分段点,可能与lpr变更,第一期和最后一期在同一期
+		interestBetween := func(principal decimal.Decimal, from, to time.Time, days decimal.Decimal, changes ...time.Time) decimal.Decimal {
+			return segmentedInterest(loan.rateSegments(from, to, days, APR, changes...), func(APR, days decimal.Decimal) decimal.Decimal {
+				return principal.Mul(APR.Div(decimal.NewFromInt(1200))).Div(decimal.NewFromInt(30)).Mul(days)
+			})
+		}
 		switch {
 		case loanTerm == 1: // 第一期
 			// 2968.28
@@ -82,27 +107,28 @@ func (loan *Loan) EqualPrincipalPayment(earlyRepayment []EarlyRepayment) []Month
 			// 实际天数2022-05-26 ~ 2022-06-17 共23天
 			// days := int(dueDate.Sub(loan.InitialDate).Hours() / 24)
 			days := loan.daysDiff(loan.InitialDate, dueDate).Sub(decimal.NewFromInt(1))
-			interestPayment = remainingPrincipal.Mul(MIR).Div(decimal.NewFromInt(30)).Mul(days).Round(2)
+			interestPayment = interestBetween(remainingPrincipal, loan.InitialDate.AddDate(0, 0, 1), dueDate, days)
 			// fmt.Printf("principal")
 		case loanTerm%12 == 1: // lpr变更月
 			// 分为两段
-			daysBefore, daysAfter := loan.LPRChangeDateOffset(dueDate)
-
 			// 上一年利率 2023-05-18 ~ 2023-05-24
-			previousDueDate := loan.previousDueDate(dueDate)
-			previousAPR := loan.getClosestLPRForYear(previousDueDate).Add(loan.PlusSpread)
-			previousMIR := previousAPR.Div(decimal.NewFromInt(1200))
-			interestPayment = remainingPrincipal.Mul(previousMIR).Div(decimal.NewFromInt(30)).Mul(daysBefore).Round(4)
-
 			// 当年利率 2023-05-25 ~ 2023-06-18
-			interestPayment = interestPayment.Add((remainingPrincipal.Mul(MIR).Div(decimal.NewFromInt(30)).Mul(daysAfter))).Round(2)
+			interestPayment = interestBetween(remainingPrincipal, loan.previousDueDate(dueDate), dueDate, decimal.NewFromInt(30), loan.lprUpdateDate(dueDate))
 
-		case loanTerm == loan.InitialTerm: // 最后一期
+		case loanTerm == lastTerm: // 最后一期
 			lastDueDate := loan.InitialDate.AddDate(0, loanTerm, 0)
 			days := loan.daysDiff(loan.previousDueDate(dueDate), lastDueDate)
 			principalPayment = lastPrincipalPayment.Round(2)
+			interestPayment = segmentedInterest(loan.rateSegments(loan.previousDueDate(dueDate), lastDueDate, days, APR), func(APR, days decimal.Decimal) decimal.Decimal {
+				return lastPrincipalPayment.Mul(APR).Div(decimal.NewFromInt(100)).Div(decimal.NewFromInt(360)).Mul(days)
+			})
 			dueDate = lastDueDate
-			interestPayment = lastPrincipalPayment.Mul(APR).Div(decimal.NewFromInt(100)).Div(decimal.NewFromInt(360)).Mul(days).Round(2)
+
+		case spreadChanged: // 加点调整月
+			// 分为两段,生效日前按原利率,生效日起按调整后的利率
+			// 本期有提前还款时,提前还款日之前的利息已随提前还款归还,从提前还款日起计息
+			from := loan.previousDueDate(dueDate).AddDate(0, 0, int(daysDiff.IntPart()))
+			interestPayment = interestBetween(remainingPrincipal, from, dueDate, decimal.NewFromInt(30).Sub(daysDiff))
 
 		default:
 			remainDay := decimal.NewFromInt(int64(30)).Sub(daysDiff)
@@ -111,6 +137,11 @@ func (loan *Loan) EqualPrincipalPayment(earlyRepayment []EarlyRepayment) []Month
 		}
 
 		remainingPrincipal = remainingPrincipal.Sub(principalPayment).Round(2)
+		// 缩短期限后最后一期可能是lpr变更月,归还全部剩余本金
+		if loanTerm == lastTerm && !remainingPrincipal.IsZero() {
+			principalPayment = principalPayment.Add(remainingPrincipal)
+			remainingPrincipal = decimal.Zero
+		}
 
 		payment := MonthlyPayment{
 			LoanTerm:           loanTerm,
@@ -124,7 +155,7 @@ func (loan *Loan) EqualPrincipalPayment(earlyRepayment []EarlyRepayment) []Month
 		monthlypayments = append(monthlypayments, payment)
 
 		// 下一个还款日期
-		dueDate = dueDate.AddDate(0, 1, 0)
+		dueDate = Monthly.next(dueDate)
 
 	}
 
@@ -0,0 +1,84 @@
+package loan
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// Sheet 导出文件中的一个工作表
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// ReportSheet 还款计划工作表
+func ReportSheet(reports []Report) Sheet {
+	rows := make([][]string, 0, len(reports))
+	for _, report := range reports {
+		rows = append(rows, ReportRow(report))
+	}
+	return Sheet{Name: "还款计划", Header: ReportHeader, Rows: rows}
+}
+
+// AnnualSheet 年度汇总工作表
+func AnnualSheet(name string, statements []AnnualStatement) Sheet {
+	rows := make([][]string, 0, len(statements))
+	for _, statement := range statements {
+		rows = append(rows, []string{
+			statement.Label,
+			statement.Start.Format("2006-01-02"),
+			statement.End.Format("2006-01-02"),
+			strconv.Itoa(statement.Periods),
+			statement.Principal.String(),
+			statement.Interest.String(),
+			statement.Prepayments.String(),
+			statement.Fees.String(),
+			statement.TotalPaid.String(),
+			statement.AverageAPR.String(),
+			statement.EndingBalance.String(),
+		})
+	}
+	return Sheet{
+		Name:   name,
+		Header: []string{"年度", "开始日期", "结束日期", "期数", "本金", "利息", "提前还款", "违约金罚息", "还款总额", "平均APR", "年末剩余本金"},
+		Rows:   rows,
+	}
+}
+
+// WriteWorkbook 按 SpreadsheetML 2003 格式输出多个工作表,excel和wps可以直接打开
+// 可以解析为数字的单元格按数字输出,其余按文本输出
+func WriteWorkbook(w io.Writer, sheets []Sheet) error {
+	out := bufio.NewWriter(w)
+	out.WriteString(xml.Header)
+	out.WriteString(`<?mso-application progid="Excel.Sheet"?>` + "\n")
+	out.WriteString(`<Workbook xmlns="urn:schemas-microsoft-com:office:spreadsheet" xmlns:ss="urn:schemas-microsoft-com:office:spreadsheet">` + "\n")
+	for _, sheet := range sheets {
+		out.WriteString(`<Worksheet ss:Name="`)
+		xml.EscapeText(out, []byte(sheet.Name))
+		out.WriteString("\"><Table>\n")
+		writeWorkbookRow(out, sheet.Header, false)
+		for _, row := range sheet.Rows {
+			writeWorkbookRow(out, row, true)
+		}
+		out.WriteString("</Table></Worksheet>\n")
+	}
+	out.WriteString("</Workbook>\n")
+	return out.Flush()
+}
+
+func writeWorkbookRow(out *bufio.Writer, cells []string, numeric bool) {
+	out.WriteString("<Row>")
+	for _, cell := range cells {
+		cellType := "String"
+		if _, err := strconv.ParseFloat(cell, 64); numeric && err == nil {
+			cellType = "Number"
+		}
+		out.WriteString(`<Cell><Data ss:Type="` + cellType + `">`)
+		xml.EscapeText(out, []byte(cell))
+		out.WriteString("</Data></Cell>")
+	}
+	out.WriteString("</Row>\n")
+}
@@ -0,0 +1,75 @@
+package loan
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWriteWorkbook(t *testing.T) {
+	input := Input{Loan: fixedLoan(120000, 24)}
+	reports := BuildReport(input, "emi")
+	statements := input.Loan.BuildAnnualStatements(reports)
+	sheets := []Sheet{
+		ReportSheet(reports),
+		AnnualSheet("自然年", statements.CalendarYears),
+		{Name: "A&B <测试>", Header: []string{"名称"}, Rows: [][]string{{"a<b & c"}}},
+	}
+	var b strings.Builder
+	if err := WriteWorkbook(&b, sheets); err != nil {
+		t.Fatal(err)
+	}
+
+	// 按 SpreadsheetML 解析,检查工作表和单元格类型
+	var workbook struct {
+		Worksheets []struct {
+			Name string `xml:"Name,attr"`
+			Rows []struct {
+				Cells []struct {
+					Data struct {
+						Type  string `xml:"Type,attr"`
+						Value string `xml:",chardata"`
+					} `xml:"Data"`
+				} `xml:"Cell"`
+			} `xml:"Table>Row"`
+		} `xml:"Worksheet"`
+	}
+	if err := xml.Unmarshal([]byte(b.String()), &workbook); err != nil {
+		t.Fatalf("invalid xml: %v", err)
+	}
+	if len(workbook.Worksheets) != len(sheets) {
+		t.Fatalf("%d worksheets, want %d", len(workbook.Worksheets), len(sheets))
+	}
+	for i, sheet := range sheets {
+		got := workbook.Worksheets[i]
+		if got.Name != sheet.Name {
+			t.Errorf("worksheet %d = %q, want %q", i, got.Name, sheet.Name)
+		}
+		if len(got.Rows) != len(sheet.Rows)+1 {
+			t.Errorf("%s: %d rows, want %d", sheet.Name, len(got.Rows), len(sheet.Rows)+1)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		sheet int
+		row   int
+		cell  int
+		value string
+		kind  string
+	}{
+		{name: "header", sheet: 1, row: 0, cell: 3, value: "期数", kind: "String"},
+		{name: "label", sheet: 1, row: 1, cell: 0, value: "2023", kind: "Number"},
+		{name: "date", sheet: 1, row: 1, cell: 1, value: "2023-01-01", kind: "String"},
+		{name: "periods", sheet: 1, row: 1, cell: 3, value: "11", kind: "Number"},
+		{name: "escaped text", sheet: 2, row: 1, cell: 0, value: "a<b & c", kind: "String"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := workbook.Worksheets[tt.sheet].Rows[tt.row].Cells[tt.cell].Data
+			if data.Value != tt.value || data.Type != tt.kind {
+				t.Errorf("cell = %q (%s), want %q (%s)", data.Value, data.Type, tt.value, tt.kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package loan
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 求解的变量
+const (
+	GoalVariableTerm      = "term"      // 贷款期限(月)
+	GoalVariableSpread    = "spread"    // 加点(%)
+	GoalVariablePrincipal = "principal" // 贷款本金
+)
+
+// 求解的目标
+const (
+	GoalTargetInstallment = "installment" // 最高每期还款
+	GoalTargetInterest    = "interest"    // 利息合计
+	GoalTargetPayoff      = "payoff"      // 结清日期
+)
+
+// ErrNoSolution 在求解范围内无法达到目标
+var ErrNoSolution = errors.New("goal seek: no solution in range")
+
+// Goal 反向求解的目标
+// 求解变量在 [Min, Max] 之间取值,使目标恰好不超过 Value 或 Date
+type Goal struct {
+	Variable string          // 求解的变量
+	Target   string          // 求解的目标
+	Value    decimal.Decimal // 每期还款或利息合计的目标
+	Date     time.Time       // 结清日期的目标
+	Min      decimal.Decimal // 变量下限
+	Max      decimal.Decimal // 变量上限
+}
+
+// GoalResult 求解结果和对应还款计划的指标
+type GoalResult struct {
+	Variable        string
+	Target          string
+	Solution        decimal.Decimal // 期限为月数,加点为百分点,本金为元
+	PeakInstallment decimal.Decimal // 最高每期还款
+	TotalInterest   decimal.Decimal // 利息合计
+	PayoffDate      time.Time       // 结清日期
+}
+
+// 按变量的值修改贷款参数
+func (goal Goal) apply(inputdata Input, x float64) Input {
+	inputdata = inputdata.clone()
+	switch goal.Variable {
+	case GoalVariableTerm:
+		inputdata.Loan.InitialTerm = int(x)
+	case GoalVariableSpread:
+		inputdata.Loan.PlusSpread = decimal.NewFromFloat(x).Round(4)
+	case GoalVariablePrincipal:
+		inputdata.Loan.InitialPrincipal = decimal.NewFromFloat(x)
+	}
+	return inputdata
+}
+
+// 变量的步长,期限按整月,加点按0.01个基点,本金按1元
+func (goal Goal) step() float64 {
+	if goal.Variable == GoalVariableSpread {
+		return 0.0001
+	}
+	return 1
+}
+
+// 二分法的精度,取步长的一半,按步长取整后可以区分相邻的两个值
+func (goal Goal) tolerance() float64 {
+	return goal.step() / 2
+}
+
+// 按步长向下取整
+func (goal Goal) round(x float64) float64 {
+	if goal.Variable == GoalVariableSpread {
+		return math.Floor(x*10000+1e-6) / 10000
+	}
+	return math.Floor(x)
+}
+
+// 计算还款计划的指标
+func goalMetrics(inputdata Input, action string) GoalResult {
+	result := GoalResult{}
+	for _, report := range BuildReport(inputdata, action) {
+		switch report.Purpose {
+		case "分期", "提前还款", "结清":
+			result.TotalInterest = result.TotalInterest.Add(report.Interest)
+			if !report.IsEmptyEarlyRepayment() {
+				result.PayoffDate = report.DueDate
+			}
+		}
+		if report.Purpose == "分期" && report.MonthTotalAmount.GreaterThan(result.PeakInstallment) {
+			result.PeakInstallment = report.MonthTotalAmount
+		}
+	}
+	return result
+}
+
+// 目标对应的指标,结清日期换算为天数
+func (goal Goal) metric(result GoalResult) float64 {
+	switch goal.Target {
+	case GoalTargetInterest:
+		return result.TotalInterest.InexactFloat64()
+	case GoalTargetPayoff:
+		return float64(result.PayoffDate.Unix()) / 86400
+	}
+	return result.PeakInstallment.InexactFloat64()
+}
+
+func (goal Goal) targetValue() float64 {
+	if goal.Target == GoalTargetPayoff {
+		return float64(goal.Date.Unix()) / 86400
+	}
+	return goal.Value.InexactFloat64()
+}
+
+// SeekGoal 反向求解贷款期限,加点或本金,使最高每期还款,利息合计或结清日期达到目标
+// 1.分别计算变量取上下限时的指标,目标不在两者之间时返回 ErrNoSolution,两者相等且等于目标时取下限
+// 2.指标随变量增大时,取指标不超过目标的最大值;随变量减小时(如期限和每期还款),取指标不超过目标的最小值
+func SeekGoal(inputdata Input, action string, goal Goal) (GoalResult, error) {
+	switch goal.Variable {
+	case GoalVariableTerm, GoalVariableSpread, GoalVariablePrincipal:
+	default:
+		return GoalResult{}, errors.New("goal seek: unknown variable " + goal.Variable)
+	}
+	switch goal.Target {
+	case GoalTargetInstallment, GoalTargetInterest, GoalTargetPayoff:
+	default:
+		return GoalResult{}, errors.New("goal seek: unknown target " + goal.Target)
+	}
+	low, high := goal.Min.InexactFloat64(), goal.Max.InexactFloat64()
+	if high < low {
+		return GoalResult{}, errors.New("goal seek: max should not be less than min")
+	}
+
+	metricAt := func(x float64) float64 {
+		return goal.metric(goalMetrics(goal.apply(inputdata, goal.round(x)), action))
+	}
+	target := goal.targetValue()
+	lowMetric, highMetric := metricAt(low), metricAt(high)
+	if target < math.Min(lowMetric, highMetric) || target > math.Max(lowMetric, highMetric) {
+		return GoalResult{}, ErrNoSolution
+	}
+
+	var solution float64
+	switch {
+	case lowMetric < highMetric:
+		solution = goal.round(bisect(low, high, goal.tolerance(), func(x float64) bool { return metricAt(x) <= target }))
+	case lowMetric <= target:
+		// 下限已达到目标,包括指标不随变量变化且等于目标
+		solution = low
+	default:
+		// 指标递减,求指标仍超过目标的最大值,再加一个精度
+		solution = goal.round(bisect(low, high, goal.tolerance(), func(x float64) bool { return metricAt(x) > target }))
+		solution = math.Min(solution+goal.step(), high)
+	}
+
+	solved := goal.apply(inputdata, solution)
+	result := goalMetrics(solved, action)
+	result.Variable, result.Target = goal.Variable, goal.Target
+	switch goal.Variable {
+	case GoalVariableTerm:
+		result.Solution = decimal.NewFromInt(int64(solved.Loan.InitialTerm))
+	case GoalVariableSpread:
+		result.Solution = solved.Loan.PlusSpread
+	case GoalVariablePrincipal:
+		result.Solution = solved.Loan.InitialPrincipal
+	}
+	return result, nil
+}
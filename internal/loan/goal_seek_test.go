@@ -0,0 +1,105 @@
+package loan
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSeekGoal(t *testing.T) {
+	base := Input{Loan: fixedLoan(100000, 120)}
+	metricAt := func(goal Goal, x float64) float64 {
+		return goal.metric(goalMetrics(goal.apply(base, x), "emi"))
+	}
+	// 期限取上下限时的最高月供,用于测试目标正好等于上下限指标的情况
+	// 期限较长时最后一期的尾差会超过月供,最高每期还款不再单调,上限取120期
+	peakAt := func(term float64) decimal.Decimal {
+		return decimal.NewFromFloat(metricAt(Goal{Variable: GoalVariableTerm, Target: GoalTargetInstallment}, term))
+	}
+
+	tests := []struct {
+		name       string
+		goal       Goal
+		increasing bool    // 指标是否随变量增大
+		want       float64 // 期望的解,为0时只检查解是最优的
+		err        error
+	}{
+		{
+			// 本金越大月供越高,求月供不超过目标的最大本金
+			name:       "increasing principal",
+			goal:       Goal{Variable: GoalVariablePrincipal, Target: GoalTargetInstallment, Value: decimal.NewFromInt(2000), Min: decimal.NewFromInt(10000), Max: decimal.NewFromInt(1000000)},
+			increasing: true,
+		},
+		{
+			name:       "increasing term for payoff date",
+			goal:       Goal{Variable: GoalVariableTerm, Target: GoalTargetPayoff, Date: ParseDate("2030-06-30"), Min: decimal.NewFromInt(12), Max: decimal.NewFromInt(360)},
+			increasing: true,
+			want:       89,
+		},
+		{
+			// 期限越长月供越低,求月供不超过目标的最短期限
+			name: "decreasing term",
+			goal: Goal{Variable: GoalVariableTerm, Target: GoalTargetInstallment, Value: decimal.NewFromInt(1500), Min: decimal.NewFromInt(12), Max: decimal.NewFromInt(120)},
+		},
+		{
+			// 只有上限满足目标,二分得到上限前一个月,加一个步长后为上限
+			name: "decreasing term at max",
+			goal: Goal{Variable: GoalVariableTerm, Target: GoalTargetInstallment, Value: peakAt(120), Min: decimal.NewFromInt(12), Max: decimal.NewFromInt(120)},
+			want: 120,
+		},
+		{
+			name: "decreasing term at min",
+			goal: Goal{Variable: GoalVariableTerm, Target: GoalTargetInstallment, Value: peakAt(12), Min: decimal.NewFromInt(12), Max: decimal.NewFromInt(120)},
+			want: 12,
+		},
+		{
+			// 固定利率不受加点影响,指标不变且等于目标时取下限
+			name: "constant metric equal to target",
+			goal: Goal{Variable: GoalVariableSpread, Target: GoalTargetInstallment, Value: decimal.NewFromFloat(metricAt(Goal{Variable: GoalVariableSpread}, 0)), Min: decimal.NewFromFloat(-0.5), Max: decimal.NewFromInt(1)},
+			want: -0.5,
+		},
+		{
+			name: "constant metric not equal to target",
+			goal: Goal{Variable: GoalVariableSpread, Target: GoalTargetInstallment, Value: decimal.NewFromInt(2000), Min: decimal.NewFromFloat(-0.5), Max: decimal.NewFromInt(1)},
+			err:  ErrNoSolution,
+		},
+		{
+			name: "target out of range",
+			goal: Goal{Variable: GoalVariableTerm, Target: GoalTargetInstallment, Value: decimal.NewFromInt(100), Min: decimal.NewFromInt(12), Max: decimal.NewFromInt(360)},
+			err:  ErrNoSolution,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SeekGoal(base, "emi", tt.goal)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			solution := result.Solution.InexactFloat64()
+			if tt.want != 0 && solution != tt.want {
+				t.Errorf("solution = %v, want %v", solution, tt.want)
+			}
+			target := tt.goal.targetValue()
+			if got := tt.goal.metric(result); got > target {
+				t.Errorf("metric %v exceeds target %v", got, target)
+			}
+			// 再多一个步长就会超出目标(指标递增),或者少一个步长就会超出目标(指标递减)
+			neighbour := solution - tt.goal.step()
+			if tt.increasing {
+				neighbour = solution + tt.goal.step()
+			}
+			if neighbour >= tt.goal.Min.InexactFloat64() && neighbour <= tt.goal.Max.InexactFloat64() && tt.goal.Variable != GoalVariableSpread {
+				if got := metricAt(tt.goal, neighbour); got <= target {
+					t.Errorf("solution %v is not optimal: %v gives %v within target %v", solution, neighbour, got, target)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package loan
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+	"time"
+)
+
+// CalendarOptions 还款日历的选项
+type CalendarOptions struct {
+	Name        string          // 日历名称
+	ID          string          // 贷款参数的标识,如订阅链接的 token,其摘要写入 UID 区分同一天放款的不同贷款
+	Alarms      []time.Duration // 提醒,还款日当天0点之前的时长
+	BusinessDay bool            // 还款日为周末时顺延到下周一
+	Generated   time.Time       // 生成时间,写入 DTSTAMP
+}
+
+// AdjustBusinessDay 周六周日顺延到下周一,没有节假日数据,法定节假日不做调整
+func AdjustBusinessDay(date time.Time) time.Time {
+	switch date.Weekday() {
+	case time.Saturday:
+		return date.AddDate(0, 0, 2)
+	case time.Sunday:
+		return date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// WriteICalendar 每次实际还款生成一个全天事件,重新生成时日历可以按 UID 更新事件
+// UID 由贷款参数的摘要,放款日,还款日期,期数和明细性质组成,同一期有多笔提前还款时再加序号
+func WriteICalendar(w io.Writer, reports []Report, options CalendarOptions) error {
+	var b strings.Builder
+	line := func(s string) { b.WriteString(foldLine(s)) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Home-Mortgage-Loans-In-China//Repayment Calendar//ZH")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if options.Name != "" {
+		line("X-WR-CALNAME:" + escapeText(options.Name))
+	}
+
+	initial := ""
+	for _, report := range reports {
+		if report.Purpose == "贷款发放" {
+			initial = report.DueDate.Format("20060102")
+		}
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(options.ID))
+	prefix := fmt.Sprintf("%08x-%s", hash.Sum32(), initial)
+	uids := make(map[string]int)
+	stamp := options.Generated.UTC().Format("20060102T150405Z")
+	for _, report := range reports {
+		switch {
+		case report.IsEmptyEarlyRepayment():
+			continue
+		case report.Purpose != "分期" && report.Purpose != "提前还款" && report.Purpose != "结清":
+			continue
+		}
+		date := report.DueDate
+		if options.BusinessDay {
+			date = AdjustBusinessDay(date)
+		}
+
+		description := []string{
+			fmt.Sprintf("%s 第%d期", report.Purpose, report.LoanTerm),
+			"还款金额: " + FormatMoney(report.MonthTotalAmount),
+			"本金: " + FormatMoney(report.Principal),
+			"利息: " + FormatMoney(report.Interest),
+		}
+		if report.Fee.IsPositive() {
+			description = append(description, "违约金: "+FormatMoney(report.Fee))
+		}
+		description = append(description,
+			"剩余本金: "+FormatMoney(report.RemainingPrincipal),
+			"执行利率: "+report.DueDateRate.String()+"%")
+		if !date.Equal(report.DueDate) {
+			description = append(description, "合同还款日: "+report.DueDate.Format("2006-01-02"))
+		}
+
+		line("BEGIN:VEVENT")
+		uid := fmt.Sprintf("%s-%s-%d-%s", prefix, report.DueDate.Format("20060102"), report.LoanTerm, purposeCode(report.Purpose))
+		uids[uid]++
+		if n := uids[uid]; n > 1 {
+			uid = fmt.Sprintf("%s-%d", uid, n)
+		}
+		line("UID:" + uid + "@home-mortgage-loans-in-china")
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + date.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeText(fmt.Sprintf("房贷%s %s", report.Purpose, FormatMoney(report.MonthTotalAmount))))
+		line("DESCRIPTION:" + escapeText(strings.Join(description, "\n")))
+		line("TRANSP:TRANSPARENT")
+		for _, alarm := range options.Alarms {
+			line("BEGIN:VALARM")
+			line("ACTION:DISPLAY")
+			line("DESCRIPTION:" + escapeText("房贷还款提醒 "+FormatMoney(report.MonthTotalAmount)))
+			line("TRIGGER:-" + formatDuration(alarm))
+			line("END:VALARM")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// UID 中的明细性质,同一期可能既有分期又有提前还款
+func purposeCode(purpose string) string {
+	switch purpose {
+	case "提前还款":
+		return "prepay"
+	case "结清":
+		return "payoff"
+	}
+	return "installment"
+}
+
+// 文本值转义反斜杠,逗号,分号和换行
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\n", `\n`).Replace(s)
+}
+
+// 每行不超过75字节,续行以空格开头,不拆开多字节字符,行尾为 CRLF
+func foldLine(s string) string {
+	var b strings.Builder
+	length := 0
+	for _, r := range s {
+		size := len(string(r))
+		if length+size > 75 {
+			b.WriteString("\r\n ")
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// 时长转换为 RFC 5545 的 dur-value,如 P1D,PT12H,PT30M
+func formatDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "PT0M"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("P%dD", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("PT%dH", d/time.Hour)
+	}
+	return fmt.Sprintf("PT%dM", d/time.Minute)
+}
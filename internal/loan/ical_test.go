@@ -0,0 +1,88 @@
+package loan
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// 日历中所有事件的 UID
+func calendarUIDs(t *testing.T, input Input, id string) []string {
+	t.Helper()
+	var b strings.Builder
+	if err := WriteICalendar(&b, BuildReport(input, "emi"), CalendarOptions{ID: id}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(b.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line is longer than 75 octets: %q", line)
+		}
+	}
+	// 展开折行后再取 UID
+	unfolded := strings.ReplaceAll(b.String(), "\r\n ", "")
+	uids := make([]string, 0)
+	for _, match := range regexp.MustCompile(`(?m)^UID:(.*)\r$`).FindAllStringSubmatch(unfolded, -1) {
+		uids = append(uids, match[1])
+	}
+	return uids
+}
+
+func TestICalendarUID(t *testing.T) {
+	prepayments := []EarlyRepayment{
+		{Amount: decimal.NewFromInt(1000), Date: ParseDate("2023-05-20")},
+		{Amount: decimal.NewFromInt(1000), Date: ParseDate("2023-08-01")},
+	}
+	tests := []struct {
+		name        string
+		a, b        Input
+		idA, idB    string
+		shared      bool // 两个日历的 UID 是否相同
+		wantEventsA int
+	}{
+		{
+			// 同一天放款的两笔贷款
+			name: "same start date",
+			a:    Input{Loan: fixedLoan(12000, 12)}, idA: "principal=12000",
+			b: Input{Loan: fixedLoan(24000, 12)}, idB: "principal=24000",
+			wantEventsA: 12,
+		},
+		{
+			// 相同的参数重新生成,日历按 UID 更新事件
+			name: "same parameters",
+			a:    Input{Loan: fixedLoan(12000, 12)}, idA: "principal=12000",
+			b: Input{Loan: fixedLoan(12000, 12)}, idB: "principal=12000",
+			shared:      true,
+			wantEventsA: 12,
+		},
+		{
+			// 提前还款记录没有期数,多笔提前还款不能只按期数区分
+			name: "several prepayments",
+			a:    Input{Loan: fixedLoan(12000, 12), EarlyRepayment: prepayments}, idA: "prepay",
+			b: Input{Loan: fixedLoan(12000, 12)}, idB: "principal=12000",
+			wantEventsA: 14,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := calendarUIDs(t, tt.a, tt.idA)
+			b := calendarUIDs(t, tt.b, tt.idB)
+			if len(a) < tt.wantEventsA {
+				t.Fatalf("%d events, want at least %d", len(a), tt.wantEventsA)
+			}
+			seen := make(map[string]bool)
+			for _, uid := range a {
+				if seen[uid] {
+					t.Errorf("duplicate UID %s", uid)
+				}
+				seen[uid] = true
+			}
+			for _, uid := range b {
+				if seen[uid] != tt.shared {
+					t.Errorf("UID %s shared = %v, want %v", uid, seen[uid], tt.shared)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package loan
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// InvestAnalysisRow 提前还款和投资两条路径每月的净值
+// 净值=投资账户价值-剩余本金
+type InvestAnalysisRow struct {
+	Month            time.Time       // 月份
+	PrepayInvestment decimal.Decimal // 提前还款路径: 每月少还的月供用于投资的累计价值
+	PrepayRemaining  decimal.Decimal // 提前还款路径: 剩余本金
+	PrepayNetValue   decimal.Decimal // 提前还款路径净值
+	InvestInvestment decimal.Decimal // 投资路径: 提前还款金额用于投资的累计价值
+	InvestRemaining  decimal.Decimal // 投资路径: 剩余本金
+	InvestNetValue   decimal.Decimal // 投资路径净值
+	NetValueDiff     decimal.Decimal // 投资路径-提前还款路径
+}
+
+// InvestAnalysis 提前还款与投资的对比
+type InvestAnalysis struct {
+	Candidate       EarlyRepayment      // 待决策的提前还款
+	AnnualReturn    decimal.Decimal     // 投资年化收益率(%)
+	TaxRate         decimal.Decimal     // 投资收益税率(%)
+	InterestSaved   decimal.Decimal     // 提前还款节省的利息
+	BreakEvenReturn decimal.Decimal     // 两条路径最终净值相等时的税前年化收益率(%)
+	BreakEvenError  string              // 搜索范围内没有盈亏平衡收益率时的说明
+	Rows            []InvestAnalysisRow // 每月净值
+}
+
+// 盈亏平衡收益率的搜索上限
+const maxBreakEvenReturn = 1.0
+
+// 按月汇总的还款计划
+type monthlyCashFlow struct {
+	payments  map[time.Time]float64 // 当月还款
+	remaining map[time.Time]float64 // 月末剩余本金
+	last      time.Time             // 最后还款月
+}
+
+func reportsByMonth(reports []Report) monthlyCashFlow {
+	flows := monthlyCashFlow{payments: make(map[time.Time]float64), remaining: make(map[time.Time]float64)}
+	for _, report := range reports {
+		if report.IsEmptyEarlyRepayment() {
+			continue
+		}
+		month := monthOf(report.DueDate)
+		switch report.Purpose {
+		case "分期", "提前还款", "结清":
+			flows.payments[month] += report.paid().InexactFloat64()
+		}
+		if report.Purpose != "利率调整" {
+			flows.remaining[month] = report.RemainingPrincipal.InexactFloat64()
+		}
+		if month.After(flows.last) {
+			flows.last = month
+		}
+	}
+	return flows
+}
+
+// 税后年化收益率折算的月收益率
+func monthlyReturn(annualReturn, taxRate float64) float64 {
+	return math.Pow(1+annualReturn*(1-taxRate), 1.0/12) - 1
+}
+
+// 模拟两条路径,返回每月净值
+// 提前还款路径: 提前还款后每月少还的月供按月投资
+// 投资路径: 提前还款金额在同一月投资,每月按原计划还款
+func simulatePrepayVsInvest(with, without monthlyCashFlow, candidate EarlyRepayment, rate float64) []InvestAnalysisRow {
+	start := monthOf(candidate.Date)
+	end := with.last
+	if without.last.After(end) {
+		end = without.last
+	}
+
+	rows := make([]InvestAnalysisRow, 0)
+	prepayInvestment, investInvestment := 0.0, 0.0
+	prepayRemaining, investRemaining := 0.0, 0.0
+	for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+		prepayInvestment *= 1 + rate
+		investInvestment *= 1 + rate
+		// 两条路径每月的现金支出都是原计划还款,起始月再加上这笔钱
+		outlay := without.payments[month]
+		if month.Equal(start) {
+			outlay += candidate.Amount.InexactFloat64()
+			investInvestment += candidate.Amount.InexactFloat64()
+		}
+		// 提前还款路径支出中多出的部分用于投资
+		prepayInvestment += outlay - with.payments[month]
+
+		if remaining, ok := with.remaining[month]; ok {
+			prepayRemaining = remaining
+		}
+		if remaining, ok := without.remaining[month]; ok {
+			investRemaining = remaining
+		}
+		rows = append(rows, InvestAnalysisRow{
+			Month:            month,
+			PrepayInvestment: decimal.NewFromFloat(prepayInvestment).Round(2),
+			PrepayRemaining:  decimal.NewFromFloat(prepayRemaining).Round(2),
+			PrepayNetValue:   decimal.NewFromFloat(prepayInvestment - prepayRemaining).Round(2),
+			InvestInvestment: decimal.NewFromFloat(investInvestment).Round(2),
+			InvestRemaining:  decimal.NewFromFloat(investRemaining).Round(2),
+			InvestNetValue:   decimal.NewFromFloat(investInvestment - investRemaining).Round(2),
+			NetValueDiff:     decimal.NewFromFloat((investInvestment - investRemaining) - (prepayInvestment - prepayRemaining)).Round(2),
+		})
+	}
+	return rows
+}
+
+// AnalyzePrepayVsInvest 对比一笔提前还款和将这笔钱用于投资
+// annualReturn 和 taxRate 均为百分比,分别生成有和没有这笔提前还款的还款计划
+func AnalyzePrepayVsInvest(inputdata Input, action string, candidate EarlyRepayment, annualReturn, taxRate decimal.Decimal) (InvestAnalysis, error) {
+	if !candidate.Amount.IsPositive() {
+		return InvestAnalysis{}, errors.New("candidate amount should be positive")
+	}
+	if !candidate.Date.After(inputdata.Loan.InitialDate) {
+		return InvestAnalysis{}, errors.New("candidate date should be after the loan start date")
+	}
+
+	withInput := inputdata.clone()
+	withInput.EarlyRepayment = append(withInput.EarlyRepayment, candidate)
+	withReports := BuildReport(withInput, action)
+	withoutReports := BuildReport(inputdata.clone(), action)
+
+	// 提前还款的本金和利息在生成还款计划时回写
+	candidate = withInput.EarlyRepayment[len(withInput.EarlyRepayment)-1]
+	with, without := reportsByMonth(withReports), reportsByMonth(withoutReports)
+	if monthOf(candidate.Date).After(without.last) {
+		return InvestAnalysis{}, errors.New("candidate date should be before the loan is paid off")
+	}
+	// 每期只处理一笔提前还款,落在还款日或与已有提前还款同一期时不会生效
+	if candidate.DueDateRate.IsZero() {
+		return InvestAnalysis{}, errors.New("candidate should not be on a due date or in the same period as another prepayment")
+	}
+	tax := taxRate.InexactFloat64() / 100
+
+	analysis := InvestAnalysis{
+		Candidate:     candidate,
+		AnnualReturn:  annualReturn,
+		TaxRate:       taxRate,
+		InterestSaved: Summarize(withoutReports, nil).TotalInterest.Sub(Summarize(withReports, nil).TotalInterest),
+		Rows:          simulatePrepayVsInvest(with, without, candidate, monthlyReturn(annualReturn.InexactFloat64()/100, tax)),
+	}
+
+	// 二分法求最终净值相等的收益率,收益率越高投资路径越有利
+	finalDiff := func(annualReturn float64) float64 {
+		rows := simulatePrepayVsInvest(with, without, candidate, monthlyReturn(annualReturn, tax))
+		return rows[len(rows)-1].NetValueDiff.InexactFloat64()
+	}
+	low, high := 0.0, maxBreakEvenReturn
+	switch {
+	case finalDiff(low) >= 0:
+		high = low
+	case finalDiff(high) < 0:
+		analysis.BreakEvenError = "年化收益率达到100%投资仍不如提前还款"
+		return analysis, nil
+	}
+	for i := 0; i < 60 && high-low > 1e-7; i++ {
+		mid := (low + high) / 2
+		if finalDiff(mid) < 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	analysis.BreakEvenReturn = decimal.NewFromFloat(high * 100).Round(4)
+	return analysis, nil
+}
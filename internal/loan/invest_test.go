@@ -0,0 +1,66 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAnalyzePrepayVsInvest(t *testing.T) {
+	existing := []EarlyRepayment{{Amount: decimal.NewFromInt(2000), Date: ParseDate("2023-05-20")}}
+	tests := []struct {
+		name      string
+		early     []EarlyRepayment
+		date      string
+		tax       int64
+		err       bool
+		breakEven bool // 是否求得盈亏平衡收益率
+	}{
+		{name: "no other prepayment", date: "2023-06-01", breakEven: true},
+		{name: "different period", early: existing, date: "2023-07-01", breakEven: true},
+		// 与已有提前还款同一期,只有一笔会生效
+		{name: "same period", early: existing, date: "2023-06-01", err: true},
+		{name: "on a due date", date: "2023-06-18", err: true},
+		{name: "after payoff", date: "2024-03-01", err: true},
+		// 收益全部缴税,收益率再高投资路径也不占优
+		{name: "all gains taxed", date: "2023-06-01", tax: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := Input{Loan: fixedLoan(12000, 12), EarlyRepayment: tt.early}
+			candidate := EarlyRepayment{Amount: decimal.NewFromInt(3000), Date: ParseDate(tt.date)}
+			analysis, err := AnalyzePrepayVsInvest(input, "emi", candidate, decimal.NewFromInt(3), decimal.NewFromInt(tt.tax))
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !analysis.InterestSaved.IsPositive() {
+				t.Errorf("interest saved = %s, want positive", analysis.InterestSaved)
+			}
+			if tt.breakEven != (analysis.BreakEvenError == "") {
+				t.Fatalf("break even error = %q", analysis.BreakEvenError)
+			}
+			if !tt.breakEven {
+				assertDecimal(t, "break even return", analysis.BreakEvenReturn, decimal.Zero)
+				return
+			}
+			// 盈亏平衡收益率接近贷款利率4.9%
+			if analysis.BreakEvenReturn.LessThan(decimal.NewFromInt(4)) || analysis.BreakEvenReturn.GreaterThan(decimal.NewFromInt(6)) {
+				t.Errorf("break even return = %s, want about 4.9", analysis.BreakEvenReturn)
+			}
+			// 按盈亏平衡收益率投资,两条路径最终净值相等
+			at, err := AnalyzePrepayVsInvest(input, "emi", candidate, analysis.BreakEvenReturn, decimal.Zero)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := at.Rows[len(at.Rows)-1].NetValueDiff.Abs(); diff.GreaterThan(decimal.NewFromInt(1)) {
+				t.Errorf("final net value diff at break even = %s", diff)
+			}
+		})
+	}
+}
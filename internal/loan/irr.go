@@ -0,0 +1,113 @@
+package loan
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CashFlow 借款人的现金流,收到贷款为正,还款为负
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+var ErrIRRNotConverged = errors.New("xirr: not converged")
+
+// 现金流在收益率 rate 下的净现值,按实际天数/365折现
+func xnpv(rate float64, flows []CashFlow) float64 {
+	npv := 0.0
+	for _, flow := range flows {
+		years := flow.Date.Sub(flows[0].Date).Hours() / 24 / 365
+		npv += flow.Amount / math.Pow(1+rate, years)
+	}
+	return npv
+}
+
+// 净现值对收益率的导数
+func xnpvDerivative(rate float64, flows []CashFlow) float64 {
+	derivative := 0.0
+	for _, flow := range flows {
+		years := flow.Date.Sub(flows[0].Date).Hours() / 24 / 365
+		derivative -= years * flow.Amount / math.Pow(1+rate, years+1)
+	}
+	return derivative
+}
+
+// XIRR 不定期现金流的内部收益率(年化),与excel的XIRR一致
+// 先用牛顿法求解,不收敛时改用二分法
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, errors.New("xirr: at least two cash flows are required")
+	}
+
+	rate := 0.05
+	for i := 0; i < 100; i++ {
+		npv := xnpv(rate, flows)
+		derivative := xnpvDerivative(rate, flows)
+		if derivative == 0 {
+			break
+		}
+		next := rate - npv/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-10 {
+			return next, nil
+		}
+		rate = next
+	}
+
+	low, high := -0.99, 10.0
+	npvLow := xnpv(low, flows)
+	if npvLow*xnpv(high, flows) > 0 {
+		return 0, ErrIRRNotConverged
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		npvMid := xnpv(mid, flows)
+		if math.Abs(npvMid) < 1e-7 || high-low < 1e-12 {
+			return mid, nil
+		}
+		if npvLow*npvMid < 0 {
+			high = mid
+		} else {
+			low, npvLow = mid, npvMid
+		}
+	}
+	return 0, ErrIRRNotConverged
+}
+
+// 根据还款计划生成借款人的现金流
+func reportCashFlows(reports []Report) []CashFlow {
+	flows := make([]CashFlow, 0, len(reports))
+	for _, report := range reports {
+		switch report.Purpose {
+		case "贷款发放":
+			flows = append(flows, CashFlow{Date: report.DueDate, Amount: report.Principal.InexactFloat64()})
+		case "分期", "提前还款", "结清":
+			if paid := report.paid(); !paid.IsZero() {
+				flows = append(flows, CashFlow{Date: report.DueDate, Amount: -paid.InexactFloat64()})
+			}
+		}
+	}
+	return flows
+}
+
+// EffectiveAnnualRate 实际现金流的年化成本(XIRR),百分比
+func EffectiveAnnualRate(reports []Report) (decimal.Decimal, error) {
+	rate, err := XIRR(reportCashFlows(reports))
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromFloat(rate * 100).Round(4), nil
+}
+
+// NominalEAR 名义年利率按月复利折算的实际年利率,百分比
+// EAR = (1 + APR/12)**12 - 1
+func NominalEAR(APR decimal.Decimal) decimal.Decimal {
+	monthly := APR.InexactFloat64() / 1200
+	return decimal.NewFromFloat((math.Pow(1+monthly, 12) - 1) * 100).Round(4)
+}
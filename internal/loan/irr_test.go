@@ -0,0 +1,102 @@
+package loan
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestXIRR(t *testing.T) {
+	date := func(s string) time.Time { return ParseDate(s) }
+	monthly := func(start string, amount float64, n int) []CashFlow {
+		flows := make([]CashFlow, n)
+		for i := range flows {
+			flows[i] = CashFlow{Date: date(start).AddDate(0, i, 0), Amount: amount}
+		}
+		return flows
+	}
+
+	tests := []struct {
+		name  string
+		flows []CashFlow
+		want  float64
+		err   error
+	}{
+		{
+			// excel XIRR 函数说明中的示例
+			name: "excel example",
+			flows: []CashFlow{
+				{date("2008-01-01"), -10000},
+				{date("2008-03-01"), 2750},
+				{date("2008-10-30"), 4250},
+				{date("2009-02-15"), 3250},
+				{date("2009-04-01"), 2750},
+			},
+			want: 0.373362535,
+		},
+		{
+			name:  "one year at 10%",
+			flows: []CashFlow{{date("2021-01-01"), 1000}, {date("2022-01-01"), -1100}},
+			want:  0.1,
+		},
+		{
+			name:  "zero interest loan",
+			flows: append([]CashFlow{{date("2023-01-01"), 1200}}, monthly("2023-02-01", -100, 12)...),
+			want:  0,
+		},
+		{
+			// 只有还款没有收到贷款,净现值没有零点
+			name:  "no sign change",
+			flows: monthly("2023-02-01", -100, 12),
+			err:   ErrIRRNotConverged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := XIRR(tt.flows)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("XIRR = %.9f, want %.9f", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := XIRR([]CashFlow{{date("2023-01-01"), 100}}); err == nil {
+		t.Error("XIRR with one cash flow should fail")
+	}
+}
+
+func TestSummarizeEffectiveRateError(t *testing.T) {
+	// 放款金额为0,只有还款,无法求解
+	reports := []Report{
+		{Purpose: "贷款发放", DueDate: ParseDate("2023-01-10")},
+		{Purpose: "分期", MonthTotalAmount: decimal.NewFromInt(100), DueDate: ParseDate("2023-02-18")},
+		{Purpose: "分期", MonthTotalAmount: decimal.NewFromInt(100), DueDate: ParseDate("2023-03-18")},
+	}
+	summary := Summarize(reports, nil)
+	if summary.EffectiveRateError == "" {
+		t.Error("EffectiveRateError should be set when XIRR does not converge")
+	}
+	assertDecimal(t, "effective rate", summary.EffectiveRate, decimal.Zero)
+
+	// 正常的还款计划没有错误,固定利率4.9%的实际年化成本略高于名义利率
+	summary = Summarize(BuildReport(Input{Loan: fixedLoan(12000, 12)}, "emi"), nil)
+	if summary.EffectiveRateError != "" {
+		t.Fatalf("EffectiveRateError = %s", summary.EffectiveRateError)
+	}
+	if summary.EffectiveRate.LessThan(decimal.NewFromFloat(4.9)) || summary.EffectiveRate.GreaterThan(decimal.NewFromFloat(5.2)) {
+		t.Errorf("effective rate = %s, want between 4.9 and 5.2", summary.EffectiveRate)
+	}
+}
@@ -1,6 +1,7 @@
 package loan
 
 import (
+	"sort"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -9,14 +10,28 @@ import (
 // Loan represents the loan details.
 
 type Loan struct {
-	InitialPrincipal decimal.Decimal // 初始本金
-	PlusSpread       decimal.Decimal // 加点
-	InitialTerm      int             // 贷款期限（月）
-	InitialDate      time.Time       // 放款年月日
-	LPR              []LPR           // 日期与利率的条目列表
-	PaymentDueDay    int             // 还款日 (1-31)
+	InitialPrincipal    decimal.Decimal         // 初始本金
+	PlusSpread          decimal.Decimal         // 加点
+	InitialTerm         int                     // 贷款期限（月）
+	InitialDate         time.Time               // 放款年月日
+	LPR                 []LPR                   // 日期与利率的条目列表,按日期倒序
+	PaymentDueDay       int                     // 还款日 (1-31)
+	BalloonAmount       decimal.Decimal         // 尾款,到期一次性归还的剩余本金
+	FixedRate           decimal.Decimal         // 固定年利率,为0时按lpr+加点浮动
+	FixedYears          int                     // 固定利率年数,为0时全程固定;大于0时之后转为lpr+加点
+	SpreadChanges       []SpreadChange          // 加点调整记录
+	RateFloor           decimal.Decimal         // 浮动利率下限,为0时不限制
+	RateCap             decimal.Decimal         // 浮动利率上限,为0时不限制
+	PenaltyRateMarkup   decimal.Decimal         // 罚息利率上浮比例,为0时按50%
+	PrepaymentPenalties []PrepaymentPenaltyRule // 提前还款违约金规则
 }
 
+// 利率上下限生效标记
+const (
+	RateLimitFloor = "下限"
+	RateLimitCap   = "上限"
+)
+
 // LPR represents the date and interest LPR entry.
 type LPR struct {
 	Date time.Time       // 日期
@@ -24,7 +39,7 @@ type LPR struct {
 }
 
 func (loan *Loan) previousDueDate(dueDate time.Time) time.Time {
-	previousDueDate := dueDate.AddDate(0, -1, 0)
+	previousDueDate := Monthly.previous(dueDate)
 	return previousDueDate
 }
 
@@ -34,8 +49,13 @@ func (loan *Loan) daysDiff(startDate, endDate time.Time) decimal.Decimal {
 	return decimal.NewFromInt(int64(daysDiff))
 }
 
+// 还款日所在年份的lpr变更日,即放款日的周年日
+func (loan *Loan) lprUpdateDate(dueDate time.Time) time.Time {
+	return time.Date(dueDate.Year(), loan.InitialDate.Month(), loan.InitialDate.Day(), 0, 0, 0, 0, loan.InitialDate.Location())
+}
+
 func (loan *Loan) LPRChangeDateOffset(dueDate time.Time) (decimal.Decimal, decimal.Decimal) {
-	LPRUpdateDate := time.Date(dueDate.Year(), loan.InitialDate.Month(), loan.InitialDate.Day(), 0, 0, 0, 0, loan.InitialDate.Location())
+	LPRUpdateDate := loan.lprUpdateDate(dueDate)
 	previousDueDate := loan.previousDueDate(dueDate)
 	// lpr变更前的天数
 	daysBefore := loan.daysDiff(previousDueDate, LPRUpdateDate)
@@ -44,6 +64,66 @@ func (loan *Loan) LPRChangeDateOffset(dueDate time.Time) (decimal.Decimal, decim
 	return daysBefore, daysAfter
 }
 
+// 获取指定日期执行的年利率
+// 1.固定利率: 全程按固定年利率
+// 2.混合利率: 放款后前N年按固定年利率,之后按lpr+加点
+// 3.浮动利率: lpr+加点,加点按生效日取调整后的值
+// 浮动利率低于下限按下限执行,高于上限按上限执行
+func (loan *Loan) aprAt(date time.Time) decimal.Decimal {
+	if loan.isFixedRateAt(date) {
+		return loan.FixedRate
+	}
+	APR := loan.getClosestLPRForYear(date).Add(loan.spreadAt(date))
+	switch loan.rateLimit(APR) {
+	case RateLimitFloor:
+		return loan.RateFloor
+	case RateLimitCap:
+		return loan.RateCap
+	}
+	return APR
+}
+
+// EffectiveRate 指定日期执行的年利率
+func (loan *Loan) EffectiveRate(date time.Time) decimal.Decimal {
+	return loan.aprAt(date)
+}
+
+// LPRAt 指定日期适用的lpr
+func (loan *Loan) LPRAt(date time.Time) decimal.Decimal {
+	return loan.getClosestLPRForYear(date)
+}
+
+// RateLimitAt 指定日期浮动利率是否触及上下限,未触及或固定利率期返回空
+func (loan *Loan) RateLimitAt(date time.Time) string {
+	// 没有上下限时不用查lpr,模拟大量路径时可以省去大部分查找
+	if loan.isFixedRateAt(date) || (loan.RateFloor.IsZero() && loan.RateCap.IsZero()) {
+		return ""
+	}
+	return loan.rateLimit(loan.getClosestLPRForYear(date).Add(loan.spreadAt(date)))
+}
+
+func (loan *Loan) rateLimit(APR decimal.Decimal) string {
+	if !loan.RateFloor.IsZero() && APR.LessThan(loan.RateFloor) {
+		return RateLimitFloor
+	}
+	if !loan.RateCap.IsZero() && APR.GreaterThan(loan.RateCap) {
+		return RateLimitCap
+	}
+	return ""
+}
+
+// 指定日期是否处于固定利率期
+func (loan *Loan) isFixedRateAt(date time.Time) bool {
+	if loan.FixedRate.IsZero() {
+		return false
+	}
+	if loan.FixedYears == 0 {
+		return true
+	}
+	// 固定期结束日与lpr变更日一致,均为放款日的周年日
+	return date.Before(loan.InitialDate.AddDate(loan.FixedYears, 0, 0))
+}
+
 // 获取离指定日期最近的LPR
 
 func (loan *Loan) getClosestLPRForYear(dueDate time.Time) (selectedRate decimal.Decimal) {
@@ -57,13 +137,11 @@ func (loan *Loan) getClosestLPRForYear(dueDate time.Time) (selectedRate decimal.
 	} else {
 		lprUpdateDate = time.Date(dueDate.Year(), loan.InitialDate.Month(), loan.InitialDate.Day(), 0, 0, 0, 0, loan.InitialDate.Location())
 	}
-	// 找到小于或等于给定年份的最近的RateEntry日期
-	for _, entry := range loan.LPR {
-		// 如果找到比 lprUpdateDate 之前的日期，且比当前选定的日期更接近，则更新 selectedRate 和 lprUpdateDate
-		if entry.Date.Before(lprUpdateDate) && (selectedRate == decimal.Decimal{} || lprUpdateDate.Sub(entry.Date) < lprUpdateDate.Sub(lprUpdateDate)) {
-			selectedRate = entry.LPR
-			lprUpdateDate = entry.Date
-		}
+	// LPR 按日期倒序,二分查找第一个早于变更日的记录
+	// 模拟大量路径时远期报价有数百条,每期都要查找,不再逐条比较
+	i := sort.Search(len(loan.LPR), func(i int) bool { return loan.LPR[i].Date.Before(lprUpdateDate) })
+	if i < len(loan.LPR) {
+		selectedRate = loan.LPR[i].LPR
 	}
 	return selectedRate
 }
@@ -0,0 +1,146 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// 固定利率4.9%的贷款,2023-01-10放款,每月18日还款,不依赖lpr数据
+func fixedLoan(principal int64, term int) Loan {
+	return Loan{
+		InitialPrincipal: decimal.NewFromInt(principal),
+		InitialTerm:      term,
+		InitialDate:      ParseDate("2023-01-10"),
+		PaymentDueDay:    18,
+		FixedRate:        decimal.NewFromFloat(4.9),
+		LPR:              Lprs,
+	}
+}
+
+func assertDecimal(t *testing.T, name string, got, want decimal.Decimal) {
+	t.Helper()
+	if !got.Equal(want) {
+		t.Errorf("%s = %s, want %s", name, got, want)
+	}
+}
+
+func TestAprAt(t *testing.T) {
+	// 2021-05-25 放款,每年5月25日按之前最近一期lpr重定价: 2021年4.65,2022年4.45,2023年4.30
+	newLoan := func(fixedRate float64, fixedYears int) Loan {
+		return Loan{
+			InitialDate: ParseDate("2021-05-25"),
+			LPR:         Lprs,
+			PlusSpread:  decimal.NewFromFloat(0.1),
+			FixedRate:   decimal.NewFromFloat(fixedRate),
+			FixedYears:  fixedYears,
+		}
+	}
+	tests := []struct {
+		name string
+		loan Loan
+		date string
+		want float64
+	}{
+		{name: "floating first year", loan: newLoan(0, 0), date: "2022-05-24", want: 4.75},
+		{name: "floating repriced", loan: newLoan(0, 0), date: "2022-05-25", want: 4.55},
+		{name: "floating third year", loan: newLoan(0, 0), date: "2023-05-25", want: 4.40},
+		{name: "fixed", loan: newLoan(4.9, 0), date: "2023-05-25", want: 4.9},
+		{name: "hybrid within fixed years", loan: newLoan(4.9, 1), date: "2022-05-24", want: 4.9},
+		{name: "hybrid after fixed years", loan: newLoan(4.9, 1), date: "2022-05-25", want: 4.55},
+		{name: "hybrid two years", loan: newLoan(4.9, 2), date: "2023-05-24", want: 4.9},
+		{name: "hybrid two years after", loan: newLoan(4.9, 2), date: "2023-05-25", want: 4.40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertDecimal(t, "rate", tt.loan.aprAt(ParseDate(tt.date)), decimal.NewFromFloat(tt.want))
+		})
+	}
+}
+
+func TestHybridSchedule(t *testing.T) {
+	// 固定期1年,之后转为lpr+加点
+	loan := Loan{
+		InitialPrincipal: decimal.NewFromInt(240000),
+		InitialTerm:      24,
+		InitialDate:      ParseDate("2021-05-25"),
+		PaymentDueDay:    18,
+		LPR:              Lprs,
+		PlusSpread:       decimal.NewFromFloat(0.1),
+		FixedRate:        decimal.NewFromFloat(4.9),
+		FixedYears:       1,
+	}
+	for _, action := range []string{"emi", "epp"} {
+		t.Run(action, func(t *testing.T) {
+			for _, report := range BuildReport(Input{Loan: loan}, action) {
+				if report.Purpose != "分期" {
+					continue
+				}
+				// 执行利率为本期最后一个计息日的利率,最后一期在重定价日到期,仍按重定价前的利率
+				want := decimal.NewFromFloat(4.9)
+				if !report.DueDate.Before(ParseDate("2022-05-25")) {
+					want = loan.aprAt(report.DueDate.AddDate(0, 0, -1))
+				}
+				if !report.DueDateRate.Equal(want) {
+					t.Errorf("term %d on %s: rate = %s, want %s", report.LoanTerm, report.DueDate.Format("2006-01-02"), report.DueDateRate, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	// 2021-05-25 放款,加点0.1: 第一年4.75,第二年4.55,第三年4.40
+	newLoan := func(floor, ceiling float64, fixedYears int) Loan {
+		loan := Loan{
+			InitialPrincipal: decimal.NewFromInt(240000),
+			InitialTerm:      24,
+			InitialDate:      ParseDate("2021-05-25"),
+			PaymentDueDay:    18,
+			LPR:              Lprs,
+			PlusSpread:       decimal.NewFromFloat(0.1),
+			RateFloor:        decimal.NewFromFloat(floor),
+			RateCap:          decimal.NewFromFloat(ceiling),
+		}
+		if fixedYears > 0 {
+			loan.FixedRate = decimal.NewFromFloat(3)
+			loan.FixedYears = fixedYears
+		}
+		return loan
+	}
+	tests := []struct {
+		name  string
+		loan  Loan
+		date  string
+		want  float64
+		limit string
+	}{
+		{name: "no limit", loan: newLoan(0, 0, 0), date: "2022-06-18", want: 4.55},
+		{name: "above floor", loan: newLoan(4.5, 0, 0), date: "2022-06-18", want: 4.55},
+		{name: "floor", loan: newLoan(4.6, 0, 0), date: "2022-06-18", want: 4.6, limit: RateLimitFloor},
+		{name: "below cap", loan: newLoan(0, 4.8, 0), date: "2021-06-18", want: 4.75},
+		{name: "cap", loan: newLoan(0, 4.7, 0), date: "2021-06-18", want: 4.7, limit: RateLimitCap},
+		{name: "floor and cap", loan: newLoan(4.6, 4.7, 0), date: "2022-06-18", want: 4.6, limit: RateLimitFloor},
+		// 固定利率期不受上下限限制
+		{name: "fixed period", loan: newLoan(4.6, 4.7, 1), date: "2021-06-18", want: 3},
+		{name: "after fixed period", loan: newLoan(4.6, 4.7, 1), date: "2022-06-18", want: 4.6, limit: RateLimitFloor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date := ParseDate(tt.date)
+			assertDecimal(t, "rate", tt.loan.aprAt(date), decimal.NewFromFloat(tt.want))
+			if got := tt.loan.RateLimitAt(date); got != tt.limit {
+				t.Errorf("limit = %q, want %q", got, tt.limit)
+			}
+			// 还款计划中的执行利率和标记一致
+			for _, report := range BuildReport(Input{Loan: tt.loan}, "emi") {
+				if report.Purpose == "分期" && report.DueDate.Equal(date) {
+					assertDecimal(t, "schedule rate", report.DueDateRate, decimal.NewFromFloat(tt.want))
+					if report.RateLimit != tt.limit {
+						t.Errorf("schedule limit = %q, want %q", report.RateLimit, tt.limit)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package loan
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LPR 远期路径类型
+const (
+	LPRPathFlat   = "flat"   // 持平
+	LPRPathStep   = "step"   // 每年固定变动
+	LPRPathCustom = "custom" // 自定义每年的LPR
+)
+
+// LPRPath LPR 远期路径,从最新一期LPR之后每月20日报价
+type LPRPath struct {
+	Name   string            // 路径名称
+	Kind   string            // 路径类型
+	Rate   decimal.Decimal   // 持平: 远期LPR,为0时沿用最新一期LPR
+	StepBP decimal.Decimal   // 每年固定变动: 每年变动的基点,可为负数
+	Rates  []decimal.Decimal // 自定义: 第1年,第2年...的LPR,最后一年之后沿用最后一个值
+}
+
+// 远期第 year 年(从0开始)的LPR,不低于0
+func (path LPRPath) rateOfYear(latest decimal.Decimal, year int) decimal.Decimal {
+	rate := latest
+	switch path.Kind {
+	case LPRPathFlat:
+		if path.Rate.IsPositive() {
+			rate = path.Rate
+		}
+	case LPRPathStep:
+		rate = latest.Add(path.StepBP.Mul(decimal.NewFromInt(int64(year + 1))).Div(decimal.NewFromInt(100)))
+	case LPRPathCustom:
+		if len(path.Rates) > 0 {
+			rate = path.Rates[len(path.Rates)-1]
+			if year < len(path.Rates) {
+				rate = path.Rates[year]
+			}
+		}
+	}
+	if rate.IsNegative() {
+		return decimal.Zero
+	}
+	return rate
+}
+
+// Forward 在已公布的LPR之前加上远期报价,直到 until
+func (path LPRPath) Forward(lprs []LPR, until time.Time) []LPR {
+	if len(lprs) == 0 {
+		return lprs
+	}
+	return forwardLPR(lprs, until, func(month int) decimal.Decimal {
+		return path.rateOfYear(lprs[0].LPR, (month-1)/12)
+	})
+}
+
+// 从最新一期LPR之后每月20日报价,第 month 个月(从1开始)的LPR由 rateOfMonth 给出
+// 已公布的LPR按日期倒序,getClosestLPRForYear 取第一个早于变更日的记录,所以远期报价也按倒序放在前面
+func forwardLPR(lprs []LPR, until time.Time, rateOfMonth func(month int) decimal.Decimal) []LPR {
+	latest := lprs[0]
+	forward := make([]LPR, 0)
+	for month := 1; ; month++ {
+		date := time.Date(latest.Date.Year(), latest.Date.Month()+time.Month(month), 20, 0, 0, 0, 0, latest.Date.Location())
+		if date.After(until) {
+			break
+		}
+		forward = append(forward, LPR{Date: date, LPR: rateOfMonth(month)})
+	}
+
+	result := make([]LPR, 0, len(forward)+len(lprs))
+	for i := len(forward) - 1; i >= 0; i-- {
+		result = append(result, forward[i])
+	}
+	return append(result, lprs...)
+}
+
+// StressResult 单条LPR路径下的还款计划汇总
+type StressResult struct {
+	Name               string
+	MaxInstallment     decimal.Decimal // 最高每期还款
+	AverageInstallment decimal.Decimal // 平均每期还款
+	TotalInterest      decimal.Decimal // 利息合计
+	PayoffDate         time.Time       // 结清日期
+	WeightedAverageAPR decimal.Decimal // 加权平均年利率
+}
+
+// StressBand 各路径中最低,基准和最高的值
+type StressBand struct {
+	Min     decimal.Decimal
+	MinPath string
+	Base    decimal.Decimal
+	Max     decimal.Decimal
+	MaxPath string
+}
+
+// StressTest LPR 压力测试结果,第一条路径为沿用最新LPR的基准
+type StressTest struct {
+	Results        []StressResult
+	MaxInstallment StressBand // 最高每期还款
+	TotalInterest  StressBand // 利息合计
+}
+
+// 按各路径的值更新最低和最高
+func newStressBand(results []StressResult, value func(StressResult) decimal.Decimal) StressBand {
+	band := StressBand{Base: value(results[0]), Min: value(results[0]), MinPath: results[0].Name, Max: value(results[0]), MaxPath: results[0].Name}
+	for _, result := range results[1:] {
+		if v := value(result); v.LessThan(band.Min) {
+			band.Min, band.MinPath = v, result.Name
+		}
+		if v := value(result); v.GreaterThan(band.Max) {
+			band.Max, band.MaxPath = v, result.Name
+		}
+	}
+	return band
+}
+
+// StressTestLPR 按各条LPR远期路径并发计算还款计划,汇总最高每期还款和利息合计的范围
+func StressTestLPR(inputdata Input, action string, paths []LPRPath) (StressTest, error) {
+	if len(paths) == 0 {
+		return StressTest{}, errors.New("at least one lpr path is required")
+	}
+	// 远期报价覆盖到贷款到期后一年,最后一期可能晚于到期月
+	until := inputdata.Loan.InitialDate.AddDate(0, inputdata.Loan.InitialTerm+12, 0)
+
+	scenarios := []Scenario{{Name: "基准", Action: action, Input: inputdata}}
+	for _, path := range paths {
+		scenario := Scenario{Name: path.Name, Action: action, Input: inputdata}
+		scenario.Input.Loan.LPR = path.Forward(inputdata.Loan.LPR, until)
+		scenarios = append(scenarios, scenario)
+	}
+
+	results := make([]StressResult, 0, len(scenarios))
+	for _, result := range runScenarios(scenarios) {
+		results = append(results, StressResult{
+			Name:               result.Name,
+			MaxInstallment:     result.Summary.MaxInstallment,
+			AverageInstallment: result.Summary.AverageInstallment,
+			TotalInterest:      result.Summary.TotalInterest,
+			PayoffDate:         result.Summary.PayoffDate,
+			WeightedAverageAPR: result.Summary.WeightedAverageAPR,
+		})
+	}
+
+	return StressTest{
+		Results:        results,
+		MaxInstallment: newStressBand(results, func(r StressResult) decimal.Decimal { return r.MaxInstallment }),
+		TotalInterest:  newStressBand(results, func(r StressResult) decimal.Decimal { return r.TotalInterest }),
+	}, nil
+}
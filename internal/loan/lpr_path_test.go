@@ -0,0 +1,137 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLPRPathForward(t *testing.T) {
+	// 最新一期为 2023-08-21 的 4.20,远期从 2023-09-20 起每月报价
+	latest := Lprs[0]
+	until := ParseDate("2026-08-20")
+	tests := []struct {
+		name  string
+		path  LPRPath
+		rates map[string]float64 // 远期报价日期和LPR
+	}{
+		{
+			name:  "flat latest",
+			path:  LPRPath{Kind: LPRPathFlat},
+			rates: map[string]float64{"2023-09-20": 4.2, "2026-08-20": 4.2},
+		},
+		{
+			name:  "flat rate",
+			path:  LPRPath{Kind: LPRPathFlat, Rate: decimal.NewFromFloat(3.5)},
+			rates: map[string]float64{"2023-09-20": 3.5, "2026-08-20": 3.5},
+		},
+		{
+			// 每12个月变动一次
+			name:  "step",
+			path:  LPRPath{Kind: LPRPathStep, StepBP: decimal.NewFromInt(-25)},
+			rates: map[string]float64{"2023-09-20": 3.95, "2024-08-20": 3.95, "2024-09-20": 3.7, "2026-08-20": 3.45},
+		},
+		{
+			// 下调到0以下时取0
+			name:  "step not below zero",
+			path:  LPRPath{Kind: LPRPathStep, StepBP: decimal.NewFromInt(-300)},
+			rates: map[string]float64{"2023-09-20": 1.2, "2024-09-20": 0},
+		},
+		{
+			// 最后一年之后沿用最后一个值
+			name:  "custom",
+			path:  LPRPath{Kind: LPRPathCustom, Rates: []decimal.Decimal{decimal.NewFromFloat(4), decimal.NewFromFloat(3.8)}},
+			rates: map[string]float64{"2023-09-20": 4, "2024-09-20": 3.8, "2026-08-20": 3.8},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lprs := tt.path.Forward(Lprs, until)
+			// 远期报价按日期倒序放在已公布的LPR之前
+			if len(lprs) != len(Lprs)+36 {
+				t.Fatalf("%d entries, want %d", len(lprs), len(Lprs)+36)
+			}
+			for i := 1; i < len(lprs); i++ {
+				if !lprs[i].Date.Before(lprs[i-1].Date) {
+					t.Fatalf("entry %d on %s is not before %s", i, lprs[i].Date.Format(time.DateOnly), lprs[i-1].Date.Format(time.DateOnly))
+				}
+			}
+			if !lprs[36].Date.Equal(latest.Date) {
+				t.Errorf("published lpr starts at %s, want %s", lprs[36].Date.Format(time.DateOnly), latest.Date.Format(time.DateOnly))
+			}
+			for date, rate := range tt.rates {
+				found := false
+				for _, entry := range lprs {
+					if entry.Date.Equal(ParseDate(date)) {
+						assertDecimal(t, date, entry.LPR, decimal.NewFromFloat(rate))
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("no quote on %s", date)
+				}
+			}
+		})
+	}
+}
+
+func TestStressTestLPR(t *testing.T) {
+	loan := Loan{
+		InitialPrincipal: decimal.NewFromInt(1000000),
+		InitialTerm:      120,
+		InitialDate:      ParseDate("2023-05-25"),
+		PaymentDueDay:    18,
+		LPR:              Lprs,
+		PlusSpread:       decimal.NewFromFloat(-0.3),
+	}
+	paths := []LPRPath{
+		{Name: "下行", Kind: LPRPathStep, StepBP: decimal.NewFromInt(-20)},
+		{Name: "上行", Kind: LPRPathStep, StepBP: decimal.NewFromInt(30)},
+		{Name: "持平", Kind: LPRPathFlat},
+	}
+	stress, err := StressTestLPR(Input{Loan: loan}, "emi", paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"基准", "下行", "上行", "持平"}
+	if len(stress.Results) != len(names) {
+		t.Fatalf("%d results, want %d", len(stress.Results), len(names))
+	}
+	for i, name := range names {
+		if stress.Results[i].Name != name {
+			t.Errorf("result %d is %s, want %s", i, stress.Results[i].Name, name)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		band    StressBand
+		value   func(StressResult) decimal.Decimal
+		minPath string
+		maxPath string
+	}{
+		{name: "total interest", band: stress.TotalInterest, value: func(r StressResult) decimal.Decimal { return r.TotalInterest }, minPath: "下行", maxPath: "上行"},
+		// LPR下行时最高月供仍是重定价前的月供,与基准相同时取先出现的基准
+		{name: "max installment", band: stress.MaxInstallment, value: func(r StressResult) decimal.Decimal { return r.MaxInstallment }, minPath: "基准", maxPath: "上行"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.band.MinPath != tt.minPath || tt.band.MaxPath != tt.maxPath {
+				t.Errorf("min %s max %s, want %s and %s", tt.band.MinPath, tt.band.MaxPath, tt.minPath, tt.maxPath)
+			}
+			assertDecimal(t, "base", tt.band.Base, tt.value(stress.Results[0]))
+			// 沿用最新LPR的持平路径和基准相同
+			assertDecimal(t, "flat", tt.value(stress.Results[3]), tt.band.Base)
+			for _, result := range stress.Results {
+				if tt.value(result).LessThan(tt.band.Min) || tt.value(result).GreaterThan(tt.band.Max) {
+					t.Errorf("%s = %s is outside [%s, %s]", result.Name, tt.value(result), tt.band.Min, tt.band.Max)
+				}
+			}
+		})
+	}
+
+	if _, err := StressTestLPR(Input{Loan: loan}, "emi", nil); err == nil {
+		t.Error("expected an error without paths")
+	}
+}
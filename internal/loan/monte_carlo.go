@@ -0,0 +1,222 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RandomWalkModel LPR 随机游走模型
+// 每月20日报价时按概率上调或下调一个步长,其余情况不变,并限制在上下界之内
+type RandomWalkModel struct {
+	StepBP   decimal.Decimal // 每次变动的基点
+	UpProb   float64         // 每月上调的概率
+	DownProb float64         // 每月下调的概率
+	MinLPR   decimal.Decimal // LPR 下界(%)
+	MaxLPR   decimal.Decimal // LPR 上界(%)
+}
+
+// MonteCarloConfig 蒙特卡洛模拟参数,相同的参数和种子得到相同的结果
+type MonteCarloConfig struct {
+	Model RandomWalkModel
+	Paths int   // 模拟路径数
+	Seed  int64 // 随机种子,第 i 条路径使用 Seed+i
+}
+
+// PercentileBand 分位数
+type PercentileBand struct {
+	P5  decimal.Decimal
+	P25 decimal.Decimal
+	P50 decimal.Decimal
+	P75 decimal.Decimal
+	P95 decimal.Decimal
+}
+
+// MonteCarloRow 每期还款的分位数
+// 按所有路径计算,已结清的路径本期还款为0,否则各路径结清时间不同时分位数会偏高
+type MonteCarloRow struct {
+	LoanTerm    int       // 期数
+	DueDate     time.Time // 还款日期,取基准还款计划,基准计划没有该期时取模拟路径
+	Installment PercentileBand
+	ActivePaths int // 本期仍在还款的路径数
+}
+
+// MonteCarloResult 蒙特卡洛模拟结果
+type MonteCarloResult struct {
+	Paths          int
+	Seed           int64
+	TotalInterest  PercentileBand  // 利息合计
+	MaxInstallment PercentileBand  // 最高每期还款
+	Rows           []MonteCarloRow // 每期还款
+}
+
+// 单条路径的模拟结果
+type monteCarloPath struct {
+	installments   map[int]float64   // 按期数的分期还款
+	dueDates       map[int]time.Time // 按期数的还款日期
+	totalInterest  float64
+	maxInstallment float64
+}
+
+// 生成一条随机游走的LPR路径
+func (model RandomWalkModel) path(lprs []LPR, until time.Time, rng *rand.Rand) []LPR {
+	step := model.StepBP.Div(decimal.NewFromInt(100))
+	rate := lprs[0].LPR
+	return forwardLPR(lprs, until, func(month int) decimal.Decimal {
+		switch p := rng.Float64(); {
+		case p < model.UpProb:
+			rate = rate.Add(step)
+		case p < model.UpProb+model.DownProb:
+			rate = rate.Sub(step)
+		}
+		if rate.GreaterThan(model.MaxLPR) {
+			rate = model.MaxLPR
+		}
+		if rate.LessThan(model.MinLPR) {
+			rate = model.MinLPR
+		}
+		return rate
+	})
+}
+
+// 按路径计算还款计划,只保留分期还款和利息合计
+func simulatePath(inputdata Input, action string, lprs []LPR) monteCarloPath {
+	inputdata = inputdata.clone()
+	inputdata.Loan.LPR = lprs
+	result := monteCarloPath{installments: make(map[int]float64), dueDates: make(map[int]time.Time)}
+	for _, report := range BuildReport(inputdata, action) {
+		switch report.Purpose {
+		case "分期", "提前还款", "结清":
+			result.totalInterest += report.Interest.InexactFloat64()
+		}
+		if report.Purpose == "分期" {
+			installment := report.MonthTotalAmount.InexactFloat64()
+			result.installments[report.LoanTerm] = installment
+			result.dueDates[report.LoanTerm] = report.DueDate
+			if installment > result.maxInstallment {
+				result.maxInstallment = installment
+			}
+		}
+	}
+	return result
+}
+
+// 线性插值的分位数,values 已排序
+func percentile(values []float64, p float64) decimal.Decimal {
+	if len(values) == 0 {
+		return decimal.Zero
+	}
+	pos := p * float64(len(values)-1)
+	lower := int(pos)
+	if lower+1 >= len(values) {
+		return decimal.NewFromFloat(values[lower]).Round(2)
+	}
+	return decimal.NewFromFloat(values[lower] + (values[lower+1]-values[lower])*(pos-float64(lower))).Round(2)
+}
+
+func newPercentileBand(values []float64) PercentileBand {
+	sort.Float64s(values)
+	return PercentileBand{
+		P5:  percentile(values, 0.05),
+		P25: percentile(values, 0.25),
+		P50: percentile(values, 0.50),
+		P75: percentile(values, 0.75),
+		P95: percentile(values, 0.95),
+	}
+}
+
+// SimulateLPR 按随机游走模型模拟LPR路径,按CPU核数并发计算还款计划,返回每期还款和利息合计的分位数
+// 每条路径都要计算完整的还款计划,ctx 取消或超时后不再计算剩余路径,返回 ctx 的错误
+func SimulateLPR(ctx context.Context, inputdata Input, action string, config MonteCarloConfig) (MonteCarloResult, error) {
+	if config.Paths <= 0 {
+		return MonteCarloResult{}, errors.New("paths should be positive")
+	}
+	if len(inputdata.Loan.LPR) == 0 {
+		return MonteCarloResult{}, errors.New("lpr history is required")
+	}
+	model := config.Model
+	if model.UpProb < 0 || model.DownProb < 0 || model.UpProb+model.DownProb > 1 {
+		return MonteCarloResult{}, errors.New("up and down probability should be between 0 and 1")
+	}
+	if model.MaxLPR.LessThan(model.MinLPR) {
+		return MonteCarloResult{}, errors.New("max lpr should not be less than min lpr")
+	}
+	// 远期报价覆盖到贷款到期后一年,最后一期可能晚于到期月
+	until := inputdata.Loan.InitialDate.AddDate(0, inputdata.Loan.InitialTerm+12, 0)
+
+	// 每条路径使用独立的随机数,结果和并发调度无关
+	paths := make([]monteCarloPath, config.Paths)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < runtime.NumCPU(); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue
+				}
+				rng := rand.New(rand.NewSource(config.Seed + int64(i)))
+				paths[i] = simulatePath(inputdata, action, model.path(inputdata.Loan.LPR, until, rng))
+			}
+		}()
+	}
+send:
+	for i := range paths {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return MonteCarloResult{}, err
+	}
+
+	totalInterests := make([]float64, 0, len(paths))
+	maxInstallments := make([]float64, 0, len(paths))
+	installments := make(map[int][]float64)
+	dueDates := make(map[int]time.Time)
+	for _, path := range paths {
+		totalInterests = append(totalInterests, path.totalInterest)
+		maxInstallments = append(maxInstallments, path.maxInstallment)
+		for loanTerm, installment := range path.installments {
+			installments[loanTerm] = append(installments[loanTerm], installment)
+			// 缩短期限的路径最后一期在到期日,取最早的日期即按月的还款日
+			if date, ok := dueDates[loanTerm]; !ok || path.dueDates[loanTerm].Before(date) {
+				dueDates[loanTerm] = path.dueDates[loanTerm]
+			}
+		}
+	}
+	// 还款日期优先取基准还款计划
+	for _, report := range BuildReport(inputdata.clone(), action) {
+		if _, ok := installments[report.LoanTerm]; ok && report.Purpose == "分期" {
+			dueDates[report.LoanTerm] = report.DueDate
+		}
+	}
+
+	rows := make([]MonteCarloRow, 0, len(installments))
+	for loanTerm, values := range installments {
+		active := len(values)
+		// 已结清的路径本期还款为0
+		values = append(values, make([]float64, len(paths)-active)...)
+		rows = append(rows, MonteCarloRow{LoanTerm: loanTerm, DueDate: dueDates[loanTerm], Installment: newPercentileBand(values), ActivePaths: active})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].LoanTerm < rows[j].LoanTerm })
+
+	return MonteCarloResult{
+		Paths:          config.Paths,
+		Seed:           config.Seed,
+		TotalInterest:  newPercentileBand(totalInterests),
+		MaxInstallment: newPercentileBand(maxInstallments),
+		Rows:           rows,
+	}, nil
+}
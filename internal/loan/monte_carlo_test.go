@@ -0,0 +1,218 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSimulateLPR(t *testing.T) {
+	lprLoan := Loan{
+		InitialPrincipal: decimal.NewFromInt(100000),
+		InitialTerm:      24,
+		InitialDate:      ParseDate("2023-05-25"),
+		PaymentDueDay:    18,
+		PlusSpread:       decimal.NewFromFloat(0.1),
+		LPR:              Lprs,
+	}
+	model := RandomWalkModel{StepBP: decimal.NewFromInt(5), UpProb: 0.2, DownProb: 0.2, MinLPR: decimal.NewFromInt(2), MaxLPR: decimal.NewFromInt(8)}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		loan   Loan
+		config MonteCarloConfig
+		flat   bool // LPR 不变,各分位数相同
+		err    error
+	}{
+		{name: "random walk", loan: lprLoan, config: MonteCarloConfig{Model: model, Paths: 50, Seed: 7}},
+		{
+			name:   "flat",
+			loan:   lprLoan,
+			config: MonteCarloConfig{Model: RandomWalkModel{StepBP: decimal.NewFromInt(5), MinLPR: decimal.NewFromInt(2), MaxLPR: decimal.NewFromInt(8)}, Paths: 10},
+			flat:   true,
+		},
+		{
+			// 上下界相同时每条路径都是同一个LPR
+			name:   "pinned by bounds",
+			loan:   lprLoan,
+			config: MonteCarloConfig{Model: RandomWalkModel{StepBP: decimal.NewFromInt(5), UpProb: 0.5, DownProb: 0.5, MinLPR: decimal.NewFromInt(4), MaxLPR: decimal.NewFromInt(4)}, Paths: 10},
+			flat:   true,
+		},
+		{name: "cancelled", ctx: cancelled, loan: lprLoan, config: MonteCarloConfig{Model: model, Paths: 50}, err: context.Canceled},
+		{name: "deadline exceeded", ctx: expired, loan: lprLoan, config: MonteCarloConfig{Model: model, Paths: 50}, err: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			result, err := SimulateLPR(ctx, Input{Loan: tt.loan}, "emi", tt.config)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Rows) != tt.loan.InitialTerm {
+				t.Fatalf("%d rows, want %d", len(result.Rows), tt.loan.InitialTerm)
+			}
+			for _, row := range result.Rows {
+				if row.ActivePaths != tt.config.Paths {
+					t.Fatalf("term %d: %d active paths, want %d", row.LoanTerm, row.ActivePaths, tt.config.Paths)
+				}
+			}
+
+			// 相同的种子结果相同,与并发调度无关
+			again, err := SimulateLPR(context.Background(), Input{Loan: tt.loan}, "emi", tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertDecimal(t, "P50 total interest", again.TotalInterest.P50, result.TotalInterest.P50)
+			assertDecimal(t, "P95 max installment", again.MaxInstallment.P95, result.MaxInstallment.P95)
+
+			bands := map[string]PercentileBand{"total interest": result.TotalInterest, "max installment": result.MaxInstallment}
+			for _, row := range result.Rows {
+				bands[row.DueDate.Format(time.DateOnly)] = row.Installment
+			}
+			for name, band := range bands {
+				values := []decimal.Decimal{band.P5, band.P25, band.P50, band.P75, band.P95}
+				for i := 1; i < len(values); i++ {
+					if values[i].LessThan(values[i-1]) {
+						t.Errorf("%s percentiles are not ordered: %+v", name, band)
+					}
+				}
+				if tt.flat && !band.P5.Equal(band.P95) {
+					t.Errorf("%s should not vary: %+v", name, band)
+				}
+			}
+		})
+	}
+}
+
+// 提前还款缩短期限后各路径结清时间不同,已结清的路径按0计入分位数
+func TestSimulateLPRPaidOffPaths(t *testing.T) {
+	input := Input{
+		Loan: Loan{
+			InitialPrincipal: decimal.NewFromInt(100000),
+			InitialTerm:      120,
+			InitialDate:      ParseDate("2023-05-25"),
+			PaymentDueDay:    18,
+			PlusSpread:       decimal.NewFromFloat(0.1),
+			LPR:              Lprs,
+		},
+		EarlyRepayment: []EarlyRepayment{{Amount: decimal.NewFromInt(30000), Date: ParseDate("2024-08-25"), KeepInstallment: true}},
+	}
+	model := RandomWalkModel{StepBP: decimal.NewFromInt(50), UpProb: 0.5, DownProb: 0.5, MinLPR: decimal.NewFromInt(1), MaxLPR: decimal.NewFromInt(9)}
+	config := MonteCarloConfig{Model: model, Paths: 40, Seed: 3}
+	result, err := SimulateLPR(context.Background(), input, "emi", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paidOff := false
+	for i, row := range result.Rows {
+		if row.LoanTerm != i+1 {
+			t.Fatalf("row %d is term %d", i, row.LoanTerm)
+		}
+		if row.ActivePaths < 1 || row.ActivePaths > config.Paths || (i > 0 && row.ActivePaths > result.Rows[i-1].ActivePaths) {
+			t.Fatalf("term %d: %d active paths", row.LoanTerm, row.ActivePaths)
+		}
+		if i > 0 && row.DueDate.Before(result.Rows[i-1].DueDate) {
+			t.Errorf("term %d is due before the previous term", row.LoanTerm)
+		}
+		// 分位数位置落在已结清的路径上时为0
+		zeros := float64(config.Paths - row.ActivePaths)
+		for p, value := range map[float64]decimal.Decimal{0.05: row.Installment.P5, 0.5: row.Installment.P50, 0.95: row.Installment.P95} {
+			if p*float64(config.Paths-1) <= zeros-1 && !value.IsZero() {
+				t.Errorf("term %d: P%v = %s with %d of %d paths paid off", row.LoanTerm, p*100, value, config.Paths-row.ActivePaths, config.Paths)
+			}
+			if p*float64(config.Paths-1) > zeros && value.IsZero() {
+				t.Errorf("term %d: P%v should not be 0 with %d of %d paths paid off", row.LoanTerm, p*100, config.Paths-row.ActivePaths, config.Paths)
+			}
+		}
+		paidOff = paidOff || row.ActivePaths < config.Paths
+	}
+	if !paidOff {
+		t.Error("paths should be paid off at different terms")
+	}
+}
+
+func TestSimulateLPRConfig(t *testing.T) {
+	valid := RandomWalkModel{StepBP: decimal.NewFromInt(5), UpProb: 0.1, DownProb: 0.1, MinLPR: decimal.NewFromInt(2), MaxLPR: decimal.NewFromInt(8)}
+	tests := []struct {
+		name   string
+		lprs   []LPR
+		config MonteCarloConfig
+	}{
+		{name: "no paths", lprs: Lprs, config: MonteCarloConfig{Model: valid}},
+		{name: "no lpr history", config: MonteCarloConfig{Model: valid, Paths: 1}},
+		{name: "probability above 1", lprs: Lprs, config: MonteCarloConfig{Model: RandomWalkModel{UpProb: 0.6, DownProb: 0.6, MaxLPR: decimal.NewFromInt(8)}, Paths: 1}},
+		{name: "negative probability", lprs: Lprs, config: MonteCarloConfig{Model: RandomWalkModel{UpProb: -0.1, MaxLPR: decimal.NewFromInt(8)}, Paths: 1}},
+		{name: "max below min", lprs: Lprs, config: MonteCarloConfig{Model: RandomWalkModel{MinLPR: decimal.NewFromInt(5), MaxLPR: decimal.NewFromInt(4)}, Paths: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := fixedLoan(12000, 12)
+			loan.LPR = tt.lprs
+			if _, err := SimulateLPR(context.Background(), Input{Loan: loan}, "emi", tt.config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestGetClosestLPRForYear(t *testing.T) {
+	// 逐条比较的查找,作为二分查找的对照
+	linear := func(lprs []LPR, date time.Time) decimal.Decimal {
+		for _, entry := range lprs {
+			if entry.Date.Before(date) {
+				return entry.LPR
+			}
+		}
+		return decimal.Decimal{}
+	}
+	for i := 1; i < len(Lprs); i++ {
+		if !Lprs[i].Date.Before(Lprs[i-1].Date) {
+			t.Fatalf("Lprs should be in descending date order: %s after %s", Lprs[i].Date.Format(time.DateOnly), Lprs[i-1].Date.Format(time.DateOnly))
+		}
+	}
+	forward := LPRPath{Kind: LPRPathStep, StepBP: decimal.NewFromInt(-10)}.Forward(Lprs, ParseDate("2035-01-01"))
+
+	tests := []struct {
+		name        string
+		initialDate string
+		lprs        []LPR
+	}{
+		{name: "history", initialDate: "2020-05-25", lprs: Lprs},
+		{name: "start of year", initialDate: "2021-01-01", lprs: Lprs},
+		{name: "forward path", initialDate: "2022-12-31", lprs: forward},
+		{name: "before first lpr", initialDate: "2010-03-15", lprs: Lprs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := Loan{InitialDate: ParseDate(tt.initialDate), LPR: tt.lprs}
+			for date := ParseDate("2010-01-01"); date.Before(ParseDate("2036-01-01")); date = date.AddDate(0, 0, 7) {
+				// 变更日为放款日的周年日,在此之前取上一年
+				update := time.Date(date.Year(), loan.InitialDate.Month(), loan.InitialDate.Day(), 0, 0, 0, 0, date.Location())
+				if date.Before(update) {
+					update = update.AddDate(-1, 0, 0)
+				}
+				if got, want := loan.getClosestLPRForYear(date), linear(tt.lprs, update); !got.Equal(want) {
+					t.Fatalf("%s: lpr = %s, want %s", date.Format(time.DateOnly), got, want)
+				}
+			}
+		})
+	}
+}
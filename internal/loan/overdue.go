@@ -0,0 +1,117 @@
+package loan
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ActualPayment 实际还款记录
+type ActualPayment struct {
+	Date   time.Time       // 实际还款日期
+	Amount decimal.Decimal // 实际还款金额
+}
+
+// 默认罚息利率在合同利率基础上上浮50%
+var defaultPenaltyRateMarkup = decimal.NewFromFloat(0.5)
+
+// 罚息年利率=合同利率*(1+上浮比例)
+func (loan *Loan) penaltyAPR(APR decimal.Decimal) decimal.Decimal {
+	markup := loan.PenaltyRateMarkup
+	if markup.IsZero() {
+		markup = defaultPenaltyRateMarkup
+	}
+	return APR.Mul(decimal.NewFromInt(1).Add(markup))
+}
+
+// ApplyActualPayments 按实际还款记录计算逾期,罚息和复利
+//
+// 计算规则如下
+// 1.每笔实际还款依次归还: 未还罚息复利 -> 逾期利息 -> 逾期本金 -> 本期利息 -> 本期本金,
+// 多余部分留到下一期
+// 2.还款日当天或之前未还清的本期本金和利息转为逾期本金和逾期利息
+// 3.罚息=逾期本金*罚息利率/360*逾期天数
+// 4.复利=逾期利息*罚息利率/360*逾期天数
+// 5.最后一笔实际还款之后的各期视为按时还款,之前累计的逾期金额在其后第一期一并归还
+// 6.当期还款总额仍为合同金额,逾期金额只记在 Arrears,罚息和复利记在计提的那一期,避免重复计算
+func (loan *Loan) ApplyActualPayments(payments []MonthlyPayment, actualPayments []ActualPayment) []MonthlyPayment {
+	if len(actualPayments) == 0 {
+		return payments
+	}
+	actuals := make([]ActualPayment, len(actualPayments))
+	copy(actuals, actualPayments)
+	sort.Slice(actuals, func(i, j int) bool {
+		return actuals[i].Date.Before(actuals[j].Date)
+	})
+	asOf := actuals[len(actuals)-1].Date
+
+	newPayments := make([]MonthlyPayment, len(payments))
+	copy(newPayments, payments)
+
+	overduePrincipal := decimal.Zero // 逾期本金
+	overdueInterest := decimal.Zero  // 逾期利息
+	unpaidPenalty := decimal.Zero    // 未还罚息和复利
+	credit := decimal.Zero           // 多还的金额
+	accrualDate := loan.InitialDate
+	next := 0
+
+	for i, payment := range newPayments {
+		penaltyInterest := decimal.Zero
+		compoundInterest := decimal.Zero
+		// 计提罚息和复利到指定日期
+		accrue := func(date time.Time) {
+			days := loan.daysDiff(accrualDate, date)
+			if days.IsPositive() {
+				penaltyRate := loan.penaltyAPR(payment.DueDateRate).Div(decimal.NewFromInt(36000)).Mul(days)
+				penalty := overduePrincipal.Mul(penaltyRate).Round(2)
+				compound := overdueInterest.Mul(penaltyRate).Round(2)
+				penaltyInterest = penaltyInterest.Add(penalty)
+				compoundInterest = compoundInterest.Add(compound)
+				unpaidPenalty = unpaidPenalty.Add(penalty).Add(compound)
+				accrualDate = date
+			}
+		}
+
+		dueInterest := payment.Interest
+		duePrincipal := payment.Principal
+		// 还款日当天及之前的实际还款,最后一笔实际还款之后的一期会用掉剩余的全部实际还款
+		for ; next < len(actuals) && !actuals[next].Date.After(payment.DueDate); next++ {
+			accrue(actuals[next].Date)
+			credit = credit.Add(actuals[next].Amount)
+			unpaidPenalty, credit = repay(unpaidPenalty, credit)
+			overdueInterest, credit = repay(overdueInterest, credit)
+			overduePrincipal, credit = repay(overduePrincipal, credit)
+		}
+		accrue(payment.DueDate)
+		newPayments[i].PenaltyInterest = penaltyInterest
+		newPayments[i].CompoundInterest = compoundInterest
+
+		// 最后一笔实际还款之后,仍未还清的逾期金额在本期一并归还,多还的金额冲减本期及之后的还款
+		if payment.DueDate.After(asOf) {
+			newPayments[i].Arrears = overduePrincipal.Add(overdueInterest).Add(unpaidPenalty)
+			overduePrincipal, overdueInterest, unpaidPenalty = decimal.Zero, decimal.Zero, decimal.Zero
+			_, credit = repay(payment.Interest.Add(payment.Principal), credit)
+			continue
+		}
+
+		dueInterest, credit = repay(dueInterest, credit)
+		duePrincipal, credit = repay(duePrincipal, credit)
+
+		// 本期未还清的部分转为逾期
+		overdueInterest = overdueInterest.Add(dueInterest)
+		overduePrincipal = overduePrincipal.Add(duePrincipal)
+
+		newPayments[i].Arrears = overduePrincipal.Add(overdueInterest).Add(unpaidPenalty)
+		newPayments[i].Overdue = newPayments[i].Arrears.IsPositive()
+	}
+	return newPayments
+}
+
+// 用可用金额归还欠款,返回剩余欠款和剩余可用金额
+func repay(owed, available decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	if available.GreaterThanOrEqual(owed) {
+		return decimal.Zero, available.Sub(owed)
+	}
+	return owed.Sub(available), decimal.Zero
+}
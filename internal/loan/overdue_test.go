@@ -0,0 +1,86 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestApplyActualPayments(t *testing.T) {
+	installment := decimal.RequireFromString("1026.74")
+	tests := []struct {
+		name     string
+		actuals  []ActualPayment
+		arrears  []string // 前3期的逾期金额
+		overdue  []bool
+		penalty  []string // 前3期计提的罚息+复利
+		totalFee string   // 罚息复利合计
+	}{
+		{
+			name: "on time",
+			actuals: []ActualPayment{
+				{ParseDate("2023-02-18"), installment},
+				{ParseDate("2023-03-18"), installment},
+				{ParseDate("2023-04-18"), installment},
+			},
+			arrears:  []string{"0", "0", "0"},
+			overdue:  []bool{false, false, false},
+			penalty:  []string{"0", "0", "0"},
+			totalFee: "0",
+		},
+		{
+			// 第2期晚2天还款,罚息0.40,复利0.02从实际还款中扣除,差额在第3期补齐
+			name: "late after the due date",
+			actuals: []ActualPayment{
+				{ParseDate("2023-02-18"), installment},
+				{ParseDate("2023-03-20"), installment},
+			},
+			arrears:  []string{"0", "1026.74", "0.42"},
+			overdue:  []bool{false, true, false},
+			penalty:  []string{"0", "0", "0.42"},
+			totalFee: "0.42",
+		},
+		{
+			// 只还500,未还本金526.74按罚息利率计提28天
+			name:     "partial",
+			actuals:  []ActualPayment{{ParseDate("2023-02-18"), decimal.NewFromInt(500)}},
+			arrears:  []string{"526.74", "529.75", "0"},
+			overdue:  []bool{true, false, false},
+			penalty:  []string{"0", "3.01", "0"},
+			totalFee: "3.01",
+		},
+		{
+			name:     "overpaid",
+			actuals:  []ActualPayment{{ParseDate("2023-02-18"), installment.Mul(decimal.NewFromInt(2))}},
+			arrears:  []string{"0", "0", "0"},
+			overdue:  []bool{false, false, false},
+			penalty:  []string{"0", "0", "0"},
+			totalFee: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := fixedLoan(12000, 12)
+			payments := loan.ApplyActualPayments(loan.EqualMonthlyInstallment(nil), tt.actuals)
+			for i := 0; i < 3; i++ {
+				p := payments[i]
+				assertDecimal(t, "arrears", p.Arrears, decimal.RequireFromString(tt.arrears[i]))
+				assertDecimal(t, "penalty", p.PenaltyInterest.Add(p.CompoundInterest), decimal.RequireFromString(tt.penalty[i]))
+				// 逾期金额不计入当期还款总额
+				assertDecimal(t, "month total", p.MonthTotalAmount, installment)
+				if p.Overdue != tt.overdue[i] {
+					t.Errorf("term %d overdue = %v, want %v", p.LoanTerm, p.Overdue, tt.overdue[i])
+				}
+			}
+
+			// 还款总额只比按时还款多出罚息和复利
+			input := Input{Loan: loan, EarlyRepayment: []EarlyRepayment{}}
+			onTime := Summarize(BuildReport(input, "emi"), nil)
+			input.ActualPayments = tt.actuals
+			summary := Summarize(BuildReport(input, "emi"), nil)
+			assertDecimal(t, "total penalty", summary.TotalPenalty, decimal.RequireFromString(tt.totalFee))
+			assertDecimal(t, "total paid", summary.TotalPaid, onTime.TotalPaid.Add(summary.TotalPenalty))
+		})
+	}
+}
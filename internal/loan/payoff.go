@@ -0,0 +1,115 @@
+package loan
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PayoffQuote 指定日期一次性结清贷款的金额
+type PayoffQuote struct {
+	Date                 time.Time       // 结清日期
+	LastDate             time.Time       // 上一次还款日期,从该日起计息
+	OutstandingPrincipal decimal.Decimal // 剩余本金
+	AccruedInterest      decimal.Decimal // 上一次还款日至结清日的利息
+	Fee                  decimal.Decimal // 提前还款违约金
+	Arrears              decimal.Decimal // 逾期未还金额(本金,利息,罚息和复利),计至结清日
+	PenaltyInterest      decimal.Decimal // 上一次还款日至结清日新计提的罚息,已含在逾期金额中
+	CompoundInterest     decimal.Decimal // 上一次还款日至结清日新计提的复利,已含在逾期金额中
+	Total                decimal.Decimal // 结清总额
+	DueDateRate          decimal.Decimal // 计息利率
+}
+
+// PayoffQuote 计算在指定日期一次性结清贷款需要归还的金额
+// 按不含结清的完整还款计划计算,与还款计划中的结清记录一致
+func (inputdata Input) PayoffQuote(date time.Time, action string) PayoffQuote {
+	inputdata = inputdata.clone()
+	payments := inputdata.monthlyPayments(action)
+	return inputdata.payoffQuote(date, payments, inputdata.schedule(payments))
+}
+
+// 根据完整的还款计划计算结清金额
+// 利息计算和提前还款一致: 剩余本金*年利率/360*(结清日-上一次还款日)
+// 逾期金额按实际还款记录计至结清日,在结清时一并归还
+func (inputdata Input) payoffQuote(date time.Time, payments []MonthlyPayment, reports []Report) PayoffQuote {
+	loan := inputdata.Loan
+	quote := PayoffQuote{
+		Date:                 date,
+		LastDate:             loan.InitialDate,
+		OutstandingPrincipal: loan.InitialPrincipal,
+		AccruedInterest:      decimal.Zero,
+		Fee:                  decimal.Zero,
+	}
+	// 结清日之后的第一个还款日,利率和提前还款一样按该期取
+	nextDueDate := date
+	for _, report := range reports {
+		if report.Purpose == "分期" && !report.DueDate.Before(date) {
+			nextDueDate = report.DueDate
+			break
+		}
+	}
+	for _, report := range reports {
+		if !report.DueDate.Before(date) {
+			break
+		}
+		if report.IsEmptyEarlyRepayment() {
+			continue
+		}
+		switch report.Purpose {
+		case "贷款发放", "分期", "提前还款":
+			quote.LastDate = report.DueDate
+			quote.OutstandingPrincipal = report.RemainingPrincipal
+		}
+	}
+
+	quote.DueDateRate = loan.aprAt(nextDueDate)
+	days := loan.daysDiff(quote.LastDate, date)
+	quote.AccruedInterest = quote.OutstandingPrincipal.Mul(quote.DueDateRate).Div(decimal.NewFromInt(36000)).Mul(days).Round(2)
+	quote.Fee = loan.prepaymentFee(date, quote.OutstandingPrincipal, quote.DueDateRate)
+
+	// 在结清日加一期金额为0的还款,按实际还款记录计算截至结清日的逾期金额
+	due := make([]MonthlyPayment, 0, len(payments)+1)
+	for _, payment := range payments {
+		if !payment.DueDate.Before(date) {
+			break
+		}
+		due = append(due, payment)
+	}
+	due = append(due, MonthlyPayment{DueDate: date, DueDateRate: quote.DueDateRate})
+	due = loan.ApplyActualPayments(due, inputdata.ActualPayments)
+	payoff := due[len(due)-1]
+	quote.Arrears = payoff.Arrears
+	quote.PenaltyInterest = payoff.PenaltyInterest
+	quote.CompoundInterest = payoff.CompoundInterest
+
+	quote.Total = quote.OutstandingPrincipal.Add(quote.AccruedInterest).Add(quote.Fee).Add(quote.Arrears)
+	return quote
+}
+
+// 在结清日截断还款计划,并添加结清记录
+// 逾期金额在之前各期已计入,结清记录的还款总额不含逾期金额,只在 Arrears 中显示
+func payoff2Report(quote PayoffQuote, reports []Report) []Report {
+	newReport := make([]Report, 0, len(reports)+1)
+	for _, report := range reports {
+		if !report.DueDate.Before(quote.Date) {
+			break
+		}
+		newReport = append(newReport, report)
+	}
+	newReport = append(newReport, Report{
+		Index:              len(newReport),
+		Purpose:            "结清",
+		Principal:          quote.OutstandingPrincipal,
+		Interest:           quote.AccruedInterest,
+		Fee:                quote.Fee,
+		MonthTotalAmount:   quote.OutstandingPrincipal.Add(quote.AccruedInterest).Add(quote.Fee),
+		RemainingPrincipal: decimal.Zero,
+		TotalInterestPaid:  decimal.Zero,
+		DueDateRate:        quote.DueDateRate,
+		DueDate:            quote.Date,
+		PenaltyInterest:    quote.PenaltyInterest,
+		CompoundInterest:   quote.CompoundInterest,
+		Arrears:            quote.Arrears,
+	})
+	return newReport
+}
@@ -0,0 +1,102 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPayoffQuote(t *testing.T) {
+	// lpr浮动利率,2023-01-18 起按新的lpr重定价
+	lprLoan := Loan{
+		InitialPrincipal: decimal.NewFromInt(1000000),
+		InitialTerm:      360,
+		InitialDate:      ParseDate("2019-12-25"),
+		PaymentDueDay:    18,
+		PlusSpread:       decimal.NewFromFloat(0.1),
+		LPR:              Lprs,
+	}
+	penaltyLoan := fixedLoan(12000, 12)
+	penaltyLoan.PrepaymentPenalties = []PrepaymentPenaltyRule{{WithinMonths: 12, Percent: decimal.NewFromInt(1)}}
+	paid := func(date string, amount float64) ActualPayment {
+		return ActualPayment{Date: ParseDate(date), Amount: decimal.NewFromFloat(amount)}
+	}
+
+	tests := []struct {
+		name    string
+		loan    Loan
+		action  string
+		actuals []ActualPayment
+		date    string
+		arrears [2]float64 // 逾期金额的范围
+		fee     bool
+	}{
+		{name: "before repricing", loan: lprLoan, action: "emi", date: "2023-01-05"},
+		{name: "equal principal", loan: lprLoan, action: "epp", date: "2023-01-05"},
+		{name: "prepayment fee", loan: penaltyLoan, action: "emi", date: "2023-04-01", fee: true},
+		{
+			name:    "on time",
+			loan:    fixedLoan(12000, 12),
+			action:  "emi",
+			actuals: []ActualPayment{paid("2023-02-18", 1026.74), paid("2023-03-18", 1026.74)},
+			date:    "2023-04-01",
+		},
+		{
+			// 第2期只还了500,结清时归还剩余的逾期金额和罚息
+			name:    "partly overdue",
+			loan:    fixedLoan(12000, 12),
+			action:  "emi",
+			actuals: []ActualPayment{paid("2023-02-18", 1026.74), paid("2023-03-25", 500)},
+			date:    "2023-04-01",
+			arrears: [2]float64{528, 530},
+		},
+		{
+			// 结清日之后的实际还款不计入
+			name:    "overdue at payoff",
+			loan:    fixedLoan(12000, 12),
+			action:  "emi",
+			actuals: []ActualPayment{paid("2023-02-18", 1026.74), paid("2023-05-01", 2000)},
+			date:    "2023-04-01",
+			arrears: [2]float64{1028, 1031},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date := ParseDate(tt.date)
+			input := Input{Loan: tt.loan, ActualPayments: tt.actuals}
+			quote := input.PayoffQuote(date, tt.action)
+
+			// 和还款计划中的结清记录一致
+			input.PayoffDate = date
+			reports := BuildReport(input, tt.action)
+			row := reports[len(reports)-1]
+			if row.Purpose != "结清" {
+				t.Fatalf("last row is %s, want 结清", row.Purpose)
+			}
+			assertDecimal(t, "principal", quote.OutstandingPrincipal, row.Principal)
+			assertDecimal(t, "interest", quote.AccruedInterest, row.Interest)
+			assertDecimal(t, "fee", quote.Fee, row.Fee)
+			assertDecimal(t, "rate", quote.DueDateRate, row.DueDateRate)
+			assertDecimal(t, "arrears", quote.Arrears, row.Arrears)
+			assertDecimal(t, "total", quote.Total, row.MonthTotalAmount.Add(row.Arrears))
+
+			// 利率按结清日之后的第一个还款日取,与提前还款一致
+			for _, report := range BuildReport(Input{Loan: tt.loan}, tt.action) {
+				if report.Purpose == "分期" && !report.DueDate.Before(date) {
+					assertDecimal(t, "rate at next due date", quote.DueDateRate, report.DueDateRate)
+					break
+				}
+			}
+			if tt.fee != quote.Fee.IsPositive() {
+				t.Errorf("fee = %s, want positive %v", quote.Fee, tt.fee)
+			}
+			arrears := quote.Arrears.InexactFloat64()
+			if arrears < tt.arrears[0] || arrears > tt.arrears[1] {
+				t.Errorf("arrears = %s, want between %v and %v", quote.Arrears, tt.arrears[0], tt.arrears[1])
+			}
+			if arrears > 0 && !quote.PenaltyInterest.Add(quote.CompoundInterest).IsPositive() {
+				t.Error("penalty should accrue until the payoff date")
+			}
+		})
+	}
+}
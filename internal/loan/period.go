@@ -0,0 +1,33 @@
+package loan
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Period 表示相邻两个还款日之间的间隔
+type Period struct {
+	Months int // 月数
+	Days   int // 天数
+}
+
+var (
+	Monthly  = Period{Months: 1} // 按月还款
+	BiWeekly = Period{Days: 14}  // 双周供,每14天还款一次
+)
+
+// 下一个还款日
+func (p Period) next(date time.Time) time.Time {
+	return date.AddDate(0, p.Months, p.Days)
+}
+
+// 上一个还款日
+func (p Period) previous(date time.Time) time.Time {
+	return date.AddDate(0, -p.Months, -p.Days)
+}
+
+// 每期计息天数,每月按30天计算
+func (p Period) days() decimal.Decimal {
+	return decimal.NewFromInt(int64(p.Months*30 + p.Days))
+}
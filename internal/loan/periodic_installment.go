@@ -0,0 +1,146 @@
+package loan
+
+import (
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 按任意还款周期生成等额还款计划,供尾款和双周供使用
+// 和等额本息的区别:
+// 1.还款日按 period 递增,而不是固定每月加一个月
+// 2.每期利息=剩余本金*年利率/360*本期天数,月按30天,双周按14天
+// 3.最后一期归还全部剩余本金(含尾款)
+// 4.lpr变更日或加点调整生效日落在本期内时,利息按变更日分段计算
+
+// 每期利率=年利率/100/360*每期天数
+func periodInterestRate(APR decimal.Decimal, period Period) decimal.Decimal {
+	return APR.Div(decimal.NewFromInt(36000)).Mul(period.days())
+}
+
+// 带尾款的等额还款额
+// PMT = ( P*(1+r)**n - B ) * r / ( (1+r)**n - 1 )
+// P 是剩余本金
+// B 是尾款
+// r 是每期利率
+// n 是剩余期数
+func calculateInstallment(remainingPrincipal, balloon, rate decimal.Decimal, remainTerm int) decimal.Decimal {
+	onePlusRatePow := decimal.NewFromInt(1).Add(rate).Pow(decimal.NewFromInt(int64(remainTerm)))
+	return remainingPrincipal.Mul(onePlusRatePow).Sub(balloon).Mul(rate).Div(onePlusRatePow.Sub(decimal.NewFromInt(1))).Round(2)
+}
+
+// 还款额不变时还清剩余本金所需的期数,用于提前还款后缩短期限
+// 由 PMT 公式反解: (1+r)**n = (PMT - B*r) / (PMT - P*r)
+// 还款额不足以支付利息时返回 false
+func shortenedTerm(remainingPrincipal, balloon, rate, installment decimal.Decimal) (int, bool) {
+	P, B, r, A := remainingPrincipal.InexactFloat64(), balloon.InexactFloat64(), rate.InexactFloat64(), installment.InexactFloat64()
+	var n float64
+	switch {
+	case r == 0 && A > 0:
+		n = (P - B) / A
+	case r > 0 && A > P*r:
+		n = math.Log((A-B*r)/(A-P*r)) / math.Log(1+r)
+	default:
+		return 0, false
+	}
+	terms := int(math.Ceil(n - 1e-6))
+	// 还款额取整到分,少还的几分钱累计到最后只剩零头,由前一期作为最后一期一并归还,不再多出一期
+	if k := terms - 1; k >= 1 {
+		growth := math.Pow(1+r, float64(k))
+		residual := P*growth - B - A*float64(k)
+		if r > 0 {
+			residual = P*growth - B - A*(growth-1)/r
+		}
+		if residual <= 0.005*float64(k)*growth {
+			terms = k
+		}
+	}
+	if terms < 1 {
+		terms = 1
+	}
+	return terms, true
+}
+
+// (from,to]之间的lpr变更日,即放款日的周年日
+func (loan *Loan) lprUpdateDatesBetween(from, to time.Time) []time.Time {
+	dates := make([]time.Time, 0, 1)
+	for year := from.Year(); year <= to.Year(); year++ {
+		LPRUpdateDate := time.Date(year, loan.InitialDate.Month(), loan.InitialDate.Day(), 0, 0, 0, 0, loan.InitialDate.Location())
+		if LPRUpdateDate.After(from) && !LPRUpdateDate.After(to) && LPRUpdateDate.After(loan.InitialDate) {
+			dates = append(dates, LPRUpdateDate)
+		}
+	}
+	return dates
+}
+
+func (loan *Loan) periodicInstallment(period Period, firstDueDate time.Time, loanTerms int, balloon decimal.Decimal, earlyRepayment []EarlyRepayment) []MonthlyPayment {
+	payments := make([]MonthlyPayment, 0, loanTerms)
+	// 提前还款缩短期限后最后一期会提前
+	lastTerm := loanTerms
+	remainingPrincipal := loan.InitialPrincipal
+	previousDueDate := loan.InitialDate
+	dueDate := firstDueDate
+	APR := loan.aprAt(dueDate)
+	installment := calculateInstallment(remainingPrincipal, balloon, periodInterestRate(APR, period), loanTerms)
+
+	for loanTerm := 1; loanTerm <= lastTerm; loanTerm++ {
+		remainTerm := lastTerm - loanTerm + 1
+		// 提前还款后重新计算每期还款额,或者还款额不变缩短期限
+		amount, daysDiff := loan.makeEarlyRepaymentBetween(remainingPrincipal, earlyRepayment, previousDueDate, dueDate)
+		if amount.Cmp(remainingPrincipal) == -1 {
+			remainingPrincipal = amount.Round(2)
+			// 提前还款已还清
+			if !remainingPrincipal.IsPositive() {
+				break
+			}
+			n, ok := shortenedTerm(remainingPrincipal, balloon, periodInterestRate(APR, period), installment)
+			if keepInstallmentBetween(earlyRepayment, previousDueDate, dueDate) && ok && n < remainTerm {
+				lastTerm = loanTerm + n - 1
+				remainTerm = n
+			} else {
+				installment = calculateInstallment(remainingPrincipal, balloon, periodInterestRate(APR, period), remainTerm)
+			}
+		}
+
+		// 本期计息天数,第一期放款日当天不计息;本期有提前还款时,之前的天数已随提前还款计息
+		from, days := previousDueDate, period.days()
+		if loanTerm == 1 {
+			from, days = loan.InitialDate.AddDate(0, 0, 1), loan.daysDiff(loan.InitialDate, dueDate).Sub(decimal.NewFromInt(1))
+		}
+		from, days = from.AddDate(0, 0, int(daysDiff.IntPart())), days.Sub(daysDiff)
+
+		previousAPR := APR
+		APR = loan.aprAt(dueDate)
+		if previousAPR.Cmp(APR) != 0 {
+			installment = calculateInstallment(remainingPrincipal, balloon, periodInterestRate(APR, period), remainTerm)
+		}
+		// 按利率分段计息,lpr变更日和加点调整生效日都是分段点,第一期也可能分段
+		principal := remainingPrincipal
+		interestPayment := segmentedInterest(loan.rateSegments(from, dueDate, days, APR, loan.lprUpdateDatesBetween(from, dueDate)...), func(APR, days decimal.Decimal) decimal.Decimal {
+			return principal.Mul(APR).Div(decimal.NewFromInt(36000)).Mul(days)
+		})
+
+		principalPayment := installment.Sub(interestPayment)
+		// 最后一期归还全部剩余本金,包括尾款
+		if loanTerm == lastTerm {
+			principalPayment = remainingPrincipal
+		}
+		remainingPrincipal = remainingPrincipal.Sub(principalPayment).Round(2)
+
+		payments = append(payments, MonthlyPayment{
+			LoanTerm:           loanTerm,
+			Principal:          principalPayment,
+			Interest:           interestPayment,
+			MonthTotalAmount:   principalPayment.Add(interestPayment),
+			RemainingPrincipal: remainingPrincipal,
+			DueDateRate:        APR,
+			DueDate:            dueDate,
+		})
+
+		previousDueDate = dueDate
+		dueDate = period.next(dueDate)
+	}
+
+	return payments
+}
@@ -0,0 +1,208 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPeriodicInstallment(t *testing.T) {
+	balloonLoan := fixedLoan(120000, 36)
+	balloonLoan.BalloonAmount = decimal.NewFromInt(40000)
+	emiTotal := decimal.Zero
+	for _, payment := range (Input{Loan: fixedLoan(120000, 36)}).monthlyPayments("emi") {
+		emiTotal = emiTotal.Add(payment.Interest)
+	}
+
+	tests := []struct {
+		name        string
+		payments    []MonthlyPayment
+		terms       int
+		installment string // 除最后一期外的每期还款额
+		days        int    // 相邻还款日间隔天数,0为按月
+		lastAtLeast int64  // 最后一期至少归还的本金
+		lessThanEMI bool   // 利息合计少于同期限的等额本息
+	}{
+		{
+			// 没有尾款时和等额本息的月供相同
+			name:        "balloon without balloon amount",
+			payments:    Input{Loan: fixedLoan(12000, 12)}.monthlyPayments("balloon"),
+			terms:       12,
+			installment: "1026.74",
+		},
+		{
+			name:        "balloon",
+			payments:    Input{Loan: balloonLoan}.monthlyPayments("balloon"),
+			terms:       36,
+			installment: calculateInstallment(decimal.NewFromInt(120000), decimal.NewFromInt(40000), periodInterestRate(decimal.NewFromFloat(4.9), Monthly), 36).String(),
+			lastAtLeast: 40000,
+		},
+		{
+			// 每年26期,每14天还款一次
+			name:        "bi-weekly",
+			payments:    Input{Loan: fixedLoan(120000, 36)}.monthlyPayments("biweekly"),
+			terms:       78,
+			installment: calculateInstallment(decimal.NewFromInt(120000), decimal.Zero, periodInterestRate(decimal.NewFromFloat(4.9), BiWeekly), 78).String(),
+			days:        14,
+			lessThanEMI: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.payments) != tt.terms {
+				t.Fatalf("%d payments, want %d", len(tt.payments), tt.terms)
+			}
+			interest := decimal.Zero
+			for i, payment := range tt.payments {
+				interest = interest.Add(payment.Interest)
+				if i < len(tt.payments)-1 && payment.MonthTotalAmount.String() != tt.installment {
+					t.Errorf("term %d = %s, want %s", payment.LoanTerm, payment.MonthTotalAmount, tt.installment)
+				}
+				if i > 0 && tt.days > 0 {
+					if days := payment.DueDate.Sub(tt.payments[i-1].DueDate).Hours() / 24; days != float64(tt.days) {
+						t.Errorf("term %d is %v days after the previous one, want %d", payment.LoanTerm, days, tt.days)
+					}
+				}
+			}
+			last := tt.payments[len(tt.payments)-1]
+			if !last.RemainingPrincipal.IsZero() {
+				t.Errorf("remaining principal = %s, want 0", last.RemainingPrincipal)
+			}
+			if last.Principal.LessThan(decimal.NewFromInt(tt.lastAtLeast)) {
+				t.Errorf("last principal = %s, want at least %d", last.Principal, tt.lastAtLeast)
+			}
+			if tt.lessThanEMI && !interest.LessThan(emiTotal) {
+				t.Errorf("interest = %s, want less than %s", interest, emiTotal)
+			}
+		})
+	}
+}
+
+func TestShortenedTerm(t *testing.T) {
+	monthly := periodInterestRate(decimal.NewFromFloat(4.9), Monthly)
+	tests := []struct {
+		name        string
+		principal   int64
+		balloon     int64
+		rate        decimal.Decimal
+		installment decimal.Decimal // 为0时按 terms 期计算的还款额
+		terms       int
+		ok          bool
+	}{
+		{name: "no balloon", principal: 120000, rate: monthly, terms: 36, ok: true},
+		{name: "with balloon", principal: 120000, balloon: 40000, rate: monthly, terms: 36, ok: true},
+		{name: "long term", principal: 1000000, rate: monthly, terms: 360, ok: true},
+		{name: "zero rate", principal: 12000, rate: decimal.Zero, installment: decimal.NewFromInt(1000), terms: 12, ok: true},
+		// 还款额不足以支付利息
+		{name: "installment below interest", principal: 120000, rate: monthly, installment: decimal.NewFromInt(400)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installment := tt.installment
+			if installment.IsZero() {
+				installment = calculateInstallment(decimal.NewFromInt(tt.principal), decimal.NewFromInt(tt.balloon), tt.rate, tt.terms)
+			}
+			terms, ok := shortenedTerm(decimal.NewFromInt(tt.principal), decimal.NewFromInt(tt.balloon), tt.rate, installment)
+			if ok != tt.ok || terms != tt.terms {
+				t.Errorf("shortenedTerm = %d, %v, want %d, %v", terms, ok, tt.terms, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPeriodicInstallmentRateSegments(t *testing.T) {
+	// 2022-05-25 放款,加点0.1,每年5月25日lpr重定价;尾款每月18日还款,双周供每14天还款
+	type segment struct {
+		from string // 分段起始日,按该日执行的利率
+		days int64
+	}
+	tests := []struct {
+		name     string
+		action   string
+		changes  []SpreadChange
+		early    string // 提前还款日期,为空时不提前还款
+		dueDate  string // 分段计息的那一期的还款日
+		segments []segment
+	}{
+		{
+			name: "balloon first term", action: "balloon", dueDate: "2022-06-18",
+			changes:  []SpreadChange{{Date: ParseDate("2022-06-10"), PlusSpread: decimal.NewFromFloat(-0.3)}},
+			segments: []segment{{"2022-05-26", 15}, {"2022-06-10", 8}},
+		},
+		{
+			// 加点调整和lpr重定价在同一期,分为三段
+			name: "balloon spread change and lpr reset", action: "balloon", dueDate: "2023-06-18",
+			changes:  []SpreadChange{{Date: ParseDate("2023-05-20"), PlusSpread: decimal.NewFromFloat(-0.3)}},
+			segments: []segment{{"2023-05-18", 2}, {"2023-05-20", 5}, {"2023-05-25", 23}},
+		},
+		{
+			// 期内调整后又调回,还款日的利率不变也要分段
+			name: "balloon rate restored within the term", action: "balloon", dueDate: "2023-03-18",
+			changes: []SpreadChange{
+				{Date: ParseDate("2023-03-01"), PlusSpread: decimal.NewFromFloat(-0.3)},
+				{Date: ParseDate("2023-03-10"), PlusSpread: decimal.NewFromFloat(0.1)},
+			},
+			segments: []segment{{"2023-02-18", 11}, {"2023-03-01", 9}, {"2023-03-10", 10}},
+		},
+		{
+			// 提前还款之前的利息随提前还款归还,本期从提前还款日起分段
+			name: "balloon prepayment in the same term", action: "balloon", early: "2023-09-20", dueDate: "2023-10-18",
+			changes:  []SpreadChange{{Date: ParseDate("2023-09-25"), PlusSpread: decimal.NewFromFloat(-0.3)}},
+			segments: []segment{{"2023-09-20", 5}, {"2023-09-25", 23}},
+		},
+		{
+			name: "bi-weekly first term", action: "biweekly", dueDate: "2022-06-08",
+			changes:  []SpreadChange{{Date: ParseDate("2022-06-01"), PlusSpread: decimal.NewFromFloat(-0.3)}},
+			segments: []segment{{"2022-05-26", 6}, {"2022-06-01", 7}},
+		},
+		{
+			name: "bi-weekly lpr reset", action: "biweekly", dueDate: "2023-06-07",
+			segments: []segment{{"2023-05-24", 1}, {"2023-05-25", 13}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := Loan{
+				InitialPrincipal: decimal.NewFromInt(1000000),
+				InitialTerm:      24,
+				InitialDate:      ParseDate("2022-05-25"),
+				LPR:              Lprs,
+				PlusSpread:       decimal.NewFromFloat(0.1),
+				PaymentDueDay:    18,
+				SpreadChanges:    tt.changes,
+			}
+			input := Input{Loan: loan}
+			if tt.early != "" {
+				input.EarlyRepayment = []EarlyRepayment{{Amount: decimal.NewFromInt(100000), Date: ParseDate(tt.early)}}
+			}
+			reports := BuildReport(input, tt.action)
+
+			// 本期计息本金为之前最近一条记录的剩余本金
+			principal := loan.InitialPrincipal
+			var row *Report
+			for i := range reports {
+				if reports[i].Purpose == "分期" && reports[i].DueDate.Equal(ParseDate(tt.dueDate)) {
+					row = &reports[i]
+					break
+				}
+				if reports[i].Purpose != "利率调整" {
+					principal = reports[i].RemainingPrincipal
+				}
+			}
+			if row == nil {
+				t.Fatalf("no installment on %s", tt.dueDate)
+			}
+
+			want := decimal.Zero
+			for _, s := range tt.segments {
+				want = want.Add(principal.Mul(loan.aprAt(ParseDate(s.from))).Div(decimal.NewFromInt(36000)).Mul(decimal.NewFromInt(s.days)))
+			}
+			if row.Interest.Sub(want).Abs().GreaterThan(decimal.NewFromFloat(0.02)) {
+				t.Errorf("interest = %s, want %s", row.Interest, want.Round(2))
+			}
+			if last := reports[len(reports)-1]; !last.RemainingPrincipal.IsZero() {
+				t.Errorf("remaining principal = %s, want 0", last.RemainingPrincipal)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package loan
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 提前还款规划目标
+const (
+	PlanObjectiveInterest = "interest" // 利息和违约金最少
+	PlanObjectivePayoff   = "payoff"   // 最早结清
+)
+
+// 还款时机
+const (
+	PlanStrategyASAP         = "资金到位即还"
+	PlanStrategyAfterTier    = "违约金降档后还"
+	PlanStrategyAfterPenalty = "违约金期满后还"
+)
+
+// LumpSum 一次性可用于提前还款的资金,例如年终奖
+type LumpSum struct {
+	Date   time.Time       // 到账日期
+	Amount decimal.Decimal // 金额
+}
+
+// PrepaymentBudget 提前还款预算和银行的限制
+type PrepaymentBudget struct {
+	MonthlySurplus    decimal.Decimal // 每月结余,每个还款日后到账
+	LumpSums          []LumpSum       // 一次性资金
+	MinAmount         decimal.Decimal // 银行单次最低提前还款金额
+	MinIntervalMonths int             // 两次提前还款最少间隔月数,0为不限制
+	Objective         string          // 规划目标
+}
+
+// PrepaymentPlan 提前还款方案
+type PrepaymentPlan struct {
+	Objective       string           // 规划目标
+	Strategy        string           // 还款时机
+	NotBefore       time.Time        // 规划的提前还款不早于该日期
+	KeepInstallment bool             // 月供不变缩短期限,否则期限不变减少月供
+	EarlyRepayments []EarlyRepayment // 规划的提前还款,不含原有的提前还款
+	Summary         Summary          // 还款计划汇总
+	Reports         []Report         // 还款计划
+}
+
+// 规划方案优于另一个方案
+// 1.利息最少: 比较利息和违约金合计,相同时比较结清日期
+// 2.最早结清: 比较结清日期,相同时比较利息和违约金合计
+func (plan PrepaymentPlan) betterThan(other PrepaymentPlan) bool {
+	cost := plan.Summary.TotalInterest.Add(plan.Summary.TotalFee)
+	otherCost := other.Summary.TotalInterest.Add(other.Summary.TotalFee)
+	if plan.Objective == PlanObjectivePayoff && !plan.Summary.PayoffDate.Equal(other.Summary.PayoffDate) {
+		return plan.Summary.PayoffDate.Before(other.Summary.PayoffDate)
+	}
+	if !cost.Equal(otherCost) {
+		return cost.LessThan(otherCost)
+	}
+	return plan.Summary.PayoffDate.Before(other.Summary.PayoffDate)
+}
+
+// 违约金各档规则的到期日期,按日期排序并去重,最后一个为违约金期满日期
+func (loan *Loan) penaltyTierDates() []time.Time {
+	dates := make([]time.Time, 0, len(loan.PrepaymentPenalties))
+	for _, rule := range loan.PrepaymentPenalties {
+		end := loan.InitialDate.AddDate(0, rule.WithinMonths, 0)
+		if !end.After(loan.InitialDate) {
+			continue
+		}
+		duplicate := false
+		for _, date := range dates {
+			if date.Equal(end) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			dates = append(dates, end)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// 规划的还款时机: 资金到位即还,以及等到违约金每一档到期后再还
+// 违约金按提前还款本金的比例收取,只有降档或期满时推迟才可能更省
+func (loan *Loan) planStrategies() []PrepaymentPlan {
+	strategies := []PrepaymentPlan{{Strategy: PlanStrategyASAP, NotBefore: loan.InitialDate}}
+	dates := loan.penaltyTierDates()
+	for i, date := range dates {
+		strategy := PlanStrategyAfterTier
+		if i == len(dates)-1 {
+			strategy = PlanStrategyAfterPenalty
+		}
+		strategies = append(strategies, PrepaymentPlan{Strategy: strategy, NotBefore: date})
+	}
+	return strategies
+}
+
+// 按资金到账情况生成提前还款日期和金额
+// 1.每月结余在还款日后到账,一次性资金按到账日期累计
+// 2.提前还款日为还款日的后一天,本期利息最少
+// 3.资金达到最低金额,且距上次提前还款满间隔月数时全部用于提前还款
+// 4.已有提前还款的月份不再安排,与已有提前还款也要满间隔月数
+func (loan *Loan) scheduleEarlyRepayments(budget PrepaymentBudget, existing []EarlyRepayment, notBefore, horizon time.Time) []EarlyRepayment {
+	lumpSums := make([]LumpSum, len(budget.LumpSums))
+	copy(lumpSums, budget.LumpSums)
+	sort.Slice(lumpSums, func(i, j int) bool { return lumpSums[i].Date.Before(lumpSums[j].Date) })
+
+	plan := make([]EarlyRepayment, 0)
+	cash := decimal.Zero
+	lastDate := time.Time{}
+	dueDate := time.Date(loan.InitialDate.Year(), loan.InitialDate.Month()+1, loan.PaymentDueDay, 0, 0, 0, 0, loan.InitialDate.Location())
+	for ; dueDate.Before(horizon); dueDate = Monthly.next(dueDate) {
+		date := dueDate.AddDate(0, 0, 1)
+		cash = cash.Add(budget.MonthlySurplus)
+		for len(lumpSums) > 0 && !lumpSums[0].Date.After(date) {
+			cash = cash.Add(lumpSums[0].Amount)
+			lumpSums = lumpSums[1:]
+		}
+		if date.Before(notBefore) {
+			continue
+		}
+
+		nextDueDate := Monthly.next(dueDate)
+		if hasEarlyRepaymentBetween(existing, dueDate, nextDueDate) {
+			lastDate = date
+			continue
+		}
+		if !cash.IsPositive() || cash.LessThan(budget.MinAmount) {
+			continue
+		}
+		if !lastDate.IsZero() && date.Before(lastDate.AddDate(0, budget.MinIntervalMonths, 0)) {
+			continue
+		}
+		// 原有的提前还款之前也要满间隔月数
+		if hasEarlyRepaymentBetween(existing, dueDate, date.AddDate(0, budget.MinIntervalMonths, 0)) {
+			continue
+		}
+		plan = append(plan, EarlyRepayment{Amount: cash, Date: date})
+		cash = decimal.Zero
+		lastDate = date
+	}
+	return plan
+}
+
+// (上一个还款日,本期还款日)之间是否有提前还款
+func hasEarlyRepaymentBetween(earlyRepayments []EarlyRepayment, previousDueDate, dueDate time.Time) bool {
+	for _, early := range earlyRepayments {
+		if early.Amount.IsPositive() && early.Date.After(previousDueDate) && early.Date.Before(dueDate) {
+			return true
+		}
+	}
+	return false
+}
+
+// 按规划的提前还款生成还款计划
+// 提前还款超过剩余本金时减少到刚好结清,之后的提前还款不再安排
+func buildPlan(inputdata Input, action string, existing, planned []EarlyRepayment, keepInstallment bool) ([]EarlyRepayment, []Report) {
+	planned = append([]EarlyRepayment(nil), planned...)
+	for {
+		in := inputdata.clone()
+		in.EarlyRepayment = append(append([]EarlyRepayment(nil), existing...), planned...)
+		for i := len(existing); i < len(in.EarlyRepayment); i++ {
+			in.EarlyRepayment[i] = EarlyRepayment{Amount: planned[i-len(existing)].Amount, Date: planned[i-len(existing)].Date, KeepInstallment: keepInstallment}
+		}
+		reports := BuildReport(in, action)
+		result := in.EarlyRepayment[len(existing):]
+
+		changed := false
+		for i, early := range result {
+			// 贷款已结清,之后的提前还款不再处理
+			if early.DueDateRate.IsZero() {
+				planned = planned[:i]
+				changed = true
+				break
+			}
+			if early.RemainingPrincipal.IsNegative() {
+				planned[i].Amount = early.Amount.Add(early.RemainingPrincipal)
+				planned = planned[:i+1]
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return result, reports
+		}
+	}
+}
+
+// PlanPrepayments 根据提前还款预算规划提前还款
+// 这是启发式的规划,不是对金额和日期的全局搜索:
+// 1.还款时机只比较资金到位即还和违约金每一档到期后再还,到期前的资金累计到到期后一次还
+// 2.每次提前还款用掉全部累计资金,不保留一部分留到以后;违约金和节省的利息都与金额成正比,拆分金额不会更省
+// 3.每个还款时机分别按月供不变缩短期限和期限不变减少月供生成方案,按目标选出最优方案
+// 原有的提前还款保留,规划的提前还款避开原有提前还款的月份
+func PlanPrepayments(inputdata Input, action string, budget PrepaymentBudget) (PrepaymentPlan, error) {
+	if action == "biweekly" {
+		return PrepaymentPlan{}, errors.New("prepayment planning is not supported for bi-weekly repayment")
+	}
+	if budget.Objective != PlanObjectivePayoff {
+		budget.Objective = PlanObjectiveInterest
+	}
+
+	existing := make([]EarlyRepayment, 0, len(inputdata.EarlyRepayment))
+	for _, early := range inputdata.EarlyRepayment {
+		if early.Amount.IsPositive() {
+			existing = append(existing, EarlyRepayment{Amount: early.Amount, Date: early.Date, KeepInstallment: early.KeepInstallment})
+		}
+	}
+
+	// 规划期限为原有还款计划的结清日期
+	baseline := inputdata.clone()
+	baseline.EarlyRepayment = existing
+	baseline.PayoffDate = time.Time{}
+	horizon := Summarize(BuildReport(baseline, action), nil).PayoffDate
+
+	// 不提前还款的还款计划,各方案汇总时共用
+	noPrepayment := BaselineReport(inputdata, action)
+
+	var best PrepaymentPlan
+	found := false
+	for _, strategy := range inputdata.Loan.planStrategies() {
+		planned := inputdata.Loan.scheduleEarlyRepayments(budget, existing, strategy.NotBefore, horizon)
+		if len(planned) == 0 {
+			continue
+		}
+		for _, keepInstallment := range []bool{true, false} {
+			earlyRepayments, reports := buildPlan(inputdata, action, existing, planned, keepInstallment)
+			plan := PrepaymentPlan{
+				Objective:       budget.Objective,
+				Strategy:        strategy.Strategy,
+				NotBefore:       strategy.NotBefore,
+				KeepInstallment: keepInstallment,
+				EarlyRepayments: earlyRepayments,
+				Summary:         Summarize(reports, noPrepayment),
+				Reports:         reports,
+			}
+			if !found || plan.betterThan(best) {
+				best = plan
+				found = true
+			}
+		}
+	}
+	if !found {
+		return PrepaymentPlan{}, errors.New("budget is not enough for any prepayment")
+	}
+	return best, nil
+}
@@ -0,0 +1,197 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPlanPrepayments(t *testing.T) {
+	// 违约金分两档: 6个月内20%,24个月内0.1%
+	tieredLoan := fixedLoan(200000, 120)
+	tieredLoan.PrepaymentPenalties = []PrepaymentPenaltyRule{
+		{WithinMonths: 24, Percent: decimal.NewFromFloat(0.1)},
+		{WithinMonths: 6, Percent: decimal.NewFromInt(20)},
+	}
+	existing := []EarlyRepayment{{Amount: decimal.NewFromInt(5000), Date: ParseDate("2023-07-20")}}
+
+	tests := []struct {
+		name      string
+		loan      Loan
+		action    string
+		existing  []EarlyRepayment
+		budget    PrepaymentBudget
+		strategy  string
+		notBefore string // 期望的最早提前还款日期,为空时为放款日
+		paidOff   bool   // 规划的资金足够提前结清
+		err       bool
+	}{
+		{
+			name:     "no penalty",
+			loan:     fixedLoan(200000, 120),
+			action:   "emi",
+			budget:   PrepaymentBudget{MonthlySurplus: decimal.NewFromInt(3000), MinAmount: decimal.NewFromInt(10000), MinIntervalMonths: 6},
+			strategy: PlanStrategyASAP,
+		},
+		{
+			// 20%的违约金远高于节省的利息,等到降档后再还
+			name:      "wait for lower tier",
+			loan:      tieredLoan,
+			action:    "epp",
+			budget:    PrepaymentBudget{LumpSums: []LumpSum{{Date: ParseDate("2023-02-01"), Amount: decimal.NewFromInt(50000)}}},
+			strategy:  PlanStrategyAfterTier,
+			notBefore: "2023-07-10",
+		},
+		{
+			// 违约金从提前还款金额中扣除,降档前还款反而结清更晚
+			name:      "payoff objective",
+			loan:      tieredLoan,
+			action:    "emi",
+			budget:    PrepaymentBudget{MonthlySurplus: decimal.NewFromInt(2000), MinAmount: decimal.NewFromInt(5000), Objective: PlanObjectivePayoff},
+			strategy:  PlanStrategyAfterTier,
+			notBefore: "2023-07-10",
+		},
+		{
+			// 规划避开原有提前还款的月份
+			name:     "keeps existing prepayments",
+			loan:     fixedLoan(200000, 120),
+			action:   "emi",
+			existing: existing,
+			budget:   PrepaymentBudget{MonthlySurplus: decimal.NewFromInt(1000), MinAmount: decimal.NewFromInt(3000), MinIntervalMonths: 3},
+			strategy: PlanStrategyASAP,
+		},
+		{
+			// 资金超过剩余本金时最后一次减少到刚好结清
+			name:     "paid off",
+			loan:     fixedLoan(50000, 120),
+			action:   "emi",
+			budget:   PrepaymentBudget{MonthlySurplus: decimal.NewFromInt(5000), LumpSums: []LumpSum{{Date: ParseDate("2023-06-01"), Amount: decimal.NewFromInt(30000)}}},
+			strategy: PlanStrategyASAP,
+			paidOff:  true,
+		},
+		{
+			name:   "bi-weekly",
+			loan:   fixedLoan(200000, 120),
+			action: "biweekly",
+			budget: PrepaymentBudget{MonthlySurplus: decimal.NewFromInt(3000)},
+			err:    true,
+		},
+		{
+			name:   "budget below minimum",
+			loan:   fixedLoan(200000, 12),
+			action: "emi",
+			budget: PrepaymentBudget{LumpSums: []LumpSum{{Date: ParseDate("2023-02-01"), Amount: decimal.NewFromInt(1000)}}, MinAmount: decimal.NewFromInt(5000)},
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := Input{Loan: tt.loan, EarlyRepayment: tt.existing}
+			plan, err := PlanPrepayments(input, tt.action, tt.budget)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.Strategy != tt.strategy {
+				t.Errorf("strategy = %s, want %s", plan.Strategy, tt.strategy)
+			}
+			notBefore := tt.loan.InitialDate
+			if tt.notBefore != "" {
+				notBefore = ParseDate(tt.notBefore)
+			}
+			if !plan.NotBefore.Equal(notBefore) {
+				t.Errorf("not before = %s, want %s", plan.NotBefore.Format(time.DateOnly), tt.notBefore)
+			}
+			if len(plan.EarlyRepayments) == 0 {
+				t.Fatal("no prepayment planned")
+			}
+
+			// 不超过预算,满足最低金额和间隔,且不早于还款时机
+			total := decimal.Zero
+			for i, early := range plan.EarlyRepayments {
+				total = total.Add(early.Amount)
+				if early.Date.Before(plan.NotBefore) {
+					t.Errorf("prepayment on %s before %s", early.Date.Format(time.DateOnly), plan.NotBefore.Format(time.DateOnly))
+				}
+				// 最后一次提前还款结清贷款时可以低于最低金额
+				if early.Amount.LessThan(tt.budget.MinAmount) && !early.RemainingPrincipal.IsZero() {
+					t.Errorf("prepayment %s below minimum %s", early.Amount, tt.budget.MinAmount)
+				}
+				if i > 0 && early.Date.Before(plan.EarlyRepayments[i-1].Date.AddDate(0, tt.budget.MinIntervalMonths, 0)) {
+					t.Errorf("prepayment on %s within %d months of the previous one", early.Date.Format(time.DateOnly), tt.budget.MinIntervalMonths)
+				}
+				for _, e := range tt.existing {
+					if early.Date.Year() == e.Date.Year() && early.Date.Month() == e.Date.Month() {
+						t.Errorf("prepayment on %s in the same month as an existing one", early.Date.Format(time.DateOnly))
+					}
+				}
+			}
+			last := plan.EarlyRepayments[len(plan.EarlyRepayments)-1].Date
+			months := (last.Year()-tt.loan.InitialDate.Year())*12 + int(last.Month()-tt.loan.InitialDate.Month()) + 1
+			budget := tt.budget.MonthlySurplus.Mul(decimal.NewFromInt(int64(months)))
+			for _, lump := range tt.budget.LumpSums {
+				budget = budget.Add(lump.Amount)
+			}
+			if total.GreaterThan(budget) {
+				t.Errorf("planned %s exceeds budget %s", total, budget)
+			}
+
+			// 原有的提前还款保留在还款计划中
+			for _, e := range tt.existing {
+				found := false
+				for _, report := range plan.Reports {
+					if report.Purpose == "提前还款" && report.DueDate.Equal(e.Date) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("existing prepayment on %s is missing", e.Date.Format(time.DateOnly))
+				}
+			}
+
+			// 选出的方案不差于其他还款时机和还款方式
+			for _, strategy := range tt.loan.planStrategies() {
+				planned := tt.loan.scheduleEarlyRepayments(tt.budget, tt.existing, strategy.NotBefore, plan.Reports[len(plan.Reports)-1].DueDate.AddDate(100, 0, 0))
+				if len(planned) == 0 {
+					continue
+				}
+				for _, keep := range []bool{true, false} {
+					early, reports := buildPlan(input, tt.action, tt.existing, planned, keep)
+					other := PrepaymentPlan{Objective: plan.Objective, EarlyRepayments: early, Summary: Summarize(reports, nil)}
+					if other.betterThan(plan) {
+						t.Errorf("%s keep=%v is better than the chosen plan", strategy.Strategy, keep)
+					}
+				}
+			}
+
+			final := plan.Reports[len(plan.Reports)-1]
+			if !final.RemainingPrincipal.IsZero() {
+				t.Errorf("remaining principal = %s, want 0", final.RemainingPrincipal)
+			}
+			if tt.paidOff && final.Purpose != "提前还款" {
+				t.Errorf("last row is %s, want the loan paid off by a prepayment", final.Purpose)
+			}
+		})
+	}
+}
+
+func TestPenaltyTierDates(t *testing.T) {
+	loan := fixedLoan(12000, 12)
+	loan.PrepaymentPenalties = []PrepaymentPenaltyRule{{WithinMonths: 24}, {WithinMonths: 0}, {WithinMonths: 6}, {WithinMonths: 24}}
+	want := []string{"2023-07-10", "2025-01-10"}
+	dates := loan.penaltyTierDates()
+	if len(dates) != len(want) {
+		t.Fatalf("dates = %v, want %v", dates, want)
+	}
+	for i := range want {
+		if got := dates[i].Format(time.DateOnly); got != want[i] {
+			t.Errorf("dates[%d] = %s, want %s", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package loan
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PrepaymentPenaltyRule 提前还款违约金规则
+// 放款后 WithinMonths 个月内提前还款时收取,按提前还款本金的百分比,或者若干个月的利息
+type PrepaymentPenaltyRule struct {
+	WithinMonths   int             // 放款后多少个月内收取
+	Percent        decimal.Decimal // 按提前还款本金的百分比收取
+	InterestMonths int             // 按提前还款本金若干个月的利息收取
+}
+
+// 提前还款违约金占提前还款本金的比例
+// 1.取放款后期限最短且覆盖还款日期的规则
+// 2.百分比和利息月数同时设置时两者相加
+func (loan *Loan) prepaymentFeeRate(date time.Time, APR decimal.Decimal) decimal.Decimal {
+	var matched *PrepaymentPenaltyRule
+	for i, rule := range loan.PrepaymentPenalties {
+		if !date.Before(loan.InitialDate.AddDate(0, rule.WithinMonths, 0)) {
+			continue
+		}
+		if matched == nil || rule.WithinMonths < matched.WithinMonths {
+			matched = &loan.PrepaymentPenalties[i]
+		}
+	}
+	if matched == nil {
+		return decimal.Zero
+	}
+	percent := matched.Percent.Div(decimal.NewFromInt(100))
+	return percent.Add(APR.Div(decimal.NewFromInt(1200)).Mul(decimal.NewFromInt(int64(matched.InterestMonths))))
+}
+
+// 提前还款违约金,按提前还款本金计算
+func (loan *Loan) prepaymentFee(date time.Time, principal, APR decimal.Decimal) decimal.Decimal {
+	if !principal.IsPositive() {
+		return decimal.Zero
+	}
+	return principal.Mul(loan.prepaymentFeeRate(date, APR)).Round(2)
+}
+
+// 提前还款金额扣除利息后同时支付本金和违约金,本金=(金额-利息)/(1+违约金比例)
+func (loan *Loan) prepaidPrincipal(date time.Time, amount, APR decimal.Decimal) decimal.Decimal {
+	return amount.Div(decimal.NewFromInt(1).Add(loan.prepaymentFeeRate(date, APR)))
+}
@@ -0,0 +1,85 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPrepaymentFee(t *testing.T) {
+	// 2023-01-10 放款: 6个月内收3个月利息,12个月内收2%,36个月内收1%
+	tiered := []PrepaymentPenaltyRule{
+		{WithinMonths: 12, Percent: decimal.NewFromInt(2)},
+		{WithinMonths: 36, Percent: decimal.NewFromInt(1)},
+		{WithinMonths: 6, InterestMonths: 3},
+	}
+	tests := []struct {
+		name      string
+		rules     []PrepaymentPenaltyRule
+		date      string
+		principal int64
+		want      string
+	}{
+		{name: "no rules", date: "2023-03-01", principal: 10000, want: "0"},
+		{name: "interest months", rules: tiered, date: "2023-03-01", principal: 10000, want: "122.5"},
+		// 到期日当天已不在该档内
+		{name: "tier boundary", rules: tiered, date: "2023-07-10", principal: 10000, want: "200"},
+		{name: "second tier", rules: tiered, date: "2023-08-01", principal: 10000, want: "200"},
+		{name: "third tier", rules: tiered, date: "2024-06-01", principal: 10000, want: "100"},
+		{name: "after all tiers", rules: tiered, date: "2026-02-01", principal: 10000, want: "0"},
+		// 百分比和利息月数同时设置时相加
+		{name: "percent and interest", rules: []PrepaymentPenaltyRule{{WithinMonths: 12, Percent: decimal.NewFromInt(1), InterestMonths: 1}}, date: "2023-03-01", principal: 10000, want: "140.83"},
+		{name: "no principal", rules: tiered, date: "2023-03-01", principal: 0, want: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := fixedLoan(120000, 120)
+			loan.PrepaymentPenalties = tt.rules
+			got := loan.prepaymentFee(ParseDate(tt.date), decimal.NewFromInt(tt.principal), decimal.NewFromFloat(4.9))
+			assertDecimal(t, "fee", got, decimal.RequireFromString(tt.want))
+		})
+	}
+}
+
+func TestPrepaymentFeeInSchedule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule PrepaymentPenaltyRule
+		date string
+		rate float64 // 违约金占归还本金的比例,0为不收
+	}{
+		{name: "percent", rule: PrepaymentPenaltyRule{WithinMonths: 12, Percent: decimal.NewFromInt(2)}, date: "2023-05-25", rate: 0.02},
+		// 3个月利息: 4.9%/12*3
+		{name: "interest months", rule: PrepaymentPenaltyRule{WithinMonths: 12, InterestMonths: 3}, date: "2023-05-25", rate: 0.01225},
+		{name: "after penalty period", rule: PrepaymentPenaltyRule{WithinMonths: 12, Percent: decimal.NewFromInt(2)}, date: "2024-02-25"},
+	}
+	for _, tt := range tests {
+		for _, action := range []string{"emi", "epp"} {
+			t.Run(tt.name+" "+action, func(t *testing.T) {
+				loan := fixedLoan(120000, 120)
+				loan.PrepaymentPenalties = []PrepaymentPenaltyRule{tt.rule}
+				input := Input{Loan: loan, EarlyRepayment: []EarlyRepayment{{Amount: decimal.NewFromInt(10000), Date: ParseDate(tt.date)}}}
+				var row *Report
+				reports := BuildReport(input, action)
+				for i := range reports {
+					if reports[i].Purpose == "提前还款" {
+						row = &reports[i]
+					}
+				}
+				if row == nil {
+					t.Fatal("no prepayment row")
+				}
+				if row.Fee.IsPositive() != (tt.rate > 0) {
+					t.Errorf("fee = %s, want positive %v", row.Fee, tt.rate > 0)
+				}
+				// 违约金按归还的本金计算,不包括违约金本身
+				want := row.Principal.Mul(decimal.NewFromFloat(tt.rate))
+				if row.Fee.Sub(want).Abs().GreaterThan(decimal.NewFromFloat(0.01)) {
+					t.Errorf("fee = %s, want %s of principal %s = %s", row.Fee, decimal.NewFromFloat(tt.rate), row.Principal, want.Round(2))
+				}
+				assertDecimal(t, "amount", row.Principal.Add(row.Interest).Add(row.Fee), decimal.NewFromInt(10000))
+				assertDecimal(t, "total fee", Summarize(reports, nil).TotalFee, row.Fee)
+			})
+		}
+	}
+}
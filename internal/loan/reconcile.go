@@ -0,0 +1,207 @@
+package loan
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BankTransaction 银行app导出的实际扣款记录
+type BankTransaction struct {
+	Date      time.Time       // 扣款日期
+	LoanTerm  int             // 期数,csv没有期数列或为提前还款时为0
+	Principal decimal.Decimal // 本金
+	Interest  decimal.Decimal // 利息
+	Balance   decimal.Decimal // 扣款后剩余本金
+}
+
+// ReconcileRow 还款计划与实际扣款的对账结果
+type ReconcileRow struct {
+	Date              time.Time       // 扣款日期
+	LoanTerm          int             // 期数
+	Purpose           string          // 明细性质
+	Matched           bool            // 是否匹配到还款计划
+	ExpectedDate      time.Time       // 计划还款日期
+	ExpectedPrincipal decimal.Decimal // 计划本金
+	ActualPrincipal   decimal.Decimal // 实际本金
+	PrincipalDiff     decimal.Decimal // 本金差异=实际-计划
+	ExpectedInterest  decimal.Decimal // 计划利息
+	ActualInterest    decimal.Decimal // 实际利息
+	InterestDiff      decimal.Decimal // 利息差异=实际-计划
+	ExpectedBalance   decimal.Decimal // 计划剩余本金
+	ActualBalance     decimal.Decimal // 实际剩余本金
+	BalanceDiff       decimal.Decimal // 剩余本金差异=实际-计划
+	Mismatch          bool            // 差异是否超过容差
+}
+
+// 扣款日可能因节假日顺延,按期匹配时允许的最大天数
+const reconcileMaxDaysApart = 7
+
+// csv表头支持中英文
+var bankTransactionHeaders = map[string]string{
+	"date":      "date",
+	"日期":        "date",
+	"扣款日期":      "date",
+	"principal": "principal",
+	"本金":        "principal",
+	"interest":  "interest",
+	"利息":        "interest",
+	"balance":   "balance",
+	"余额":        "balance",
+	"剩余本金":      "balance",
+	"term":      "term",
+	"期数":        "term",
+}
+
+// ParseBankTransactions 解析银行导出的csv,第一行为表头,需包含日期,本金,利息,余额四列,期数列可选
+func ParseBankTransactions(r io.Reader) ([]BankTransaction, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty csv")
+	}
+
+	columns := make(map[string]int)
+	for i, header := range records[0] {
+		// 去掉excel导出时的BOM
+		header = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")))
+		if name, ok := bankTransactionHeaders[header]; ok {
+			columns[name] = i
+		}
+	}
+	for _, name := range []string{"date", "principal", "interest", "balance"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column: %s", name)
+		}
+	}
+
+	transactions := make([]BankTransaction, 0, len(records)-1)
+	for line, record := range records[1:] {
+		date := ParseDate(strings.TrimSpace(record[columns["date"]]))
+		if date.IsZero() {
+			return nil, fmt.Errorf("line %d: date should be yyyy-mm-dd", line+2)
+		}
+		transaction := BankTransaction{Date: date}
+		for name, value := range map[string]*decimal.Decimal{
+			"principal": &transaction.Principal,
+			"interest":  &transaction.Interest,
+			"balance":   &transaction.Balance,
+		} {
+			// 去掉千分位
+			amount, err := decimal.NewFromString(strings.ReplaceAll(strings.TrimSpace(record[columns[name]]), ",", ""))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s should be a number", line+2, name)
+			}
+			*value = amount
+		}
+		// 提前还款可能没有期数
+		if column, ok := columns["term"]; ok && strings.TrimSpace(record[column]) != "" {
+			term, err := strconv.Atoi(strings.TrimSpace(record[column]))
+			if err != nil || term < 1 {
+				return nil, fmt.Errorf("line %d: term should be a positive integer", line+2)
+			}
+			transaction.LoanTerm = term
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// Reconcile 将实际扣款逐条匹配到还款计划,标记超过容差的差异
+//
+// 匹配规则如下,未填写的提前还款(金额为0)不参与匹配
+// 1.优先匹配日期相同的分期或提前还款,csv有期数时期数也要相同(提前还款的期数为0)
+// 2.csv有期数时匹配该期的分期,不论日期
+// 3.否则匹配日期最接近且未被匹配的分期,相差不超过7天(节假日顺延),扣款按期数顺序发生,只匹配已匹配的最后一期之后的分期
+// 4.都匹配不到的记录标记为未匹配
+func Reconcile(reports []Report, transactions []BankTransaction, tolerance decimal.Decimal) []ReconcileRow {
+	sorted := make([]BankTransaction, len(transactions))
+	copy(sorted, transactions)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	matched := make(map[int]bool)
+	lastTerm := 0
+	rows := make([]ReconcileRow, 0, len(sorted))
+	for _, transaction := range sorted {
+		row := ReconcileRow{
+			Date:            transaction.Date,
+			ActualPrincipal: transaction.Principal,
+			ActualInterest:  transaction.Interest,
+			ActualBalance:   transaction.Balance,
+		}
+		if i, ok := matchReport(reports, transaction, matched, lastTerm); ok {
+			matched[i] = true
+			report := reports[i]
+			if report.Purpose == "分期" && report.LoanTerm > lastTerm {
+				lastTerm = report.LoanTerm
+			}
+			row.Matched = true
+			row.LoanTerm = report.LoanTerm
+			row.Purpose = report.Purpose
+			row.ExpectedDate = report.DueDate
+			row.ExpectedPrincipal = report.Principal
+			row.ExpectedInterest = report.Interest
+			row.ExpectedBalance = report.RemainingPrincipal
+			row.PrincipalDiff = transaction.Principal.Sub(report.Principal)
+			row.InterestDiff = transaction.Interest.Sub(report.Interest)
+			row.BalanceDiff = transaction.Balance.Sub(report.RemainingPrincipal)
+			row.Mismatch = row.PrincipalDiff.Abs().GreaterThan(tolerance) ||
+				row.InterestDiff.Abs().GreaterThan(tolerance) ||
+				row.BalanceDiff.Abs().GreaterThan(tolerance)
+		} else {
+			row.Mismatch = true
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// 查找与扣款匹配的还款计划行,lastTerm 为已匹配的最后一期
+func matchReport(reports []Report, transaction BankTransaction, matched map[int]bool, lastTerm int) (int, bool) {
+	candidate := func(i int, report Report) bool {
+		return !matched[i] && !report.IsEmptyEarlyRepayment() &&
+			(transaction.LoanTerm == 0 || report.LoanTerm == transaction.LoanTerm)
+	}
+	for i, report := range reports {
+		if candidate(i, report) && report.DueDate.Equal(transaction.Date) && (report.Purpose == "分期" || report.Purpose == "提前还款") {
+			return i, true
+		}
+	}
+	if transaction.LoanTerm > 0 {
+		for i, report := range reports {
+			if candidate(i, report) && report.Purpose == "分期" {
+				return i, true
+			}
+		}
+		return -1, false
+	}
+
+	closest := -1
+	closestDays := reconcileMaxDaysApart + 1
+	for i, report := range reports {
+		if !candidate(i, report) || report.Purpose != "分期" || report.LoanTerm <= lastTerm {
+			continue
+		}
+		days := int(transaction.Date.Sub(report.DueDate).Hours() / 24)
+		if days < 0 {
+			days = -days
+		}
+		if days < closestDays {
+			closest, closestDays = i, days
+		}
+	}
+	return closest, closest >= 0
+}
@@ -0,0 +1,153 @@
+package loan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseBankTransactions(t *testing.T) {
+	tests := []struct {
+		name  string
+		csv   string
+		count int
+		first BankTransaction
+		err   string
+	}{
+		{
+			name:  "english headers",
+			csv:   "date,principal,interest,balance\n2023-02-18,976.67,50.07,11023.33\n2023-03-18,986.69,40.05,10036.64\n",
+			count: 2,
+			first: BankTransaction{Date: ParseDate("2023-02-18"), Principal: decimal.RequireFromString("976.67"), Interest: decimal.RequireFromString("50.07"), Balance: decimal.RequireFromString("11023.33")},
+		},
+		{
+			// excel导出带BOM,列顺序不同,金额带千分位
+			name:  "chinese headers",
+			csv:   "\ufeff扣款日期, 利息, 本金, 剩余本金\n2023-02-18,50.07,976.67,\"11,023.33\"\n",
+			count: 1,
+			first: BankTransaction{Date: ParseDate("2023-02-18"), Principal: decimal.RequireFromString("976.67"), Interest: decimal.RequireFromString("50.07"), Balance: decimal.RequireFromString("11023.33")},
+		},
+		{
+			name:  "term column",
+			csv:   "期数,日期,本金,利息,余额\n1,2023-02-18,976.67,50.07,11023.33\n",
+			count: 1,
+			first: BankTransaction{Date: ParseDate("2023-02-18"), LoanTerm: 1, Principal: decimal.RequireFromString("976.67"), Interest: decimal.RequireFromString("50.07"), Balance: decimal.RequireFromString("11023.33")},
+		},
+		{
+			// 提前还款没有期数
+			name:  "empty term",
+			csv:   "期数,日期,本金,利息,余额\n,2023-04-25,1991.98,8.02,8989.70\n",
+			count: 1,
+			first: BankTransaction{Date: ParseDate("2023-04-25"), Principal: decimal.RequireFromString("1991.98"), Interest: decimal.RequireFromString("8.02"), Balance: decimal.RequireFromString("8989.70")},
+		},
+		{name: "bad term", csv: "term,date,principal,interest,balance\n0,2023-02-18,976.67,50.07,11023.33\n", err: "line 2: term"},
+		{name: "missing column", csv: "date,principal,interest\n2023-02-18,976.67,50.07\n", err: "missing column: balance"},
+		{name: "bad date", csv: "date,principal,interest,balance\n2023/02/18,976.67,50.07,11023.33\n", err: "line 2: date"},
+		{name: "bad amount", csv: "date,principal,interest,balance\n2023-02-18,976.67,n/a,11023.33\n", err: "line 2: interest"},
+		{name: "empty", csv: "", err: "empty csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions, err := ParseBankTransactions(strings.NewReader(tt.csv))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(transactions) != tt.count {
+				t.Fatalf("%d transactions, want %d", len(transactions), tt.count)
+			}
+			got := transactions[0]
+			if !got.Date.Equal(tt.first.Date) || got.LoanTerm != tt.first.LoanTerm {
+				t.Errorf("date = %s, term = %d, want %s, %d", got.Date, got.LoanTerm, tt.first.Date, tt.first.LoanTerm)
+			}
+			assertDecimal(t, "principal", got.Principal, tt.first.Principal)
+			assertDecimal(t, "interest", got.Interest, tt.first.Interest)
+			assertDecimal(t, "balance", got.Balance, tt.first.Balance)
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	// 第二笔提前还款未填写金额,日期和第7期顺延后的扣款日相同
+	input := Input{Loan: fixedLoan(12000, 12), EarlyRepayment: []EarlyRepayment{
+		{Amount: decimal.NewFromInt(2000), Date: ParseDate("2023-04-25")},
+		{Amount: decimal.Zero, Date: ParseDate("2023-08-20")},
+	}}
+	reports := BuildReport(input, "emi")
+	find := func(purpose, date string) Report {
+		for _, report := range reports {
+			if report.Purpose == purpose && report.DueDate.Equal(ParseDate(date)) {
+				return report
+			}
+		}
+		t.Fatalf("no %s on %s", purpose, date)
+		return Report{}
+	}
+	// 按还款计划生成扣款记录,可以调整日期和金额
+	transaction := func(report Report, date string, interestDiff float64) BankTransaction {
+		return BankTransaction{
+			Date:      ParseDate(date),
+			Principal: report.Principal,
+			Interest:  report.Interest.Add(decimal.NewFromFloat(interestDiff)),
+			Balance:   report.RemainingPrincipal,
+		}
+	}
+	second := find("分期", "2023-03-18")
+	tolerance := decimal.NewFromFloat(0.05)
+
+	withTerm := func(transaction BankTransaction, term int) BankTransaction {
+		transaction.LoanTerm = term
+		return transaction
+	}
+	tests := []struct {
+		name         string
+		transaction  BankTransaction
+		matched      bool
+		expectedDate string
+		mismatch     bool
+	}{
+		{name: "same date", transaction: transaction(find("分期", "2023-02-18"), "2023-02-18", 0), matched: true, expectedDate: "2023-02-18"},
+		// 节假日顺延
+		{name: "postponed", transaction: transaction(second, "2023-03-20", 0), matched: true, expectedDate: "2023-03-18"},
+		{name: "prepayment", transaction: transaction(find("提前还款", "2023-04-25"), "2023-04-25", 0), matched: true, expectedDate: "2023-04-25"},
+		{name: "within tolerance", transaction: transaction(find("分期", "2023-05-18"), "2023-05-18", 0.05), matched: true, expectedDate: "2023-05-18"},
+		{name: "interest differs", transaction: transaction(find("分期", "2023-06-18"), "2023-06-18", 1.2), matched: true, expectedDate: "2023-06-18", mismatch: true},
+		{name: "too far from any due date", transaction: transaction(second, "2023-09-01", 0), mismatch: true},
+		// 未填写的提前还款不参与匹配
+		{name: "placeholder on the debit date", transaction: transaction(find("分期", "2023-08-18"), "2023-08-20", 0), matched: true, expectedDate: "2023-08-18"},
+		// 有期数时按期数匹配,不受日期限制
+		{name: "term far from due date", transaction: withTerm(transaction(second, "2023-04-05", 0), 2), matched: true, expectedDate: "2023-03-18"},
+		{name: "term differs from same date", transaction: withTerm(transaction(second, "2023-02-18", 0), 2), matched: true, expectedDate: "2023-03-18"},
+		{name: "unknown term", transaction: withTerm(transaction(second, "2023-03-18", 0), 20), mismatch: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, row := range Reconcile(reports, []BankTransaction{tt.transaction}, tolerance) {
+				if tt.matched && row.Purpose != "分期" && row.Purpose != "提前还款" {
+					t.Errorf("matched a %s row", row.Purpose)
+				}
+				if row.Matched != tt.matched || row.Mismatch != tt.mismatch {
+					t.Errorf("matched = %v, mismatch = %v, want %v, %v", row.Matched, row.Mismatch, tt.matched, tt.mismatch)
+				}
+				if tt.matched && !row.ExpectedDate.Equal(ParseDate(tt.expectedDate)) {
+					t.Errorf("matched %s, want %s", row.ExpectedDate.Format("2006-01-02"), tt.expectedDate)
+				}
+			}
+		})
+	}
+
+	// 同一期不会被两条扣款匹配,7天内没有其他分期时第二条为未匹配
+	rows := Reconcile(reports, []BankTransaction{transaction(second, "2023-03-19", 0), transaction(second, "2023-03-18", 0)}, tolerance)
+	if !rows[0].Matched || !rows[0].ExpectedDate.Equal(second.DueDate) {
+		t.Errorf("first transaction matched %s, want %s", rows[0].ExpectedDate.Format("2006-01-02"), second.DueDate.Format("2006-01-02"))
+	}
+	if rows[1].Matched || !rows[1].Mismatch {
+		t.Errorf("second transaction matched %s, want unmatched", rows[1].ExpectedDate.Format("2006-01-02"))
+	}
+}
@@ -17,11 +17,28 @@ type Report struct {
 	Purpose            string          // 明细性质
 	Principal          decimal.Decimal // 本金部分
 	Interest           decimal.Decimal // 利息部分
+	Fee                decimal.Decimal // 违约金
 	MonthTotalAmount   decimal.Decimal // 当月还款总金额
 	RemainingPrincipal decimal.Decimal // 剩余本金
 	TotalInterestPaid  decimal.Decimal // 已支付总利息
+	TotalFeePaid       decimal.Decimal // 已支付总违约金
 	DueDateRate        decimal.Decimal // 当月利率=lpr+加点
 	DueDate            time.Time       // 当月还款日期
+	RateLimit          string          // 利率上下限生效标记
+	PenaltyInterest    decimal.Decimal // 罚息
+	CompoundInterest   decimal.Decimal // 复利
+	Arrears            decimal.Decimal // 逾期未还金额
+	Status             string          // 还款状态
+}
+
+// IsEmptyEarlyRepayment 金额为0的提前还款是表单中未填写的占位,未实际发生
+func (report Report) IsEmptyEarlyRepayment() bool {
+	return report.Purpose == "提前还款" && report.MonthTotalAmount.IsZero()
+}
+
+// 实际支付的现金,当期还款总额加罚息和复利,逾期金额已包含在之前各期的还款总额中
+func (report Report) paid() decimal.Decimal {
+	return report.MonthTotalAmount.Add(report.PenaltyInterest).Add(report.CompoundInterest)
 }
 
 func loan2Report(loan Loan, report []Report) []Report {
@@ -36,7 +53,7 @@ func loan2Report(loan Loan, report []Report) []Report {
 		MonthTotalAmount:   decimal.Zero,
 		RemainingPrincipal: loan.InitialPrincipal,
 		TotalInterestPaid:  decimal.Zero,
-		DueDateRate:        loan.getClosestLPRForYear(loan.InitialDate).Add(loan.PlusSpread),
+		DueDateRate:        loan.aprAt(loan.InitialDate),
 		DueDate:            loan.InitialDate,
 	}
 	return newReport
@@ -50,7 +67,8 @@ func earlyRepayment2Report(earlyRepayments []EarlyRepayment, report []Report) []
 			Purpose:            "提前还款",
 			Principal:          early.Principal,
 			Interest:           early.Interest,
-			MonthTotalAmount:   early.Principal.Add(early.Interest),
+			Fee:                early.Fee,
+			MonthTotalAmount:   early.Principal.Add(early.Interest).Add(early.Fee),
 			RemainingPrincipal: early.RemainingPrincipal,
 			TotalInterestPaid:  decimal.Zero,
 			DueDateRate:        early.DueDateRate,
@@ -60,6 +78,39 @@ func earlyRepayment2Report(earlyRepayments []EarlyRepayment, report []Report) []
 	return newReport
 }
 
+func spreadChange2Report(loan Loan, payments []MonthlyPayment, report []Report) []Report {
+	newReport := make([]Report, len(report), len(report)+len(loan.SpreadChanges))
+	copy(newReport, report)
+	for i, change := range loan.SpreadChanges {
+		// 剩余本金取生效日前最近一期还款后的余额
+		remainingPrincipal := loan.InitialPrincipal
+		inLoanLife := false
+		for _, payment := range payments {
+			if payment.DueDate.Before(change.Date) {
+				remainingPrincipal = payment.RemainingPrincipal
+			} else {
+				inLoanLife = true
+			}
+		}
+		// 贷款发放前或者结清后的调整不显示
+		if !inLoanLife || change.Date.Before(loan.InitialDate) {
+			continue
+		}
+		newReport = append(newReport, Report{
+			Index:              i,
+			Purpose:            "利率调整",
+			Principal:          decimal.Zero,
+			Interest:           decimal.Zero,
+			MonthTotalAmount:   decimal.Zero,
+			RemainingPrincipal: remainingPrincipal,
+			TotalInterestPaid:  decimal.Zero,
+			DueDateRate:        loan.aprAt(change.Date),
+			DueDate:            change.Date,
+		})
+	}
+	return newReport
+}
+
 func monthlyPayment2Report(payments []MonthlyPayment, report []Report) []Report {
 	newReport := make([]Report, len(report)+len(payments))
 	copy(newReport, report)
@@ -75,6 +126,12 @@ func monthlyPayment2Report(payments []MonthlyPayment, report []Report) []Report
 			TotalInterestPaid:  decimal.Zero,
 			DueDateRate:        payment.DueDateRate,
 			DueDate:            payment.DueDate,
+			PenaltyInterest:    payment.PenaltyInterest,
+			CompoundInterest:   payment.CompoundInterest,
+			Arrears:            payment.Arrears,
+		}
+		if payment.Overdue {
+			newReport[len(report)+i].Status = "逾期"
 		}
 
 	}
@@ -91,11 +148,44 @@ func sortReport(reports []Report) {
 
 }
 
+// 标记利率触及上下限的行
+func markRateLimits(loan Loan, reports []Report) {
+	for i := range reports {
+		reports[i].RateLimit = loan.RateLimitAt(reports[i].DueDate)
+	}
+}
+
 func CalculateTotalInterest(reports []Report) {
 	for i := 1; i < len(reports); i++ {
 
 		reports[i].TotalInterestPaid = reports[i-1].TotalInterestPaid.Add(reports[i].Interest)
+		reports[i].TotalFeePaid = reports[i-1].TotalFeePaid.Add(reports[i].Fee)
+
+	}
+}
 
+// ReportHeader 还款计划的表头
+var ReportHeader = []string{"序号", "期数", "明细", "日期", "本金", "利息", "违约金", "本月还款", "剩余本金", "利息合计", "APR", "利率限制", "罚息", "复利", "逾期未还", "状态"}
+
+// ReportRow 还款计划的一行,与 ReportHeader 对应
+func ReportRow(row Report) []string {
+	return []string{
+		strconv.Itoa(row.Index),
+		strconv.Itoa(row.LoanTerm),
+		row.Purpose,
+		row.DueDate.Format("2006-01-02"),
+		row.Principal.String(),
+		row.Interest.String(),
+		row.Fee.String(),
+		row.MonthTotalAmount.String(),
+		row.RemainingPrincipal.String(),
+		row.TotalInterestPaid.String(),
+		row.DueDateRate.String(),
+		row.RateLimit,
+		row.PenaltyInterest.String(),
+		row.CompoundInterest.String(),
+		row.Arrears.String(),
+		row.Status,
 	}
 }
 
@@ -106,22 +196,13 @@ func Report2table(reports []Report) string {
 	table := tablewriter.NewWriter(tableString)
 
 	for _, row := range reports {
-		table.Append([]string{
-			strconv.Itoa(row.Index),
-			strconv.Itoa(row.LoanTerm),
-			row.Purpose,
-			row.DueDate.Format("2006-01-02"),
-			row.Principal.String(),
-			row.Interest.String(),
-			row.MonthTotalAmount.String(),
-			row.RemainingPrincipal.String(),
-			row.TotalInterestPaid.String(),
-			row.DueDateRate.String(),
-		})
+		table.Append(ReportRow(row))
 	}
 	// 渲染表格到 buffer 中
 	// 设置表格内容，可以调用 table.SetHeader()、table.Append() 等方法
-	table.SetHeader([]string{"序号", "期数", "明细", "日期", "本金", "利息", "本月还款   ", "剩余本金", "利息合计", "APR"})
+	header := append([]string(nil), ReportHeader...)
+	header[7] = "本月还款   "
+	table.SetHeader(header)
 	table.SetAutoWrapText(true)
 	table.SetAutoFormatHeaders(true)
 	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
@@ -143,31 +224,61 @@ func Report2table(reports []Report) string {
 type Input struct {
 	Loan           Loan
 	EarlyRepayment []EarlyRepayment
+	ActualPayments []ActualPayment // 实际还款记录,为空时视为每期按时还款
+	PayoffDate     time.Time       // 结清日期,为空时按计划还完
 }
 
 func LoanPrintTable(inputdata Input, action string) string {
-	var payments []MonthlyPayment
+	report := BuildReport(inputdata, action)
+
+	// printReport(report)
+	p := Report2table(report)
+
+	return p
+}
+
+// BuildReport 按还款方式生成还款计划,合并放款,提前还款和利率调整记录
+func BuildReport(inputdata Input, action string) []Report {
+	payments := inputdata.monthlyPayments(action)
+	report := inputdata.schedule(payments)
+	if !inputdata.PayoffDate.IsZero() {
+		// 结清金额按完整的还款计划计算,与 PayoffQuote 一致
+		quote := inputdata.payoffQuote(inputdata.PayoffDate, payments, report)
+		report = payoff2Report(quote, report)
+	}
+	markRateLimits(inputdata.Loan, report)
+	CalculateTotalInterest(report)
 
+	return report
+}
+
+// 按还款方式计算每期还款,提前还款的本金和利息回写到 inputdata.EarlyRepayment
+func (inputdata Input) monthlyPayments(action string) []MonthlyPayment {
 	switch action {
 	case "epp":
 		// 计算等额本金还款计划
-		payments = inputdata.Loan.EqualPrincipalPayment(inputdata.EarlyRepayment)
-	default:
-		// 计算等额本息还款计划
-		payments = inputdata.Loan.EqualMonthlyInstallment(inputdata.EarlyRepayment)
-
+		return inputdata.Loan.EqualPrincipalPayment(inputdata.EarlyRepayment)
+	case "balloon":
+		// 计算带尾款的还款计划
+		return inputdata.Loan.BalloonPayment(inputdata.EarlyRepayment)
+	case "biweekly":
+		// 计算双周供还款计划
+		return inputdata.Loan.BiWeeklyPayment(inputdata.EarlyRepayment)
 	}
+	// 计算等额本息还款计划
+	return inputdata.Loan.EqualMonthlyInstallment(inputdata.EarlyRepayment)
+}
+
+// 按实际还款记录计算逾期,合并放款,提前还款和利率调整记录,不含结清
+func (inputdata Input) schedule(payments []MonthlyPayment) []Report {
+	payments = inputdata.Loan.ApplyActualPayments(payments, inputdata.ActualPayments)
 
 	// 整理数据
 	report := []Report{}
 	report = loan2Report(inputdata.Loan, report)
 	report = monthlyPayment2Report(payments, report)
 	report = earlyRepayment2Report(inputdata.EarlyRepayment, report)
+	report = spreadChange2Report(inputdata.Loan, payments, report)
 	sortReport(report)
-	CalculateTotalInterest(report)
-
-	// printReport(report)
-	p := Report2table(report)
-
-	return p
+	return report
 }
@@ -0,0 +1,78 @@
+package loan
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SpreadChange 加点调整,如2023年9月存量首套房贷利率调整
+// 银行在生效日调整加点,而不是在lpr变更日
+type SpreadChange struct {
+	Date       time.Time       // 生效日期
+	PlusSpread decimal.Decimal // 调整后的加点
+}
+
+// 获取指定日期执行的加点,取生效日在该日期或之前的最近一次调整
+func (loan *Loan) spreadAt(date time.Time) decimal.Decimal {
+	plusSpread := loan.PlusSpread
+	var effectiveDate time.Time
+	for _, change := range loan.SpreadChanges {
+		if !change.Date.After(date) && !change.Date.Before(effectiveDate) {
+			plusSpread = change.PlusSpread
+			effectiveDate = change.Date
+		}
+	}
+	return plusSpread
+}
+
+// 加点调整生效日是否在(上一个还款日,本期还款日]之间
+func (loan *Loan) spreadChangeBetween(previousDueDate, dueDate time.Time) (SpreadChange, bool) {
+	for _, change := range loan.SpreadChanges {
+		if change.Date.After(previousDueDate) && !change.Date.After(dueDate) {
+			return change, true
+		}
+	}
+	return SpreadChange{}, false
+}
+
+// 利率分段,按段起始日执行的年利率计息
+type rateSegment struct {
+	APR  decimal.Decimal // 年利率
+	days decimal.Decimal // 计息天数
+}
+
+// 将本期计息天数按利率变化日分段,from 为计息起始日,days 为本期计息天数
+// changes 为本期内的lpr重定价日,(from,dueDate]之间的加点调整生效日也作为分段点
+// 每段天数为起始日到下一个分段点的天数,按段起始日执行的利率计息
+// 最后一段为剩余天数,按本期执行的利率 APR 计息
+func (loan *Loan) rateSegments(from, dueDate time.Time, days, APR decimal.Decimal, changes ...time.Time) []rateSegment {
+	for _, change := range loan.SpreadChanges {
+		if change.Date.After(from) && !change.Date.After(dueDate) {
+			changes = append(changes, change.Date)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Before(changes[j]) })
+
+	segments := make([]rateSegment, 0, len(changes)+1)
+	start, used := from, decimal.Zero
+	for _, change := range changes {
+		segmentDays := loan.daysDiff(from, change).Sub(used)
+		segments = append(segments, rateSegment{APR: loan.aprAt(start), days: segmentDays})
+		start, used = change, used.Add(segmentDays)
+	}
+	return append(segments, rateSegment{APR: APR, days: days.Sub(used)})
+}
+
+// 分段利息之和,中间结果保留4位小数,最后保留2位
+func segmentedInterest(segments []rateSegment, interest func(APR, days decimal.Decimal) decimal.Decimal) decimal.Decimal {
+	total := decimal.Zero
+	for i, segment := range segments {
+		total = total.Add(interest(segment.APR, segment.days))
+		if i < len(segments)-1 {
+			total = total.Round(4)
+		}
+	}
+	return total.Round(2)
+}
@@ -0,0 +1,150 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSpreadChangeInterest(t *testing.T) {
+	// 2022-05-25 放款,每月18日还款,24期,每年5月25日lpr重定价,最后一期 2024-05-25
+	newLoan := func(change string) Loan {
+		return Loan{
+			InitialPrincipal: decimal.NewFromInt(1000000),
+			InitialTerm:      24,
+			InitialDate:      ParseDate("2022-05-25"),
+			LPR:              Lprs,
+			PlusSpread:       decimal.NewFromFloat(0.1),
+			PaymentDueDay:    18,
+			SpreadChanges:    []SpreadChange{{Date: ParseDate(change), PlusSpread: decimal.NewFromFloat(-0.3)}},
+		}
+	}
+	type segment struct {
+		from string // 分段起始日,按该日执行的利率
+		days int64
+	}
+	tests := []struct {
+		name     string
+		action   string
+		change   string
+		early    string // 提前还款日期,为空时不提前还款
+		dueDate  string // 加点调整所在期的还款日
+		segments []segment
+	}{
+		{
+			name: "emi lpr reset month", action: "emi", change: "2023-06-01", dueDate: "2023-06-18",
+			segments: []segment{{"2023-05-18", 7}, {"2023-05-25", 7}, {"2023-06-01", 16}},
+		},
+		{
+			name: "epp lpr reset month", action: "epp", change: "2023-06-01", dueDate: "2023-06-18",
+			segments: []segment{{"2023-05-18", 7}, {"2023-05-25", 7}, {"2023-06-01", 16}},
+		},
+		{
+			// 调整日在lpr变更日之前
+			name: "epp before lpr reset", action: "epp", change: "2023-05-20", dueDate: "2023-06-18",
+			segments: []segment{{"2023-05-18", 2}, {"2023-05-20", 5}, {"2023-05-25", 23}},
+		},
+		{
+			// 等额本息最后一期从最后还款日的前一个月起计息
+			name: "emi final term", action: "emi", change: "2024-05-10", dueDate: "2024-05-25",
+			segments: []segment{{"2024-04-25", 15}, {"2024-05-10", 15}},
+		},
+		{
+			name: "epp final term", action: "epp", change: "2024-05-10", dueDate: "2024-05-25",
+			segments: []segment{{"2024-04-18", 22}, {"2024-05-10", 15}},
+		},
+		{
+			name: "emi first term", action: "emi", change: "2022-06-10", dueDate: "2022-06-18",
+			segments: []segment{{"2022-05-26", 15}, {"2022-06-10", 8}},
+		},
+		{
+			// 提前还款之前的利息随提前还款归还,本期从提前还款日起分段
+			name: "epp prepayment in the same period", action: "epp", change: "2023-09-25", early: "2023-09-20", dueDate: "2023-10-18",
+			segments: []segment{{"2023-09-20", 5}, {"2023-09-25", 23}},
+		},
+		{
+			name: "emi prepayment in the same period", action: "emi", change: "2023-09-25", early: "2023-09-20", dueDate: "2023-10-18",
+			segments: []segment{{"2023-09-18", 7}, {"2023-09-25", 23}},
+		},
+		{
+			// 尾款和双周供同样按生效日分段
+			name: "balloon mid period", action: "balloon", change: "2023-03-05", dueDate: "2023-03-18",
+			segments: []segment{{"2023-02-18", 15}, {"2023-03-05", 15}},
+		},
+		{
+			name: "bi-weekly mid period", action: "biweekly", change: "2023-03-05", dueDate: "2023-03-15",
+			segments: []segment{{"2023-03-01", 4}, {"2023-03-05", 10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := newLoan(tt.change)
+			input := Input{Loan: loan}
+			if tt.early != "" {
+				input.EarlyRepayment = []EarlyRepayment{{Amount: decimal.NewFromInt(100000), Date: ParseDate(tt.early)}}
+			}
+			reports := BuildReport(input, tt.action)
+
+			// 本期计息本金为之前最近一条记录的剩余本金
+			principal := loan.InitialPrincipal
+			var row *Report
+			for i := range reports {
+				if reports[i].Purpose == "分期" && reports[i].DueDate.Equal(ParseDate(tt.dueDate)) {
+					row = &reports[i]
+					break
+				}
+				if reports[i].Purpose != "利率调整" {
+					principal = reports[i].RemainingPrincipal
+				}
+			}
+			if row == nil {
+				t.Fatalf("no installment on %s", tt.dueDate)
+			}
+
+			want := decimal.Zero
+			for _, s := range tt.segments {
+				want = want.Add(principal.Mul(loan.aprAt(ParseDate(s.from))).Div(decimal.NewFromInt(36000)).Mul(decimal.NewFromInt(s.days)))
+			}
+			if row.Interest.Sub(want).Abs().GreaterThan(decimal.NewFromFloat(0.02)) {
+				t.Errorf("interest = %s, want %s", row.Interest, want.Round(2))
+			}
+			// 执行利率为本期最后一段的利率
+			if rate := loan.aprAt(ParseDate(tt.segments[len(tt.segments)-1].from)); !row.DueDateRate.Equal(rate) {
+				t.Errorf("rate = %s, want %s", row.DueDateRate, rate)
+			}
+			last := reports[len(reports)-1]
+			if !last.RemainingPrincipal.IsZero() || last.DueDate.After(ParseDate("2024-05-25").Add(time.Hour)) {
+				t.Errorf("loan should be paid off on 2024-05-25, last row %s remaining %s", last.DueDate.Format(time.DateOnly), last.RemainingPrincipal)
+			}
+		})
+	}
+}
+
+func TestRateSegments(t *testing.T) {
+	loan := fixedLoan(12000, 12)
+	loan.FixedRate = decimal.Zero
+	loan.PlusSpread = decimal.NewFromFloat(0.5)
+	loan.SpreadChanges = []SpreadChange{
+		{Date: ParseDate("2023-03-25"), PlusSpread: decimal.NewFromFloat(0.2)},
+		{Date: ParseDate("2023-03-05"), PlusSpread: decimal.NewFromFloat(0.3)},
+	}
+	APR := loan.aprAt(ParseDate("2023-03-18"))
+	segments := loan.rateSegments(ParseDate("2023-02-18"), ParseDate("2023-03-18"), decimal.NewFromInt(30), APR)
+	want := []rateSegment{
+		{APR: loan.aprAt(ParseDate("2023-02-18")), days: decimal.NewFromInt(15)},
+		{APR: APR, days: decimal.NewFromInt(15)},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("segments = %+v, want %+v", segments, want)
+	}
+	for i := range want {
+		assertDecimal(t, "APR", segments[i].APR, want[i].APR)
+		assertDecimal(t, "days", segments[i].days, want[i].days)
+	}
+	// 没有变化时只有一段
+	segments = loan.rateSegments(ParseDate("2023-04-18"), ParseDate("2023-05-18"), decimal.NewFromInt(30), APR)
+	if len(segments) != 1 || !segments[0].days.Equal(decimal.NewFromInt(30)) {
+		t.Errorf("segments = %+v, want one segment of 30 days", segments)
+	}
+}
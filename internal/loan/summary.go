@@ -0,0 +1,114 @@
+package loan
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Summary 还款计划汇总
+type Summary struct {
+	TotalPrincipal     decimal.Decimal // 归还本金合计
+	TotalInterest      decimal.Decimal // 利息合计
+	TotalFee           decimal.Decimal // 违约金合计
+	TotalPenalty       decimal.Decimal // 罚息和复利合计
+	TotalPaid          decimal.Decimal // 还款总额
+	InterestSaved      decimal.Decimal // 提前还款节省的利息,对比不提前还款
+	PayoffDate         time.Time       // 结清日期
+	Periods            int             // 还款期数
+	AverageInstallment decimal.Decimal // 平均每期还款
+	MaxInstallment     decimal.Decimal // 最高每期还款
+	WeightedAverageAPR decimal.Decimal // 按剩余本金和天数加权的平均年利率
+	EffectiveRate      decimal.Decimal // 实际现金流的年化成本(XIRR)
+	EffectiveRateError string          // 无法计算XIRR的原因,不为空时 EffectiveRate 无效
+	NominalEAR         decimal.Decimal // 加权平均年利率按月复利折算的实际年利率
+}
+
+// Summarize 根据还款计划计算汇总,baseline 为不提前还款的还款计划,为空时不计算节省的利息
+func Summarize(reports []Report, baseline []Report) Summary {
+	summary := Summary{}
+	installments := decimal.Zero
+	weightedRate := decimal.Zero
+	weight := decimal.Zero
+
+	for i, report := range reports {
+		switch report.Purpose {
+		case "分期", "提前还款", "结清":
+			summary.TotalPrincipal = summary.TotalPrincipal.Add(report.Principal)
+			summary.TotalInterest = summary.TotalInterest.Add(report.Interest)
+			summary.TotalFee = summary.TotalFee.Add(report.Fee)
+			summary.TotalPenalty = summary.TotalPenalty.Add(report.PenaltyInterest).Add(report.CompoundInterest)
+			summary.TotalPaid = summary.TotalPaid.Add(report.paid())
+			if !report.IsEmptyEarlyRepayment() {
+				summary.PayoffDate = report.DueDate
+			}
+		}
+		if report.Purpose == "分期" {
+			summary.Periods++
+			installments = installments.Add(report.MonthTotalAmount)
+			if report.MonthTotalAmount.GreaterThan(summary.MaxInstallment) {
+				summary.MaxInstallment = report.MonthTotalAmount
+			}
+		}
+
+		// 加权平均利率,权重为上一条记录后的剩余本金*天数
+		// 利率调整记录之前的一段仍按原利率
+		if i > 0 {
+			previous := reports[i-1]
+			rate := report.DueDateRate
+			if report.Purpose == "利率调整" {
+				rate = previous.DueDateRate
+			}
+			days := decimal.NewFromFloat(report.DueDate.Sub(previous.DueDate).Hours() / 24)
+			w := previous.RemainingPrincipal.Mul(days)
+			if w.IsPositive() {
+				weightedRate = weightedRate.Add(w.Mul(rate))
+				weight = weight.Add(w)
+			}
+		}
+	}
+
+	if summary.Periods > 0 {
+		summary.AverageInstallment = installments.Div(decimal.NewFromInt(int64(summary.Periods))).Round(2)
+	}
+	if weight.IsPositive() {
+		summary.WeightedAverageAPR = weightedRate.Div(weight).Round(4)
+	}
+	summary.NominalEAR = NominalEAR(summary.WeightedAverageAPR)
+	// 现金流不足或无法求解时记录原因,不显示为0%
+	if rate, err := EffectiveAnnualRate(reports); err != nil {
+		summary.EffectiveRateError = err.Error()
+	} else {
+		summary.EffectiveRate = rate
+	}
+	if baseline != nil {
+		summary.InterestSaved = totalInterest(baseline).Sub(summary.TotalInterest)
+	}
+	return summary
+}
+
+// 利息合计
+func totalInterest(reports []Report) decimal.Decimal {
+	total := decimal.Zero
+	for _, report := range reports {
+		switch report.Purpose {
+		case "分期", "提前还款", "结清":
+			total = total.Add(report.Interest)
+		}
+	}
+	return total
+}
+
+// BaselineReport 不提前还款的还款计划,作为节省利息的对比基准
+// 同一笔贷款多次汇总时(如规划提前还款)只需生成一次,再传给 Summarize
+func BaselineReport(inputdata Input, action string) []Report {
+	baselineInput := inputdata
+	baselineInput.EarlyRepayment = nil
+	baselineInput.PayoffDate = time.Time{}
+	return BuildReport(baselineInput, action)
+}
+
+// BuildSummary 生成还款计划汇总,并和不提前还款的还款计划对比
+func BuildSummary(inputdata Input, action string, reports []Report) Summary {
+	return Summarize(reports, BaselineReport(inputdata, action))
+}
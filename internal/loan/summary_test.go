@@ -0,0 +1,71 @@
+package loan
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		early   []EarlyRepayment
+		payoff  string
+		saved   bool // 是否节省利息
+		periods int
+	}{
+		{name: "emi without prepayment", action: "emi", periods: 12},
+		{name: "epp without prepayment", action: "epp", periods: 12},
+		{
+			name:    "reduce installment",
+			action:  "emi",
+			early:   []EarlyRepayment{{Amount: decimal.NewFromInt(3000), Date: ParseDate("2023-05-20")}},
+			saved:   true,
+			periods: 12,
+		},
+		{
+			name:    "keep installment",
+			action:  "epp",
+			early:   []EarlyRepayment{{Amount: decimal.NewFromInt(3000), Date: ParseDate("2023-05-20"), KeepInstallment: true}},
+			saved:   true,
+			periods: 10,
+		},
+		{name: "payoff", action: "emi", payoff: "2023-07-01", saved: true, periods: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := Input{Loan: fixedLoan(12000, 12), EarlyRepayment: tt.early}
+			if tt.payoff != "" {
+				input.PayoffDate = ParseDate(tt.payoff)
+			}
+			reports := BuildReport(input, tt.action)
+			baseline := BaselineReport(input, tt.action)
+			summary := Summarize(reports, baseline)
+
+			if got := BuildSummary(input, tt.action, reports); fmt.Sprint(got) != fmt.Sprint(summary) {
+				t.Errorf("BuildSummary = %+v, want %+v", got, summary)
+			}
+			// 对比基准不含提前还款和结清
+			assertDecimal(t, "interest saved", summary.InterestSaved, totalInterest(baseline).Sub(summary.TotalInterest))
+			if tt.saved != summary.InterestSaved.IsPositive() {
+				t.Errorf("interest saved = %s, want positive %v", summary.InterestSaved, tt.saved)
+			}
+			if !tt.saved && !summary.InterestSaved.IsZero() {
+				t.Errorf("interest saved = %s, want 0", summary.InterestSaved)
+			}
+			assertDecimal(t, "total principal", summary.TotalPrincipal.Round(0), decimal.NewFromInt(12000))
+			assertDecimal(t, "total paid", summary.TotalPaid,
+				summary.TotalPrincipal.Add(summary.TotalInterest).Add(summary.TotalFee).Add(summary.TotalPenalty))
+			if summary.Periods != tt.periods {
+				t.Errorf("periods = %d, want %d", summary.Periods, tt.periods)
+			}
+			if !summary.PayoffDate.Equal(reports[len(reports)-1].DueDate) {
+				t.Errorf("payoff date = %s, want %s", summary.PayoffDate.Format(time.DateOnly), reports[len(reports)-1].DueDate.Format(time.DateOnly))
+			}
+		})
+	}
+}
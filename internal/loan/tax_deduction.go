@@ -0,0 +1,144 @@
+package loan
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 个税住房贷款利息专项附加扣除
+// 首套住房贷款从合同约定开始还款的当月起,至全部归还的当月止,每月定额扣除,最长不超过240个月
+const (
+	TaxDeductionMonthly   = 1000 // 每月扣除标准
+	TaxDeductionMaxMonths = 240  // 最长扣除月数
+)
+
+// TaxDeductionParams 扣除方式和边际税率,税率以%表示
+type TaxDeductionParams struct {
+	SpouseSplit        bool            // 夫妻各扣除50%,否则由本人扣除100%
+	MarginalRate       decimal.Decimal // 本人边际税率
+	SpouseMarginalRate decimal.Decimal // 配偶边际税率
+}
+
+// TaxDeductionYear 每个纳税年度的扣除
+type TaxDeductionYear struct {
+	Year            int
+	Months          int             // 可扣除月数
+	InterestPaid    decimal.Decimal // 可扣除月份支付的利息
+	SelfDeduction   decimal.Decimal // 本人扣除额
+	SpouseDeduction decimal.Decimal // 配偶扣除额
+	Deduction       decimal.Decimal // 扣除合计
+	TaxSaving       decimal.Decimal // 按边际税率估算的节税
+}
+
+// TaxDeduction 住房贷款利息扣除汇总
+type TaxDeduction struct {
+	StartMonth    time.Time // 开始扣除的月份
+	EndMonth      time.Time // 最后扣除的月份
+	Months        int
+	Deduction     decimal.Decimal
+	TaxSaving     decimal.Decimal
+	Years         []TaxDeductionYear
+	SpouseSplit   bool
+	SelfMonthly   decimal.Decimal // 本人每月扣除额
+	SpouseMonthly decimal.Decimal // 配偶每月扣除额
+}
+
+// BuildTaxDeduction 根据还款计划按纳税年度计算可扣除月数,扣除额和节税
+// 1.第一期分期的月份开始扣除,结清的月份为最后一个月,最多240个月
+// 2.扣除额与实际利息无关,利息只用于参考
+func BuildTaxDeduction(reports []Report, params TaxDeductionParams) (TaxDeduction, error) {
+	var start, end time.Time
+	interests := make(map[time.Time]decimal.Decimal) // 按月份的利息
+	for _, report := range reports {
+		switch report.Purpose {
+		case "分期", "提前还款", "结清":
+			if report.IsEmptyEarlyRepayment() {
+				continue
+			}
+			if start.IsZero() && report.Purpose == "分期" {
+				start = report.DueDate
+			}
+			end = report.DueDate
+			month := time.Date(report.DueDate.Year(), report.DueDate.Month(), 1, 0, 0, 0, 0, report.DueDate.Location())
+			interests[month] = interests[month].Add(report.Interest)
+		}
+	}
+	if start.IsZero() {
+		return TaxDeduction{}, errors.New("no installment in the schedule")
+	}
+
+	self := decimal.NewFromInt(TaxDeductionMonthly)
+	spouse := decimal.Zero
+	if params.SpouseSplit {
+		self = self.Div(decimal.NewFromInt(2))
+		spouse = self
+	}
+
+	result := TaxDeduction{
+		StartMonth:    time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location()),
+		SpouseSplit:   params.SpouseSplit,
+		SelfMonthly:   self,
+		SpouseMonthly: spouse,
+	}
+	hundred := decimal.NewFromInt(100)
+	month := result.StartMonth
+	for ; !month.After(end) && result.Months < TaxDeductionMaxMonths; month = month.AddDate(0, 1, 0) {
+		if len(result.Years) == 0 || result.Years[len(result.Years)-1].Year != month.Year() {
+			result.Years = append(result.Years, TaxDeductionYear{Year: month.Year()})
+		}
+		year := &result.Years[len(result.Years)-1]
+		year.Months++
+		year.InterestPaid = year.InterestPaid.Add(interests[month])
+		year.SelfDeduction = year.SelfDeduction.Add(self)
+		year.SpouseDeduction = year.SpouseDeduction.Add(spouse)
+		result.Months++
+		result.EndMonth = month
+	}
+	for i := range result.Years {
+		year := &result.Years[i]
+		year.Deduction = year.SelfDeduction.Add(year.SpouseDeduction)
+		year.TaxSaving = year.SelfDeduction.Mul(params.MarginalRate).Add(year.SpouseDeduction.Mul(params.SpouseMarginalRate)).Div(hundred).Round(2)
+		result.Deduction = result.Deduction.Add(year.Deduction)
+		result.TaxSaving = result.TaxSaving.Add(year.TaxSaving)
+	}
+	return result, nil
+}
+
+// WriteTaxDeductionCSV 按纳税年度输出csv,最后一行为合计
+func WriteTaxDeductionCSV(w io.Writer, deduction TaxDeduction) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{{"纳税年度", "扣除月数", "支付利息", "本人扣除", "配偶扣除", "扣除合计", "节税"}}
+	interests, selfs, spouses := decimal.Zero, decimal.Zero, decimal.Zero
+	for _, year := range deduction.Years {
+		rows = append(rows, []string{
+			strconv.Itoa(year.Year),
+			strconv.Itoa(year.Months),
+			year.InterestPaid.StringFixed(2),
+			year.SelfDeduction.StringFixed(2),
+			year.SpouseDeduction.StringFixed(2),
+			year.Deduction.StringFixed(2),
+			year.TaxSaving.StringFixed(2),
+		})
+		interests = interests.Add(year.InterestPaid)
+		selfs = selfs.Add(year.SelfDeduction)
+		spouses = spouses.Add(year.SpouseDeduction)
+	}
+	rows = append(rows, []string{
+		"合计",
+		strconv.Itoa(deduction.Months),
+		interests.StringFixed(2),
+		selfs.StringFixed(2),
+		spouses.StringFixed(2),
+		deduction.Deduction.StringFixed(2),
+		deduction.TaxSaving.StringFixed(2),
+	})
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
@@ -0,0 +1,141 @@
+package loan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildTaxDeduction(t *testing.T) {
+	// 第一期 2023-02-18
+	payoff := Input{Loan: fixedLoan(120000, 24), PayoffDate: ParseDate("2023-06-25")}
+	tests := []struct {
+		name      string
+		input     Input
+		params    TaxDeductionParams
+		months    int
+		end       string      // 最后扣除的月份
+		years     map[int]int // 每年的扣除月数
+		deduction int64       // 扣除合计
+		saving    string      // 节税合计
+		first     [2]int64    // 第一年本人和配偶的扣除额
+	}{
+		{
+			name:      "self",
+			input:     Input{Loan: fixedLoan(120000, 24)},
+			params:    TaxDeductionParams{MarginalRate: decimal.NewFromInt(20)},
+			months:    24,
+			end:       "2025-01",
+			years:     map[int]int{2023: 11, 2024: 12, 2025: 1},
+			deduction: 24000,
+			saving:    "4800",
+			first:     [2]int64{11000, 0},
+		},
+		{
+			// 夫妻各扣除50%,按各自的边际税率节税
+			name:      "spouse split",
+			input:     Input{Loan: fixedLoan(120000, 24)},
+			params:    TaxDeductionParams{SpouseSplit: true, MarginalRate: decimal.NewFromInt(20), SpouseMarginalRate: decimal.NewFromInt(10)},
+			months:    24,
+			end:       "2025-01",
+			years:     map[int]int{2023: 11, 2024: 12, 2025: 1},
+			deduction: 24000,
+			saving:    "3600",
+			first:     [2]int64{5500, 5500},
+		},
+		{
+			// 最长扣除240个月
+			name:      "capped at 240 months",
+			input:     Input{Loan: fixedLoan(1000000, 360)},
+			params:    TaxDeductionParams{MarginalRate: decimal.NewFromInt(10)},
+			months:    240,
+			end:       "2043-01",
+			deduction: 240000,
+			saving:    "24000",
+			first:     [2]int64{11000, 0},
+		},
+		{
+			// 结清的月份为最后一个月
+			name:      "paid off",
+			input:     payoff,
+			params:    TaxDeductionParams{MarginalRate: decimal.NewFromInt(3)},
+			months:    5,
+			end:       "2023-06",
+			years:     map[int]int{2023: 5},
+			deduction: 5000,
+			saving:    "150",
+			first:     [2]int64{5000, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := BuildReport(tt.input, "emi")
+			deduction, err := BuildTaxDeduction(reports, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deduction.Months != tt.months || deduction.EndMonth.Format("2006-01") != tt.end {
+				t.Errorf("%d months until %s, want %d until %s", deduction.Months, deduction.EndMonth.Format("2006-01"), tt.months, tt.end)
+			}
+			if deduction.StartMonth.Format("2006-01") != "2023-02" {
+				t.Errorf("start month = %s, want 2023-02", deduction.StartMonth.Format("2006-01"))
+			}
+			assertDecimal(t, "deduction", deduction.Deduction, decimal.NewFromInt(tt.deduction))
+			assertDecimal(t, "tax saving", deduction.TaxSaving, decimal.RequireFromString(tt.saving))
+			assertDecimal(t, "first year self", deduction.Years[0].SelfDeduction, decimal.NewFromInt(tt.first[0]))
+			assertDecimal(t, "first year spouse", deduction.Years[0].SpouseDeduction, decimal.NewFromInt(tt.first[1]))
+			for year, months := range tt.years {
+				found := false
+				for _, y := range deduction.Years {
+					if y.Year == year {
+						found = y.Months == months
+					}
+				}
+				if !found {
+					t.Errorf("%d should have %d months", year, months)
+				}
+			}
+
+			// 扣除期间支付的利息
+			interest := decimal.Zero
+			end := deduction.EndMonth.AddDate(0, 1, 0)
+			for _, report := range reports {
+				if report.Purpose != "贷款发放" && report.DueDate.Before(end) {
+					interest = interest.Add(report.Interest)
+				}
+			}
+			paid := decimal.Zero
+			for _, year := range deduction.Years {
+				paid = paid.Add(year.InterestPaid)
+			}
+			assertDecimal(t, "interest paid", paid, interest)
+		})
+	}
+
+	if _, err := BuildTaxDeduction(BuildReport(Input{Loan: fixedLoan(12000, 12)}, "emi")[:1], TaxDeductionParams{}); err == nil {
+		t.Error("expected an error without installments")
+	}
+}
+
+func TestWriteTaxDeductionCSV(t *testing.T) {
+	deduction, err := BuildTaxDeduction(BuildReport(Input{Loan: fixedLoan(120000, 24)}, "emi"), TaxDeductionParams{MarginalRate: decimal.NewFromInt(20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := WriteTaxDeductionCSV(&b, deduction); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	// 表头,3个纳税年度和合计
+	if len(lines) != 5 {
+		t.Fatalf("%d lines, want 5:\n%s", len(lines), b.String())
+	}
+	if !strings.HasPrefix(lines[1], "2023,11,") {
+		t.Errorf("first year = %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[4], "合计,24,") || !strings.HasSuffix(lines[4], ",24000.00,4800.00") {
+		t.Errorf("total = %q", lines[4])
+	}
+}
@@ -0,0 +1,196 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 常用纸张尺寸,单位为点(1/72英寸)
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Document 一个pdf文档,所有文字使用同一个字体
+type Document struct {
+	font  Font
+	used  map[rune]bool // 用到的字符,写入字体宽度和文字映射
+	pages []*Page
+}
+
+// Page 一页的内容,坐标原点在左上角,y 向下,写入时转换为pdf坐标
+type Page struct {
+	Width   float64
+	Height  float64
+	doc     *Document
+	content bytes.Buffer
+}
+
+// New 使用指定字体创建文档
+func New(font Font) *Document {
+	return &Document{font: font, used: make(map[rune]bool)}
+}
+
+// AddPage 添加一页
+func (d *Document) AddPage(width, height float64) *Page {
+	page := &Page{Width: width, Height: height, doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// TextWidth 文字在指定字号下的宽度
+func (d *Document) TextWidth(size float64, text string) float64 {
+	width := 0.0
+	for _, r := range text {
+		width += d.font.width(r)
+	}
+	return width * size / 1000
+}
+
+// 颜色 #rrggbb 或 #rgb 转换为pdf的 r g b,解析失败时为黑色
+func rgb(color string) string {
+	if len(color) == 4 && color[0] == '#' {
+		color = string([]byte{'#', color[1], color[1], color[2], color[2], color[3], color[3]})
+	}
+	if len(color) != 7 || color[0] != '#' {
+		return "0 0 0"
+	}
+	parts := make([]string, 0, 3)
+	for i := 1; i < 7; i += 2 {
+		v, err := strconv.ParseUint(color[i:i+2], 16, 8)
+		if err != nil {
+			return "0 0 0"
+		}
+		parts = append(parts, strconv.FormatFloat(float64(v)/255, 'f', 3, 64))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (p *Page) y(y float64) float64 {
+	return p.Height - y
+}
+
+// Text 在基线 (x, y) 处左对齐输出文字,bold 时描边加粗
+func (p *Page) Text(x, y, size float64, color string, bold bool, text string) {
+	if text == "" {
+		return
+	}
+	for _, r := range text {
+		p.doc.used[r] = true
+	}
+	mode := "0 Tr"
+	if bold {
+		mode = fmt.Sprintf("2 Tr %.2f w %s RG", size/30, rgb(color))
+	}
+	fmt.Fprintf(&p.content, "BT /F1 %.2f Tf %s %s rg %.2f %.2f Td <%s> Tj ET\n",
+		size, mode, rgb(color), x, p.y(y), p.doc.font.encode(text))
+}
+
+// TextRight 右对齐,x 为右边界
+func (p *Page) TextRight(x, y, size float64, color string, bold bool, text string) {
+	p.Text(x-p.doc.TextWidth(size, text), y, size, color, bold, text)
+}
+
+// TextCenter 居中,x 为中心
+func (p *Page) TextCenter(x, y, size float64, color string, bold bool, text string) {
+	p.Text(x-p.doc.TextWidth(size, text)/2, y, size, color, bold, text)
+}
+
+// Line 直线
+func (p *Page) Line(x1, y1, x2, y2, width float64, color string, dashed bool) {
+	dash := "[] 0 d"
+	if dashed {
+		dash = "[4 3] 0 d"
+	}
+	fmt.Fprintf(&p.content, "%s %.2f w %s RG %.2f %.2f m %.2f %.2f l S\n", dash, width, rgb(color), x1, p.y(y1), x2, p.y(y2))
+}
+
+// Polyline 折线
+func (p *Page) Polyline(points [][2]float64, width float64, color string) {
+	if len(points) < 2 {
+		return
+	}
+	fmt.Fprintf(&p.content, "[] 0 d %.2f w %s RG %.2f %.2f m", width, rgb(color), points[0][0], p.y(points[0][1]))
+	for _, point := range points[1:] {
+		fmt.Fprintf(&p.content, " %.2f %.2f l", point[0], p.y(point[1]))
+	}
+	p.content.WriteString(" S\n")
+}
+
+// Rect 填充矩形,(x, y) 为左上角
+func (p *Page) Rect(x, y, w, h float64, color string) {
+	fmt.Fprintf(&p.content, "%s rg %.2f %.2f %.2f %.2f re f\n", rgb(color), x, p.y(y+h), w, h)
+}
+
+// Circle 填充圆形,用4段贝塞尔曲线近似
+func (p *Page) Circle(x, y, r float64, color string) {
+	k := r * 4 * (math.Sqrt2 - 1) / 3
+	cy := p.y(y)
+	fmt.Fprintf(&p.content, "%s rg %.2f %.2f m", rgb(color), x+r, cy)
+	fmt.Fprintf(&p.content, " %.2f %.2f %.2f %.2f %.2f %.2f c", x+r, cy+k, x+k, cy+r, x, cy+r)
+	fmt.Fprintf(&p.content, " %.2f %.2f %.2f %.2f %.2f %.2f c", x-k, cy+r, x-r, cy+k, x-r, cy)
+	fmt.Fprintf(&p.content, " %.2f %.2f %.2f %.2f %.2f %.2f c", x-r, cy-k, x-k, cy-r, x, cy-r)
+	fmt.Fprintf(&p.content, " %.2f %.2f %.2f %.2f %.2f %.2f c f\n", x+k, cy-r, x+r, cy-k, x+r, cy)
+}
+
+// 按对象编号记录偏移量,最后生成交叉引用表
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// 预留一个对象编号
+func (w *writer) alloc() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+func (w *writer) object(n int, body string) {
+	w.offsets[n-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+// 压缩后写入流对象,dict 为除 Length 和 Filter 之外的字典项
+func (w *writer) stream(n int, dict string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+	w.offsets[n-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d /Filter /FlateDecode >>\nstream\n", n, dict, compressed.Len())
+	w.buf.Write(compressed.Bytes())
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// Write 输出pdf
+func (d *Document) Write(out io.Writer) error {
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	catalog, pages := w.alloc(), w.alloc()
+	font := d.font.write(w, d.used)
+
+	kids := make([]string, 0, len(d.pages))
+	for _, page := range d.pages {
+		pageObj, contentObj := w.alloc(), w.alloc()
+		w.stream(contentObj, "", page.content.Bytes())
+		w.object(pageObj, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pages, page.Width, page.Height, font, contentObj))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObj))
+	}
+	w.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, catalog, xref)
+	_, err := out.Write(w.buf.Bytes())
+	return err
+}
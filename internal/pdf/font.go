@@ -0,0 +1,475 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Font 文档使用的字体,字体本身不记录状态,可以在多个文档之间共用
+type Font interface {
+	// 文字转换为 Tj 使用的十六进制字符串
+	encode(text string) string
+	// 字符宽度,单位为 1/1000 em
+	width(r rune) float64
+	// 写入字体对象,返回字体字典的对象编号
+	write(w *writer, used map[rune]bool) int
+}
+
+// 文字按 UTF-16 大端输出为十六进制,用于 ToUnicode
+func utf16Hex(text string) string {
+	units := utf16.Encode([]rune(text))
+	b := make([]byte, len(units)*2)
+	for i, unit := range units {
+		binary.BigEndian.PutUint16(b[i*2:], unit)
+	}
+	return hex.EncodeToString(b)
+}
+
+// TrueType 嵌入的 TrueType 字体,支持 ttf 和 ttc(取第一个字体)
+// 只嵌入文档用到的字形(子集),文字按字形编号(Identity-H)输出,并写入 ToUnicode 以便复制和搜索
+type TrueType struct {
+	name       string
+	tables     map[string][]byte // 子集化需要的表
+	loca       []uint32          // 每个字形在 glyf 中的起止位置,共 numGlyphs+1 项
+	glyphs     map[rune]uint16   // 字符到字形编号
+	advances   []uint16          // 字形宽度,单位为 unitsPerEm
+	unitsPerEm uint16
+	bbox       [4]int16
+	ascent     int16
+	descent    int16
+}
+
+// 嵌入的子集保留的表,cvt,fpgm,prep 为可选的字形微调指令
+var subsetTables = []string{"head", "hhea", "hmtx", "maxp", "glyf", "cvt ", "fpgm", "prep"}
+
+// LoadTrueType 读取字体文件,不支持 CFF 轮廓(OTTO)的字体
+func LoadTrueType(path string) (*TrueType, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := sfntTables(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "cmap", "glyf", "loca"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, errors.New("font: missing table " + tag)
+		}
+	}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("font: invalid table")
+	}
+	font := &TrueType{
+		name:       fontName(path),
+		tables:     make(map[string][]byte),
+		unitsPerEm: binary.BigEndian.Uint16(head[18:]),
+		ascent:     int16(binary.BigEndian.Uint16(hhea[4:])),
+		descent:    int16(binary.BigEndian.Uint16(hhea[6:])),
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int16(binary.BigEndian.Uint16(head[36+i*2:]))
+	}
+	if font.unitsPerEm == 0 {
+		return nil, errors.New("font: invalid unitsPerEm")
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := tables["hmtx"]
+	if numHMetrics == 0 || len(hmtx) < numHMetrics*4 {
+		return nil, errors.New("font: invalid hmtx")
+	}
+	font.advances = make([]uint16, numGlyphs)
+	for i := range font.advances {
+		if i < numHMetrics {
+			font.advances[i] = binary.BigEndian.Uint16(hmtx[i*4:])
+		} else {
+			font.advances[i] = font.advances[numHMetrics-1]
+		}
+	}
+
+	for _, tag := range subsetTables {
+		if table, ok := tables[tag]; ok {
+			font.tables[tag] = table
+		}
+	}
+	if font.loca, err = parseLoca(tables["loca"], int(int16(binary.BigEndian.Uint16(head[50:]))), numGlyphs, len(tables["glyf"])); err != nil {
+		return nil, err
+	}
+	font.glyphs, err = parseCmap(tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	return font, nil
+}
+
+// Covers 字体是否包含文字中的所有字符
+func (f *TrueType) Covers(text string) bool {
+	for _, r := range text {
+		if _, ok := f.glyphs[r]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// 解析 loca,format 0 为 uint16 且按2字节计,format 1 为 uint32
+func parseLoca(loca []byte, format, numGlyphs, glyfLength int) ([]uint32, error) {
+	offsets := make([]uint32, numGlyphs+1)
+	for i := range offsets {
+		switch {
+		case format == 0 && len(loca) >= (i+1)*2:
+			offsets[i] = uint32(binary.BigEndian.Uint16(loca[i*2:])) * 2
+		case format == 1 && len(loca) >= (i+1)*4:
+			offsets[i] = binary.BigEndian.Uint32(loca[i*4:])
+		default:
+			return nil, errors.New("font: invalid loca")
+		}
+		if offsets[i] > uint32(glyfLength) || (i > 0 && offsets[i] < offsets[i-1]) {
+			return nil, errors.New("font: invalid loca")
+		}
+	}
+	return offsets, nil
+}
+
+// 字形数据,空字形返回 nil
+func (f *TrueType) glyph(id uint16) []byte {
+	if int(id)+1 >= len(f.loca) {
+		return nil
+	}
+	return f.tables["glyf"][f.loca[id]:f.loca[id+1]]
+}
+
+// 复合字形引用的部件字形
+func components(glyph []byte) []uint16 {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+	result := make([]uint16, 0, 2)
+	for offset := 10; offset+4 <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[offset:])
+		result = append(result, binary.BigEndian.Uint16(glyph[offset+2:]))
+		offset += 4
+		if flags&0x0001 != 0 { // ARG_1_AND_2_ARE_WORDS
+			offset += 4
+		} else {
+			offset += 2
+		}
+		switch {
+		case flags&0x0008 != 0: // WE_HAVE_A_SCALE
+			offset += 2
+		case flags&0x0040 != 0: // WE_HAVE_AN_X_AND_Y_SCALE
+			offset += 4
+		case flags&0x0080 != 0: // WE_HAVE_A_TWO_BY_TWO
+			offset += 8
+		}
+		if flags&0x0020 == 0 { // MORE_COMPONENTS
+			break
+		}
+	}
+	return result
+}
+
+// 生成只含指定字形的字体文件
+// 字形编号保持不变(CIDToGIDMap 为 Identity),未用到的字形在 glyf 中为空,loca 改为 uint32 格式
+// 0号字形(.notdef)和复合字形的部件一并保留;不再需要 cmap,name 等表
+func (f *TrueType) subset(ids []uint16) []byte {
+	keep := map[uint16]bool{0: true}
+	queue := append([]uint16{0}, ids...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		keep[id] = true
+		for _, component := range components(f.glyph(id)) {
+			if !keep[component] {
+				keep[component] = true
+				queue = append(queue, component)
+			}
+		}
+	}
+
+	numGlyphs := len(f.loca) - 1
+	glyf := make([]byte, 0)
+	loca := make([]byte, (numGlyphs+1)*4)
+	for id := 0; id < numGlyphs; id++ {
+		binary.BigEndian.PutUint32(loca[id*4:], uint32(len(glyf)))
+		if keep[uint16(id)] {
+			glyf = append(glyf, f.glyph(uint16(id))...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[numGlyphs*4:], uint32(len(glyf)))
+
+	tables := make(map[string][]byte, len(f.tables)+1)
+	for tag, table := range f.tables {
+		tables[tag] = table
+	}
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat
+	tables["head"], tables["glyf"], tables["loca"] = head, glyf, loca
+	return buildSfnt(tables)
+}
+
+// 子集字体名称的前缀,6个大写字母,按用到的字形生成
+func subsetTag(ids []uint16) string {
+	hash := fnv.New32a()
+	for _, id := range ids {
+		binary.Write(hash, binary.BigEndian, id)
+	}
+	sum := hash.Sum32()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = byte('A' + sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
+
+// 字体名称取文件名中的字母和数字
+func fontName(path string) string {
+	name := regexp.MustCompile(`[^A-Za-z0-9]`).ReplaceAllString(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "")
+	if name == "" {
+		return "EmbeddedFont"
+	}
+	return name
+}
+
+// 读取表目录,ttc 取第一个字体
+func sfntTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("font: file too short")
+	}
+	offset := 0
+	switch string(data[:4]) {
+	case "ttcf":
+		if len(data) < 16 {
+			return nil, errors.New("font: file too short")
+		}
+		offset = int(binary.BigEndian.Uint32(data[12:]))
+	case "OTTO":
+		return nil, errors.New("font: CFF outlines are not supported, use a TrueType font")
+	}
+	if offset+12 > len(data) {
+		return nil, errors.New("font: invalid offset table")
+	}
+	numTables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		record := offset + 12 + i*16
+		if record+16 > len(data) {
+			return nil, errors.New("font: invalid table record")
+		}
+		start := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if start+length > len(data) {
+			return nil, errors.New("font: table out of range")
+		}
+		tables[string(data[record:record+4])] = data[start : start+length]
+	}
+	return tables, nil
+}
+
+// 按表重新组装为独立的 ttf,表按4字节对齐,重新计算 head 中的 checkSumAdjustment
+func buildSfnt(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	header := 12 + 16*len(tags)
+	out := make([]byte, header)
+	binary.BigEndian.PutUint32(out, 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(len(tags)))
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= len(tags) {
+		entrySelector++
+	}
+	binary.BigEndian.PutUint16(out[6:], uint16(16<<entrySelector))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(16*len(tags)-16<<entrySelector))
+	head := -1
+	for i, tag := range tags {
+		table := tables[tag]
+		if tag == "head" && len(table) >= 12 {
+			table = append([]byte(nil), table...)
+			binary.BigEndian.PutUint32(table[8:], 0)
+			head = len(out)
+		}
+		record := out[12+i*16:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], checksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	if head >= 0 {
+		binary.BigEndian.PutUint32(out[head+8:], 0xB1B0AFBA-checksum(out))
+	}
+	return out
+}
+
+func checksum(table []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(table); i += 4 {
+		var word [4]byte
+		copy(word[:], table[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// 解析 cmap,优先使用 Unicode 完整字符集(format 12),其次是基本多文种平面(format 4)
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errors.New("font: invalid cmap")
+	}
+	var format4, format12 []byte
+	for i := 0; i < int(binary.BigEndian.Uint16(cmap[2:])); i++ {
+		record := 4 + i*8
+		if record+8 > len(cmap) {
+			break
+		}
+		platform, encoding := binary.BigEndian.Uint16(cmap[record:]), binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+4 > len(cmap) || !(platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))) {
+			continue
+		}
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+
+	glyphs := make(map[rune]uint16)
+	switch {
+	case len(format12) >= 16:
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		for i := 0; i < groups && 16+i*12+12 <= len(format12); i++ {
+			group := format12[16+i*12:]
+			start, end, glyph := binary.BigEndian.Uint32(group), binary.BigEndian.Uint32(group[4:]), binary.BigEndian.Uint32(group[8:])
+			for c := start; c <= end && c <= 0x10ffff; c++ {
+				glyphs[rune(c)] = uint16(glyph + c - start)
+			}
+		}
+	case len(format4) >= 14:
+		segments := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		if len(format4) < 16+segments*8 {
+			return nil, errors.New("font: invalid cmap format 4")
+		}
+		ends, starts := format4[14:], format4[16+segments*2:]
+		deltas, rangeOffsets := format4[16+segments*4:], format4[16+segments*6:]
+		for s := 0; s < segments; s++ {
+			start, end := binary.BigEndian.Uint16(starts[s*2:]), binary.BigEndian.Uint16(ends[s*2:])
+			delta, rangeOffset := binary.BigEndian.Uint16(deltas[s*2:]), int(binary.BigEndian.Uint16(rangeOffsets[s*2:]))
+			for c := int(start); c <= int(end) && c != 0xffff; c++ {
+				glyph := uint16(c) + delta
+				if rangeOffset != 0 {
+					index := 16 + segments*6 + s*2 + rangeOffset + (c-int(start))*2
+					if index+2 > len(format4) {
+						continue
+					}
+					if glyph = binary.BigEndian.Uint16(format4[index:]); glyph != 0 {
+						glyph += delta
+					}
+				}
+				if glyph != 0 {
+					glyphs[rune(c)] = glyph
+				}
+			}
+		}
+	default:
+		return nil, errors.New("font: no unicode cmap")
+	}
+	return glyphs, nil
+}
+
+func (f *TrueType) encode(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		fmt.Fprintf(&b, "%04x", f.glyphs[r])
+	}
+	return b.String()
+}
+
+func (f *TrueType) width(r rune) float64 {
+	glyph := f.glyphs[r]
+	if int(glyph) >= len(f.advances) {
+		return 0
+	}
+	return f.scale(int(f.advances[glyph]))
+}
+
+// 字体单位换算为 1/1000 em
+func (f *TrueType) scale(v int) float64 {
+	return float64(v) * 1000 / float64(f.unitsPerEm)
+}
+
+func (f *TrueType) write(w *writer, used map[rune]bool) int {
+	font, cidFont, descriptor, file, toUnicode := w.alloc(), w.alloc(), w.alloc(), w.alloc(), w.alloc()
+
+	// 只写入用到的字形的宽度和文字映射
+	runes := make([]rune, 0, len(used))
+	for r := range used {
+		if _, ok := f.glyphs[r]; ok {
+			runes = append(runes, r)
+		}
+	}
+	sort.Slice(runes, func(i, j int) bool { return f.glyphs[runes[i]] < f.glyphs[runes[j]] })
+	ids := make([]uint16, 0, len(runes))
+	widths := make([]string, 0, len(runes))
+	mappings := make([]string, 0, len(runes))
+	for _, r := range runes {
+		glyph := f.glyphs[r]
+		ids = append(ids, glyph)
+		widths = append(widths, fmt.Sprintf("%d [%.0f]", glyph, f.width(r)))
+		mappings = append(mappings, fmt.Sprintf("<%04x> <%s>", glyph, utf16Hex(string(r))))
+	}
+	name := subsetTag(ids) + "+" + f.name
+	data := f.subset(ids)
+
+	w.object(font, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, cidFont, toUnicode))
+	w.object(cidFont, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>", name, descriptor, strings.Join(widths, " ")))
+	w.object(descriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%.0f %.0f %.0f %.0f] "+
+		"/ItalicAngle 0 /Ascent %.0f /Descent %.0f /CapHeight %.0f /StemV 80 /FontFile2 %d 0 R >>",
+		name, f.scale(int(f.bbox[0])), f.scale(int(f.bbox[1])), f.scale(int(f.bbox[2])), f.scale(int(f.bbox[3])),
+		f.scale(int(f.ascent)), f.scale(int(f.descent)), f.scale(int(f.ascent)), file))
+	w.stream(file, fmt.Sprintf("/Length1 %d", len(data)), data)
+
+	// bfchar 每段最多100条
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <ffff>\nendcodespacerange\n")
+	for i := 0; i < len(mappings); i += 100 {
+		end := i + 100
+		if end > len(mappings) {
+			end = len(mappings)
+		}
+		fmt.Fprintf(&cmap, "%d beginbfchar\n%s\nendbfchar\n", end-i, strings.Join(mappings[i:end], "\n"))
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	w.stream(toUnicode, "", []byte(cmap.String()))
+	return font
+}
@@ -0,0 +1,333 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 测试用的 TrueType 字体,没有时跳过
+const testFontPath = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+
+func loadTestFont(t *testing.T) *TrueType {
+	t.Helper()
+	if _, err := os.Stat(testFontPath); err != nil {
+		t.Skip("test font not installed: ", testFontPath)
+	}
+	font, err := LoadTrueType(testFontPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func TestTrueTypeSubset(t *testing.T) {
+	font := loadTestFont(t)
+	info, _ := os.Stat(testFontPath)
+
+	tests := []struct {
+		name      string
+		text      string
+		composite bool // 是否包含复合字形
+	}{
+		{"digits", "0123456789.,", false},
+		{"letters", "Repayment Plan", false},
+		// 带重音的字母是复合字形,部件字形也要保留
+		{"accents", "éàü", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !font.Covers(tt.text) {
+				t.Fatalf("test font should cover %q", tt.text)
+			}
+			ids := make([]uint16, 0)
+			for _, r := range tt.text {
+				ids = append(ids, font.glyphs[r])
+			}
+			composite := false
+			for _, id := range ids {
+				composite = composite || len(components(font.glyph(id))) > 0
+			}
+			if composite != tt.composite {
+				t.Fatalf("composite = %v, want %v", composite, tt.composite)
+			}
+			data := font.subset(ids)
+			if len(data)*10 > int(info.Size()) {
+				t.Errorf("subset is %d bytes, font file is %d bytes", len(data), info.Size())
+			}
+
+			tables, err := sfntTables(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "glyf", "loca"} {
+				if _, ok := tables[tag]; !ok {
+					t.Errorf("missing table %s", tag)
+				}
+			}
+			if _, ok := tables["cmap"]; ok {
+				t.Error("cmap should not be embedded")
+			}
+			if sum := checksum(data); sum != 0xB1B0AFBA {
+				t.Errorf("font checksum = %#x, want 0xb1b0afba", sum)
+			}
+
+			loca, err := parseLoca(tables["loca"], int(binary.BigEndian.Uint16(tables["head"][50:])), len(font.loca)-1, len(tables["glyf"]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			subsetFont := &TrueType{tables: tables, loca: loca}
+			keep := map[uint16]bool{0: true}
+			for _, id := range ids {
+				keep[id] = true
+				for _, component := range components(font.glyph(id)) {
+					keep[component] = true
+					if len(subsetFont.glyph(component)) == 0 && len(font.glyph(component)) > 0 {
+						t.Errorf("component glyph %d of %d is missing", component, id)
+					}
+				}
+				// 子集中的字形按4字节补齐
+				if !bytes.HasPrefix(subsetFont.glyph(id), font.glyph(id)) {
+					t.Errorf("glyph %d differs from the font", id)
+				}
+			}
+			for id := 0; id < len(loca)-1; id++ {
+				if !keep[uint16(id)] && len(subsetFont.glyph(uint16(id))) != 0 {
+					t.Fatalf("unused glyph %d is embedded", id)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteEmbedsSubset(t *testing.T) {
+	font := loadTestFont(t)
+	doc := New(font)
+	doc.AddPage(A4Width, A4Height).Text(50, 50, 12, "#000", false, "Repayment 123")
+	var out bytes.Buffer
+	if err := doc.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.String()
+	if !strings.Contains(pdf, "/FontFile2") {
+		t.Error("font file is not embedded")
+	}
+	if !strings.Contains(pdf, "+DejaVuSans") {
+		t.Error("subset font name should have a tag prefix")
+	}
+	if out.Len() > 50000 {
+		t.Errorf("pdf is %d bytes, the font should be subset", out.Len())
+	}
+}
+
+func TestCovers(t *testing.T) {
+	font := loadTestFont(t)
+	if !font.Covers("Loan 2024") {
+		t.Error("latin text should be covered")
+	}
+	// DejaVu Sans 没有中文字形,配置为pdf字体时不能导出pdf
+	if font.Covers("还款计划表") {
+		t.Error("DejaVu Sans should not cover Chinese")
+	}
+}
+
+// 测试用的中文字体,按字符区间生成,不依赖系统字体
+// 汉字为复合字形,引用最后一个字形(部首),其他字符为方块
+type cjkRange struct {
+	first, last rune
+	advance     uint16
+}
+
+var cjkRanges = []cjkRange{
+	{0x20, 0x7e, 500},      // ASCII
+	{0x3000, 0x303f, 1000}, // 中文标点
+	{0x4e00, 0x9fff, 1000}, // 汉字
+	{0xff00, 0xffef, 1000}, // 全角字符
+}
+
+// cmap 格式: 12, 4 或 ttc(格式12,包在 ttc 中)
+func buildCJKFont(format string) []byte {
+	simple := []byte{
+		0, 1, 0, 0, 0, 0, 0x03, 0x20, 0x03, 0x20, // 1个轮廓,bbox 0,0,800,800
+		0, 3, 0, 0, // endPtsOfContours,无指令
+		1, 1, 1, 1, // 4个在曲线上的点,坐标为 int16
+		0, 0, 0, 0, 0x03, 0x20, 0, 0, // x 增量
+		0, 0, 0x03, 0x20, 0, 0, 0xfc, 0xe0, // y 增量
+	}
+	composite := func(component uint16) []byte {
+		glyph := []byte{0xff, 0xff, 0, 0, 0, 0, 0x03, 0x20, 0x03, 0x20, 0, 0x02, 0, 0, 0, 0}
+		binary.BigEndian.PutUint16(glyph[12:], component) // ARGS_ARE_XY_VALUES,参数为字节
+		return glyph
+	}
+
+	numGlyphs := 1
+	for _, r := range cjkRanges {
+		numGlyphs += int(r.last - r.first + 1)
+	}
+	radical := uint16(numGlyphs)
+	numGlyphs++
+
+	glyf := make([]byte, 0)
+	loca := make([]byte, (numGlyphs+1)*4)
+	hmtx := make([]byte, numGlyphs*4)
+	addGlyph := func(id int, glyph []byte, advance uint16) {
+		binary.BigEndian.PutUint32(loca[id*4:], uint32(len(glyf)))
+		binary.BigEndian.PutUint16(hmtx[id*4:], advance)
+		glyf = append(glyf, glyph...)
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	addGlyph(0, simple, 1000)
+	id := 1
+	for _, r := range cjkRanges {
+		for c := r.first; c <= r.last; c++ {
+			if c >= 0x4e00 && c <= 0x9fff {
+				addGlyph(id, composite(radical), r.advance)
+			} else {
+				addGlyph(id, simple, r.advance)
+			}
+			id++
+		}
+	}
+	addGlyph(int(radical), simple, 1000)
+	binary.BigEndian.PutUint32(loca[numGlyphs*4:], uint32(len(glyf)))
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head, 0x00010000)
+	binary.BigEndian.PutUint32(head[12:], 0x5F0F3CF5)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	binary.BigEndian.PutUint16(head[40:], 800)
+	binary.BigEndian.PutUint16(head[42:], 800)
+	binary.BigEndian.PutUint16(head[50:], 1)
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint32(hhea, 0x00010000)
+	binary.BigEndian.PutUint16(hhea[4:], 880)
+	binary.BigEndian.PutUint16(hhea[6:], uint16(0xff88)) // -120
+	binary.BigEndian.PutUint16(hhea[34:], uint16(numGlyphs))
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(numGlyphs))
+
+	var subtable []byte
+	if format == "4" {
+		// 每个区间一段,glyph = c + idDelta,最后一段为 0xffff
+		segments := len(cjkRanges) + 1
+		subtable = make([]byte, 16+segments*8)
+		binary.BigEndian.PutUint16(subtable, 4)
+		binary.BigEndian.PutUint16(subtable[2:], uint16(len(subtable)))
+		binary.BigEndian.PutUint16(subtable[6:], uint16(segments*2))
+		glyph := 1
+		for s := 0; s < segments; s++ {
+			start, end, delta := uint16(0xffff), uint16(0xffff), uint16(1)
+			if s < len(cjkRanges) {
+				r := cjkRanges[s]
+				start, end, delta = uint16(r.first), uint16(r.last), uint16(glyph-int(r.first))
+				glyph += int(r.last - r.first + 1)
+			}
+			binary.BigEndian.PutUint16(subtable[14+s*2:], end)
+			binary.BigEndian.PutUint16(subtable[16+segments*2+s*2:], start)
+			binary.BigEndian.PutUint16(subtable[16+segments*4+s*2:], delta)
+		}
+	} else {
+		subtable = make([]byte, 16+len(cjkRanges)*12)
+		binary.BigEndian.PutUint16(subtable, 12)
+		binary.BigEndian.PutUint32(subtable[4:], uint32(len(subtable)))
+		binary.BigEndian.PutUint32(subtable[12:], uint32(len(cjkRanges)))
+		glyph := 1
+		for i, r := range cjkRanges {
+			group := subtable[16+i*12:]
+			binary.BigEndian.PutUint32(group, uint32(r.first))
+			binary.BigEndian.PutUint32(group[4:], uint32(r.last))
+			binary.BigEndian.PutUint32(group[8:], uint32(glyph))
+			glyph += int(r.last - r.first + 1)
+		}
+	}
+	cmap := make([]byte, 12, 12+len(subtable))
+	binary.BigEndian.PutUint16(cmap[2:], 1)
+	binary.BigEndian.PutUint16(cmap[4:], 3)
+	binary.BigEndian.PutUint16(cmap[6:], 10)
+	if format == "4" {
+		binary.BigEndian.PutUint16(cmap[6:], 1)
+	}
+	binary.BigEndian.PutUint32(cmap[8:], 12)
+	cmap = append(cmap, subtable...)
+
+	data := buildSfnt(map[string][]byte{
+		"head": head, "hhea": hhea, "hmtx": hmtx, "maxp": maxp, "cmap": cmap, "glyf": glyf, "loca": loca,
+	})
+	if format != "ttc" {
+		return data
+	}
+	// ttc 中表的偏移从文件开头算起
+	ttc := []byte{'t', 't', 'c', 'f', 0, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 16}
+	for i := 0; i < int(binary.BigEndian.Uint16(data[4:])); i++ {
+		record := data[12+i*16:]
+		binary.BigEndian.PutUint32(record[8:], binary.BigEndian.Uint32(record[8:])+16)
+	}
+	return append(ttc, data...)
+}
+
+// 写入临时目录并载入测试用的中文字体
+func loadCJKFont(t *testing.T, format string) *TrueType {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "TestCJK-"+format+".ttf")
+	if err := os.WriteFile(path, buildCJKFont(format), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	font, err := LoadTrueType(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func TestCJKFont(t *testing.T) {
+	// 汉字"还"的字形编号: .notdef,ASCII,中文标点之后按码位排列
+	huan := uint16(1 + 95 + 64 + 0x8fd8 - 0x4e00)
+	for _, format := range []string{"12", "4", "ttc"} {
+		t.Run(format, func(t *testing.T) {
+			font := loadCJKFont(t, format)
+			if !font.Covers("个人住房贷款还款计划表,第 1 页。（）") {
+				t.Fatal("fixture should cover Chinese text")
+			}
+			if font.Covers("한") {
+				t.Error("fixture should not cover Hangul")
+			}
+			if got := font.encode("还A"); got != fmt.Sprintf("%04x%04x", huan, 1+'A'-0x20) {
+				t.Errorf("encode = %s", got)
+			}
+			if font.width('还') != 1000 || font.width('A') != 500 {
+				t.Errorf("widths = %v, %v", font.width('还'), font.width('A'))
+			}
+
+			// 子集保留汉字和它引用的部首,其他汉字为空
+			radical := uint16(len(font.loca) - 2)
+			data := font.subset([]uint16{font.glyphs['还'], font.glyphs['款']})
+			tables, err := sfntTables(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loca, err := parseLoca(tables["loca"], 1, len(font.loca)-1, len(tables["glyf"]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			subsetFont := &TrueType{tables: tables, loca: loca}
+			for _, id := range []uint16{0, huan, font.glyphs['款'], radical} {
+				if len(subsetFont.glyph(id)) == 0 {
+					t.Errorf("glyph %d is missing from the subset", id)
+				}
+			}
+			if len(subsetFont.glyph(font.glyphs['贷'])) != 0 {
+				t.Error("unused glyph is embedded")
+			}
+			if sum := checksum(data); sum != 0xB1B0AFBA {
+				t.Errorf("font checksum = %#x, want 0xb1b0afba", sum)
+			}
+		})
+	}
+}
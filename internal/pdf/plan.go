@@ -0,0 +1,317 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/chart"
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// 还款计划表的版面,A4横向
+const (
+	pageWidth     = A4Height
+	pageHeight    = A4Width
+	margin        = 40.0
+	rowHeight     = 14.0
+	fontSize      = 9.0
+	colorBlack    = "#000000"
+	colorGray     = "#666666"
+	colorHeader   = "#4ba875"
+	colorBorder   = "#cccccc"
+	colorHiPrepay = "#e3f2e9"
+	colorHiRate   = "#fff4d6"
+)
+
+// 还款方式的名称
+var actionNames = map[string]string{
+	"emi":      "等额本息",
+	"epp":      "等额本金",
+	"balloon":  "等额本息(带尾款)",
+	"biweekly": "双周供",
+}
+
+// Plan 还款计划表的内容
+type Plan struct {
+	Bank      string // 银行,为空时显示默认的计算方法
+	Action    string // 还款方式
+	Input     loan.Input
+	Summary   loan.Summary
+	Reports   []loan.Report
+	Generated time.Time // 生成时间
+}
+
+// 表格的列
+type column struct {
+	title string
+	width float64
+	right bool // 右对齐
+	value func(loan.Report) string
+}
+
+var planColumns = []column{
+	{"期数", 40, true, func(r loan.Report) string { return strconv.Itoa(r.LoanTerm) }},
+	{"明细", 55, false, func(r loan.Report) string { return r.Purpose }},
+	{"还款日期", 70, false, func(r loan.Report) string { return r.DueDate.Format("2006-01-02") }},
+	{"本期还款", 85, true, func(r loan.Report) string { return loan.FormatMoney(r.MonthTotalAmount) }},
+	{"本金", 85, true, func(r loan.Report) string { return loan.FormatMoney(r.Principal) }},
+	{"利息", 80, true, func(r loan.Report) string { return loan.FormatMoney(r.Interest) }},
+	{"违约金", 60, true, func(r loan.Report) string { return loan.FormatMoney(r.Fee) }},
+	{"剩余本金", 90, true, func(r loan.Report) string { return loan.FormatMoney(r.RemainingPrincipal) }},
+	{"累计利息", 90, true, func(r loan.Report) string { return loan.FormatMoney(r.TotalInterestPaid) }},
+	{"年利率(%)", 60, true, func(r loan.Report) string { return r.DueDateRate.String() }},
+	{"状态", 40, false, func(r loan.Report) string { return r.Status }},
+}
+
+// WritePlan 输出还款计划表: 封面汇总,分页的还款明细(每页重复表头并小计),图表
+func WritePlan(out io.Writer, font Font, plan Plan) error {
+	doc := New(font)
+
+	rows := make([]loan.Report, 0, len(plan.Reports))
+	for _, report := range plan.Reports {
+		if report.IsEmptyEarlyRepayment() {
+			continue
+		}
+		rows = append(rows, report)
+	}
+	// 每页除去标题,表头,小计和页码后的行数
+	available := pageHeight - 2*margin - 40 - 3*rowHeight
+	perPage := int(available / rowHeight)
+	schedulePages := (len(rows) + perPage - 1) / perPage
+	// 每页一个图表,两个图表的高度会压到页码
+	chartPages := len(chart.Names)
+	total := 1 + schedulePages + chartPages
+
+	titlePage(doc, plan, total)
+	for i := 0; i < schedulePages; i++ {
+		end := (i + 1) * perPage
+		if end > len(rows) {
+			end = len(rows)
+		}
+		schedulePage(doc, rows[i*perPage:end], 2+i, total)
+	}
+	scale := (pageWidth - 2*margin) / chart.Width
+	for i, name := range chart.Names {
+		page := doc.AddPage(pageWidth, pageHeight)
+		pageTitle(page, "图表")
+		canvas := &pageCanvas{page: page, x: margin, y: margin + 30, scale: scale}
+		if _, err := chart.Draw(name, plan.Reports, canvas); err != nil {
+			return err
+		}
+		pageFooter(page, 2+schedulePages+i, total)
+	}
+	return doc.Write(out)
+}
+
+func pageTitle(page *Page, title string) {
+	page.Text(margin, margin+12, 14, colorBlack, true, "个人住房贷款还款计划表 - "+title)
+	page.Line(margin, margin+18, pageWidth-margin, margin+18, 0.8, colorHeader, false)
+}
+
+func pageFooter(page *Page, number, total int) {
+	page.TextCenter(pageWidth/2, pageHeight-margin/2, 8, colorGray, false, fmt.Sprintf("第 %d 页 / 共 %d 页", number, total))
+}
+
+// 封面: 贷款参数,银行,LPR数据和汇总
+func titlePage(doc *Document, plan Plan, total int) {
+	page := doc.AddPage(pageWidth, pageHeight)
+	l := plan.Input.Loan
+	page.TextCenter(pageWidth/2, margin+30, 22, colorBlack, true, "个人住房贷款还款计划表")
+	page.TextCenter(pageWidth/2, margin+50, 9, colorGray, false, "生成时间 "+plan.Generated.Format("2006-01-02 15:04")+",本计划表按合同条款测算,实际以银行扣款为准")
+
+	bank := plan.Bank
+	if bank == "" {
+		bank = "默认(按中国工商银行的计算方法)"
+	}
+	rate := "LPR+加点浮动"
+	switch {
+	case l.FixedRate.IsPositive() && l.FixedYears > 0:
+		rate = fmt.Sprintf("前%d年固定 %s%%,之后LPR+加点", l.FixedYears, l.FixedRate)
+	case l.FixedRate.IsPositive():
+		rate = "固定利率 " + l.FixedRate.String() + "%"
+	}
+	params := [][2]string{
+		{"贷款银行", bank},
+		{"还款方式", actionNames[plan.Action]},
+		{"贷款本金", loan.FormatMoney(l.InitialPrincipal)},
+		{"贷款期限", strconv.Itoa(l.InitialTerm) + " 个月"},
+		{"放款日期", l.InitialDate.Format("2006-01-02")},
+		{"每月还款日", strconv.Itoa(l.PaymentDueDay) + " 日"},
+		{"利率方式", rate},
+		{"加点", l.PlusSpread.String() + "%"},
+		{"首期执行利率", l.EffectiveRate(l.InitialDate).String() + "%"},
+	}
+	if l.BalloonAmount.IsPositive() {
+		params = append(params, [2]string{"尾款", loan.FormatMoney(l.BalloonAmount)})
+	}
+	for _, early := range plan.Input.EarlyRepayment {
+		if early.Amount.IsPositive() {
+			params = append(params, [2]string{"提前还款", early.Date.Format("2006-01-02") + " " + loan.FormatMoney(early.Amount)})
+		}
+	}
+	params = append(params, lprDataset(l.LPR, l.LPRAt(l.InitialDate))...)
+
+	s := plan.Summary
+	summary := [][2]string{
+		{"归还本金", loan.FormatMoney(s.TotalPrincipal)},
+		{"利息合计", loan.FormatMoney(s.TotalInterest)},
+		{"违约金合计", loan.FormatMoney(s.TotalFee)},
+		{"罚息复利合计", loan.FormatMoney(s.TotalPenalty)},
+		{"还款总额", loan.FormatMoney(s.TotalPaid)},
+		{"结清日期", s.PayoffDate.Format("2006-01-02")},
+		{"还款期数", strconv.Itoa(s.Periods)},
+		{"平均每期还款", loan.FormatMoney(s.AverageInstallment)},
+		{"最高每期还款", loan.FormatMoney(s.MaxInstallment)},
+		{"加权平均利率", s.WeightedAverageAPR.String() + "%"},
+		{"实际年化成本(XIRR)", s.EffectiveRate.String() + "%"},
+	}
+	if s.EffectiveRateError != "" {
+		summary[len(summary)-1][1] = "无法计算"
+	}
+
+	keyValues(page, margin+40, margin+90, "贷款参数", params)
+	keyValues(page, pageWidth/2+20, margin+90, "还款汇总", summary)
+	pageFooter(page, 1, total)
+}
+
+// LPR 数据集: 期限品种,条目数,日期范围,最新报价和放款时适用的报价
+func lprDataset(lprs []loan.LPR, initial decimal.Decimal) [][2]string {
+	if len(lprs) == 0 {
+		return [][2]string{{"LPR数据", "无"}}
+	}
+	first, last := lprs[0], lprs[0]
+	for _, lpr := range lprs {
+		if lpr.Date.Before(first.Date) {
+			first = lpr
+		}
+		if lpr.Date.After(last.Date) {
+			last = lpr
+		}
+	}
+	return [][2]string{
+		{"LPR数据", fmt.Sprintf("5年期以上LPR,%d条", len(lprs))},
+		{"LPR日期范围", first.Date.Format("2006-01-02") + " ~ " + last.Date.Format("2006-01-02")},
+		{"最新LPR", last.LPR.String() + "% (" + last.Date.Format("2006-01-02") + ")"},
+		{"放款时适用LPR", initial.String() + "%"},
+	}
+}
+
+func keyValues(page *Page, x, y float64, title string, items [][2]string) {
+	page.Text(x, y, 12, colorHeader, true, title)
+	for i, item := range items {
+		rowY := y + 22 + float64(i)*18
+		page.Text(x, rowY, 10, colorGray, false, item[0])
+		page.Text(x+110, rowY, 10, colorBlack, false, item[1])
+		page.Line(x, rowY+5, x+320, rowY+5, 0.3, colorBorder, false)
+	}
+}
+
+// 还款明细页,表头在每页重复,最后一行为本页小计
+func schedulePage(doc *Document, rows []loan.Report, number, total int) {
+	page := doc.AddPage(pageWidth, pageHeight)
+	pageTitle(page, "还款明细")
+
+	y := margin + 30
+	page.Rect(margin, y, pageWidth-2*margin, rowHeight+2, colorHeader)
+	tableRow(page, y, rowHeight+2, "#ffffff", true, func(c column) string { return c.title })
+	y += rowHeight + 2
+
+	var principal, interest, fee, paid decimal.Decimal
+	for _, row := range rows {
+		switch {
+		case row.Purpose == "提前还款":
+			page.Rect(margin, y, pageWidth-2*margin, rowHeight, colorHiPrepay)
+		case row.Purpose == "利率调整":
+			page.Rect(margin, y, pageWidth-2*margin, rowHeight, colorHiRate)
+		}
+		r := row
+		tableRow(page, y, rowHeight, colorBlack, false, func(c column) string { return c.value(r) })
+		page.Line(margin, y+rowHeight, pageWidth-margin, y+rowHeight, 0.3, colorBorder, false)
+		y += rowHeight
+
+		switch row.Purpose {
+		case "分期", "提前还款", "结清":
+			principal = principal.Add(row.Principal)
+			interest = interest.Add(row.Interest)
+			fee = fee.Add(row.Fee)
+			paid = paid.Add(row.MonthTotalAmount)
+		}
+	}
+
+	page.Line(margin, y, pageWidth-margin, y, 0.8, colorBlack, false)
+	subtotal := map[string]string{
+		"明细":   "本页合计",
+		"本期还款": loan.FormatMoney(paid),
+		"本金":   loan.FormatMoney(principal),
+		"利息":   loan.FormatMoney(interest),
+		"违约金":  loan.FormatMoney(fee),
+	}
+	tableRow(page, y, rowHeight, colorBlack, true, func(c column) string { return subtotal[c.title] })
+	pageFooter(page, number, total)
+}
+
+// 按列输出一行,文字在行内垂直居中
+func tableRow(page *Page, y, height float64, color string, bold bool, value func(column) string) {
+	x := margin
+	baseline := y + height/2 + fontSize*0.35
+	for _, c := range planColumns {
+		text := value(c)
+		if c.right {
+			page.TextRight(x+c.width-4, baseline, fontSize, color, bold, text)
+		} else {
+			page.Text(x+4, baseline, fontSize, color, bold, text)
+		}
+		x += c.width
+	}
+}
+
+// 在页面的指定区域绘制图表,按比例缩放,提示只用于网页,这里忽略
+type pageCanvas struct {
+	page  *Page
+	x, y  float64
+	scale float64
+}
+
+func (c *pageCanvas) px(x float64) float64 { return c.x + x*c.scale }
+func (c *pageCanvas) py(y float64) float64 { return c.y + y*c.scale }
+
+func (c *pageCanvas) Line(x1, y1, x2, y2 float64, color string, dashed bool) {
+	c.page.Line(c.px(x1), c.py(y1), c.px(x2), c.py(y2), 0.5, color, dashed)
+}
+
+func (c *pageCanvas) Polyline(points [][2]float64, color string) {
+	scaled := make([][2]float64, len(points))
+	for i, point := range points {
+		scaled[i] = [2]float64{c.px(point[0]), c.py(point[1])}
+	}
+	c.page.Polyline(scaled, 1.2, color)
+}
+
+func (c *pageCanvas) Rect(x, y, w, h float64, color string) {
+	c.page.Rect(c.px(x), c.py(y), w*c.scale, h*c.scale, color)
+}
+
+func (c *pageCanvas) Circle(x, y, r float64, color string) {
+	if color == "transparent" {
+		return
+	}
+	c.page.Circle(c.px(x), c.py(y), r*c.scale, color)
+}
+
+func (c *pageCanvas) Text(x, y, size float64, color, anchor string, bold bool, text string) {
+	switch anchor {
+	case "middle":
+		c.page.TextCenter(c.px(x), c.py(y), size*c.scale, color, bold, text)
+	case "end":
+		c.page.TextRight(c.px(x), c.py(y), size*c.scale, color, bold, text)
+	default:
+		c.page.Text(c.px(x), c.py(y), size*c.scale, color, bold, text)
+	}
+}
+
+func (c *pageCanvas) Tip(title string) {}
+
+func (c *pageCanvas) End() {}
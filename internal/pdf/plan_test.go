@@ -0,0 +1,181 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+func TestWritePlan(t *testing.T) {
+	font := loadTestFont(t)
+	newLoan := func(term int) loan.Loan {
+		return loan.Loan{
+			InitialPrincipal: decimal.NewFromInt(1000000),
+			InitialTerm:      term,
+			InitialDate:      loan.ParseDate("2023-01-10"),
+			PaymentDueDay:    18,
+			FixedRate:        decimal.NewFromFloat(4.9),
+			LPR:              loan.Lprs,
+		}
+	}
+	// 每页30行明细,另有封面和每页一个的4页图表
+	tests := []struct {
+		name    string
+		input   loan.Input
+		rows    int // 只保留前几行还款明细,0为全部
+		pages   int
+		wantErr bool
+	}{
+		{name: "one schedule page", input: loan.Input{Loan: newLoan(12)}, pages: 6},
+		{name: "page boundary", input: loan.Input{Loan: newLoan(29)}, pages: 6},
+		{name: "two schedule pages", input: loan.Input{Loan: newLoan(30)}, pages: 7},
+		{name: "thirty years", input: loan.Input{Loan: newLoan(360)}, pages: 18},
+		{
+			// 未填写的提前还款不占行
+			name: "placeholder prepayment",
+			input: loan.Input{Loan: newLoan(29), EarlyRepayment: []loan.EarlyRepayment{
+				{Amount: decimal.Zero, Date: loan.ParseDate("2099-05-25")},
+			}},
+			pages: 6,
+		},
+		// 图表数据不足时返回错误
+		{name: "chart error", input: loan.Input{Loan: newLoan(12)}, rows: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := loan.BuildReport(tt.input, "emi")
+			if tt.rows > 0 {
+				reports = reports[:tt.rows]
+			}
+			plan := Plan{
+				Action:    "emi",
+				Input:     tt.input,
+				Summary:   loan.BuildSummary(tt.input, "emi", reports),
+				Reports:   reports,
+				Generated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+			}
+			var out bytes.Buffer
+			err := WritePlan(&out, font, plan)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			pdf := out.String()
+			if !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+				t.Fatal("not a complete pdf")
+			}
+			match := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindStringSubmatch(pdf)
+			if match == nil {
+				t.Fatal("no page tree")
+			}
+			if pages, _ := strconv.Atoi(match[1]); pages != tt.pages {
+				t.Errorf("%d pages, want %d", pages, tt.pages)
+			}
+		})
+	}
+}
+
+// 解压pdf中的所有流
+func pdfStreams(t *testing.T, pdf []byte) [][]byte {
+	t.Helper()
+	streams := make([][]byte, 0)
+	header := regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+	for _, match := range header.FindAllSubmatchIndex(pdf, -1) {
+		length, _ := strconv.Atoi(string(pdf[match[2]:match[3]]))
+		reader, err := zlib.NewReader(bytes.NewReader(pdf[match[1] : match[1]+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, data)
+	}
+	return streams
+}
+
+// 中文经子集化嵌入,文字按字形编号输出,ToUnicode 映射回中文
+func TestWritePlanCJK(t *testing.T) {
+	font := loadCJKFont(t, "ttc")
+	input := loan.Input{Loan: loan.Loan{
+		InitialPrincipal: decimal.NewFromInt(1000000),
+		InitialTerm:      24,
+		InitialDate:      loan.ParseDate("2023-01-10"),
+		PaymentDueDay:    18,
+		FixedRate:        decimal.NewFromFloat(4.9),
+		LPR:              loan.Lprs,
+	}}
+	reports := loan.BuildReport(input, "epp")
+	plan := Plan{
+		Action:    "epp",
+		Input:     input,
+		Summary:   loan.BuildSummary(input, "epp", reports),
+		Reports:   reports,
+		Generated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+	}
+	var out bytes.Buffer
+	if err := WritePlan(&out, font, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	var content, toUnicode string
+	var embedded []byte
+	for _, stream := range pdfStreams(t, out.Bytes()) {
+		switch {
+		case bytes.HasPrefix(stream, []byte("/CIDInit")):
+			toUnicode = string(stream)
+		case bytes.HasPrefix(stream, []byte{0, 1, 0, 0}):
+			embedded = stream
+		default:
+			content += string(stream)
+		}
+	}
+	for _, text := range []string{"个人住房贷款还款计划表", "等额本金", "还款明细", "图表"} {
+		if !strings.Contains(content, font.encode(text)) {
+			t.Errorf("%s is not in the page content", text)
+		}
+		for _, r := range text {
+			mapping := fmt.Sprintf("<%04x> <%s>", font.glyphs[r], utf16Hex(string(r)))
+			if !strings.Contains(toUnicode, mapping) {
+				t.Errorf("ToUnicode has no %s for %c", mapping, r)
+			}
+		}
+	}
+
+	tables, err := sfntTables(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loca, err := parseLoca(tables["loca"], 1, len(font.loca)-1, len(tables["glyf"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subsetFont := &TrueType{tables: tables, loca: loca}
+	radical := uint16(len(font.loca) - 2)
+	for _, r := range "还款计划表" {
+		if !bytes.HasPrefix(subsetFont.glyph(font.glyphs[r]), font.glyph(font.glyphs[r])) {
+			t.Errorf("glyph of %c is not embedded", r)
+		}
+	}
+	if len(subsetFont.glyph(radical)) == 0 {
+		t.Error("component glyph is not embedded")
+	}
+	if len(subsetFont.glyph(font.glyphs['龘'])) != 0 {
+		t.Error("unused glyph is embedded")
+	}
+	if len(tables["glyf"])*10 > len(font.tables["glyf"]) {
+		t.Errorf("embedded glyf is %d bytes, it should be subset", len(tables["glyf"]))
+	}
+}
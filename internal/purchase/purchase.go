@@ -0,0 +1,150 @@
+package purchase
+
+import (
+	"errors"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+// 购房套数
+const (
+	FirstHome  = "first"  // 首套
+	SecondHome = "second" // 二套
+)
+
+// Rules 首付比例和税费规则,各地政策不同,百分比均以%表示
+type Rules struct {
+	FirstHomeDownPayment   decimal.Decimal // 首套最低首付比例
+	SecondHomeDownPayment  decimal.Decimal // 二套最低首付比例
+	DeedTaxAreaThreshold   decimal.Decimal // 契税面积分界(平方米)
+	FirstHomeDeedTaxSmall  decimal.Decimal // 首套面积不超过分界的契税税率
+	FirstHomeDeedTaxLarge  decimal.Decimal // 首套面积超过分界的契税税率
+	SecondHomeDeedTaxSmall decimal.Decimal // 二套面积不超过分界的契税税率
+	SecondHomeDeedTaxLarge decimal.Decimal // 二套面积超过分界的契税税率
+	VATRate                decimal.Decimal // 增值税及附加税率,按不含税价计算,不满两年的二手房征收
+	IncomeTaxRate          decimal.Decimal // 个税核定征收税率,不是满五唯一的二手房征收
+}
+
+// DefaultRules 全国通行的首付比例和契税规则
+// 首付: 首套20%,二套30%
+// 契税: 140平方米及以下1%;以上首套1.5%,二套2%
+// 2024年12月1日起施行(财政部 税务总局 住房城乡建设部公告2024年第16号),之前的面积分界为90平方米
+// 增值税: 5%,附加税按增值税的6%,合计5.3%
+// 个税: 核定征收1%
+var DefaultRules = Rules{
+	FirstHomeDownPayment:   decimal.NewFromInt(20),
+	SecondHomeDownPayment:  decimal.NewFromInt(30),
+	DeedTaxAreaThreshold:   decimal.NewFromInt(140),
+	FirstHomeDeedTaxSmall:  decimal.NewFromInt(1),
+	FirstHomeDeedTaxLarge:  decimal.NewFromFloat(1.5),
+	SecondHomeDeedTaxSmall: decimal.NewFromInt(1),
+	SecondHomeDeedTaxLarge: decimal.NewFromInt(2),
+	VATRate:                decimal.NewFromFloat(5.3),
+	IncomeTaxRate:          decimal.NewFromInt(1),
+}
+
+// Input 购房参数
+type Input struct {
+	Price            decimal.Decimal // 成交价
+	HomeType         string          // 首套或二套
+	Area             decimal.Decimal // 建筑面积(平方米)
+	NewHome          bool            // 一手房,不征收增值税和个税
+	OverTwoYears     bool            // 二手房满两年,免征增值税
+	FiveYearsOnly    bool            // 二手房满五唯一,免征个税,同时视为满两年
+	DownPaymentRatio decimal.Decimal // 首付比例,为0时取最低首付比例
+	AgencyFeeRate    decimal.Decimal // 中介费率
+	OtherFees        decimal.Decimal // 其他费用,如登记费,评估费
+}
+
+// Quote 购房所需资金和贷款金额
+type Quote struct {
+	Price            decimal.Decimal
+	DownPaymentRatio decimal.Decimal // 首付比例(%)
+	DownPayment      decimal.Decimal // 首付
+	DeedTaxRate      decimal.Decimal // 契税税率(%)
+	DeedTax          decimal.Decimal // 契税
+	VAT              decimal.Decimal // 增值税及附加
+	IncomeTax        decimal.Decimal // 个税
+	AgencyFee        decimal.Decimal // 中介费
+	OtherFees        decimal.Decimal // 其他费用
+	TotalTaxes       decimal.Decimal // 税费合计
+	CashAtClosing    decimal.Decimal // 交易时需要的现金=首付+税费+中介费+其他费用
+	LoanAmount       decimal.Decimal // 贷款金额=成交价-首付
+}
+
+// 最低首付比例
+func (rules Rules) minDownPayment(homeType string) decimal.Decimal {
+	if homeType == SecondHome {
+		return rules.SecondHomeDownPayment
+	}
+	return rules.FirstHomeDownPayment
+}
+
+// 契税税率,按套数和面积
+func (rules Rules) deedTaxRate(homeType string, area decimal.Decimal) decimal.Decimal {
+	large := area.GreaterThan(rules.DeedTaxAreaThreshold)
+	switch {
+	case homeType == SecondHome && large:
+		return rules.SecondHomeDeedTaxLarge
+	case homeType == SecondHome:
+		return rules.SecondHomeDeedTaxSmall
+	case large:
+		return rules.FirstHomeDeedTaxLarge
+	}
+	return rules.FirstHomeDeedTaxSmall
+}
+
+// 按百分比计算
+func percent(amount, rate decimal.Decimal) decimal.Decimal {
+	return amount.Mul(rate).Div(decimal.NewFromInt(100)).Round(2)
+}
+
+// Calculate 按规则计算首付,税费和贷款金额
+// 1.征收增值税时,契税和个税按不含增值税的价格计算
+// 2.首付比例低于最低首付比例时返回错误
+func (rules Rules) Calculate(input Input) (Quote, error) {
+	if !input.Price.IsPositive() {
+		return Quote{}, errors.New("price should be positive")
+	}
+	if input.HomeType != FirstHome && input.HomeType != SecondHome {
+		return Quote{}, errors.New("home type should be first or second")
+	}
+	ratio := input.DownPaymentRatio
+	if ratio.IsZero() {
+		ratio = rules.minDownPayment(input.HomeType)
+	}
+	if ratio.LessThan(rules.minDownPayment(input.HomeType)) || ratio.GreaterThan(decimal.NewFromInt(100)) {
+		return Quote{}, errors.New("down payment ratio should be between the minimum ratio and 100")
+	}
+
+	quote := Quote{
+		Price:            input.Price,
+		DownPaymentRatio: ratio,
+		DownPayment:      percent(input.Price, ratio),
+		DeedTaxRate:      rules.deedTaxRate(input.HomeType, input.Area),
+		AgencyFee:        percent(input.Price, input.AgencyFeeRate),
+		OtherFees:        input.OtherFees,
+	}
+
+	taxablePrice := input.Price
+	if !input.NewHome && !input.OverTwoYears && !input.FiveYearsOnly {
+		taxablePrice = input.Price.Div(decimal.NewFromFloat(1.05)).Round(2)
+		quote.VAT = percent(taxablePrice, rules.VATRate)
+	}
+	if !input.NewHome && !input.FiveYearsOnly {
+		quote.IncomeTax = percent(taxablePrice, rules.IncomeTaxRate)
+	}
+	quote.DeedTax = percent(taxablePrice, quote.DeedTaxRate)
+
+	quote.TotalTaxes = quote.DeedTax.Add(quote.VAT).Add(quote.IncomeTax)
+	quote.CashAtClosing = quote.DownPayment.Add(quote.TotalTaxes).Add(quote.AgencyFee).Add(quote.OtherFees)
+	quote.LoanAmount = input.Price.Sub(quote.DownPayment)
+	return quote, nil
+}
+
+// ApplyTo 贷款金额作为贷款本金
+func (quote Quote) ApplyTo(l loan.Loan) loan.Loan {
+	l.InitialPrincipal = quote.LoanAmount
+	return l
+}
@@ -0,0 +1,103 @@
+package purchase
+
+import (
+	"testing"
+
+	"github.com/oplancelot/Home-Mortgage-Loans-In-China/internal/loan"
+	"github.com/shopspring/decimal"
+)
+
+func TestCalculate(t *testing.T) {
+	price := decimal.NewFromInt(3000000)
+	tests := []struct {
+		name       string
+		input      Input
+		downRatio  string
+		deedTax    string
+		vat        string
+		incomeTax  string
+		agencyFee  string
+		cash       string
+		loanAmount string
+		err        bool
+	}{
+		{
+			name:      "new first home",
+			input:     Input{Price: price, HomeType: FirstHome, Area: decimal.NewFromInt(89), NewHome: true},
+			downRatio: "20", deedTax: "30000", vat: "0", incomeTax: "0", agencyFee: "0", cash: "630000", loanAmount: "2400000",
+		},
+		{
+			// 140平方米按小面积税率
+			name:      "area at threshold",
+			input:     Input{Price: price, HomeType: FirstHome, Area: decimal.NewFromInt(140), NewHome: true},
+			downRatio: "20", deedTax: "30000", vat: "0", incomeTax: "0", agencyFee: "0", cash: "630000", loanAmount: "2400000",
+		},
+		{
+			// 2024年12月前的分界为90平方米,现在120平方米也按1%
+			name:      "area over the old threshold",
+			input:     Input{Price: price, HomeType: SecondHome, Area: decimal.NewFromInt(120), NewHome: true},
+			downRatio: "30", deedTax: "30000", vat: "0", incomeTax: "0", agencyFee: "0", cash: "930000", loanAmount: "2100000",
+		},
+		{
+			name:      "large first home",
+			input:     Input{Price: price, HomeType: FirstHome, Area: decimal.NewFromInt(150), NewHome: true, DownPaymentRatio: decimal.NewFromInt(50)},
+			downRatio: "50", deedTax: "45000", vat: "0", incomeTax: "0", agencyFee: "0", cash: "1545000", loanAmount: "1500000",
+		},
+		{
+			// 不满两年的二手房按不含增值税的价格计算契税和个税
+			name:      "second home under two years",
+			input:     Input{Price: price, HomeType: SecondHome, Area: decimal.NewFromInt(150), AgencyFeeRate: decimal.NewFromInt(2), OtherFees: decimal.NewFromInt(500)},
+			downRatio: "30", deedTax: "57142.86", vat: "151428.57", incomeTax: "28571.43", agencyFee: "60000", cash: "1197642.86", loanAmount: "2100000",
+		},
+		{
+			name:      "over two years",
+			input:     Input{Price: price, HomeType: SecondHome, Area: decimal.NewFromInt(80), OverTwoYears: true},
+			downRatio: "30", deedTax: "30000", vat: "0", incomeTax: "30000", agencyFee: "0", cash: "960000", loanAmount: "2100000",
+		},
+		{
+			name:      "five years only",
+			input:     Input{Price: price, HomeType: FirstHome, Area: decimal.NewFromInt(80), FiveYearsOnly: true},
+			downRatio: "20", deedTax: "30000", vat: "0", incomeTax: "0", agencyFee: "0", cash: "630000", loanAmount: "2400000",
+		},
+		{name: "no price", input: Input{HomeType: FirstHome}, err: true},
+		{name: "unknown home type", input: Input{Price: price, HomeType: "third"}, err: true},
+		{name: "below minimum down payment", input: Input{Price: price, HomeType: SecondHome, DownPaymentRatio: decimal.NewFromInt(25)}, err: true},
+		{name: "down payment above 100", input: Input{Price: price, HomeType: FirstHome, DownPaymentRatio: decimal.NewFromInt(101)}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := DefaultRules.Calculate(tt.input)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", quote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, field := range []struct {
+				name      string
+				got, want string
+			}{
+				{"down payment ratio", quote.DownPaymentRatio.String(), tt.downRatio},
+				{"deed tax", quote.DeedTax.String(), tt.deedTax},
+				{"vat", quote.VAT.String(), tt.vat},
+				{"income tax", quote.IncomeTax.String(), tt.incomeTax},
+				{"agency fee", quote.AgencyFee.String(), tt.agencyFee},
+				{"cash at closing", quote.CashAtClosing.String(), tt.cash},
+				{"loan amount", quote.LoanAmount.String(), tt.loanAmount},
+			} {
+				if !decimal.RequireFromString(field.got).Equal(decimal.RequireFromString(field.want)) {
+					t.Errorf("%s = %s, want %s", field.name, field.got, field.want)
+				}
+			}
+			if !quote.TotalTaxes.Equal(quote.DeedTax.Add(quote.VAT).Add(quote.IncomeTax)) {
+				t.Errorf("total taxes = %s", quote.TotalTaxes)
+			}
+			if l := quote.ApplyTo(loan.Loan{InitialTerm: 360}); !l.InitialPrincipal.Equal(quote.LoanAmount) || l.InitialTerm != 360 {
+				t.Errorf("ApplyTo = %+v", l)
+			}
+		})
+	}
+}